@@ -1,11 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/marcelofabianov/course/pkg/retry"
 	"github.com/spf13/viper"
 )
@@ -20,6 +25,14 @@ type Config struct {
 	Redis      RedisConfig
 	Migrations MigrationsConfig
 	JWT        JWTConfig
+	Tenant     TenantConfig
+	Privacy    PrivacyConfig
+	Crypto     CryptoConfig
+	Password   PasswordConfig
+	I18n       I18nConfig
+	Metrics    MetricsConfig
+	Tracing    TracingConfig
+	Admin      AdminConfig
 }
 
 // GeneralConfig holds general application settings
@@ -31,7 +44,29 @@ type GeneralConfig struct {
 
 // LoggerConfig holds logger settings
 type LoggerConfig struct {
-	Level string
+	Level           string
+	SensitiveFields []string // redacted from logs and validation errors, besides the defaults
+	Debug           DebugLoggingConfig
+	Sampling        LogSamplingConfig
+}
+
+// LogSamplingConfig holds the sampling that keeps repeated records and
+// access logs from flooding the log pipeline
+type LogSamplingConfig struct {
+	Enabled      bool
+	First        int                // records per level and message logged in each tick
+	Thereafter   int                // then one in every Thereafter; 0 drops the rest of the tick
+	Tick         time.Duration      // sampling window
+	AccessRoutes map[string]float64 // share of access log lines kept per "[METHOD ]chi pattern"
+}
+
+// DebugLoggingConfig holds the signed tokens that enable debug logging for
+// one request or user without changing the logger level
+type DebugLoggingConfig struct {
+	Enabled    bool
+	HeaderName string        // request header carrying the token
+	Secret     string        // signs the tokens issued by the admin listener
+	MaxTTL     time.Duration // longest lifetime of an issued token
 }
 
 // HTTPConfig holds HTTP server settings
@@ -48,6 +83,8 @@ type HTTPConfig struct {
 	CORS            CORSConfig
 	Compression     CompressionConfig
 	RateLimit       RateLimitConfig
+	LoadShedding    LoadSheddingConfig
+	Idempotency     IdempotencyConfig
 	CSRF            CSRFConfig
 	TLS             TLSConfig
 }
@@ -88,6 +125,18 @@ type RateLimitConfig struct {
 	Global         RateConfig
 	Routes         map[string]RateConfig
 	TrustedProxies []string
+	Fallback       RateLimitFallbackConfig
+	// Credentials limits the routes that check a password, even with rate
+	// limiting disabled
+	Credentials RateConfig
+}
+
+// RateLimitFallbackConfig holds the in-memory limiter used while Redis is
+// unavailable
+type RateLimitFallbackConfig struct {
+	Enabled  bool
+	MaxKeys  int // buckets kept per instance
+	Replicas int // estimated instances sharing the limits
 }
 
 // RateConfig holds rate limit settings for a specific scope
@@ -97,14 +146,38 @@ type RateConfig struct {
 	Burst  int           // burst allowance
 }
 
-// CSRFConfig holds CSRF protection settings
-type CSRFConfig struct {
+// LoadSheddingConfig holds the adaptive in-flight request limit
+type LoadSheddingConfig struct {
+	Enabled          bool
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	Tolerance        float64  // latency over the no-load baseline that backs off
+	CriticalPaths    []string // path prefixes never shed
+	LowPriorityPaths []string // path prefixes shed first
+}
+
+// IdempotencyConfig holds the Idempotency-Key handling of unsafe requests
+type IdempotencyConfig struct {
 	Enabled    bool
-	Secret     string
-	CookieName string
 	HeaderName string
-	TTL        time.Duration
-	Exempt     []string
+	TTL        time.Duration // how long a response is replayed for its key
+	LockTTL    time.Duration // how long a key stays locked by a request in progress
+}
+
+// CSRFConfig holds CSRF protection settings
+type CSRFConfig struct {
+	Enabled        bool
+	Secret         string
+	PreviousSecret string    // replaced secret, still accepted for one TTL after RotatedAt
+	RotatedAt      time.Time // when Secret replaced PreviousSecret (RFC 3339)
+	CookieName     string
+	HeaderName     string
+	TTL            time.Duration
+	Exempt         []string
+	SessionCookie  string   // cookie holding the session id tokens are bound to
+	CheckOrigin    bool     // also require a same-host or trusted Origin/Referer
+	TrustedOrigins []string // scheme://host[:port] allowed besides the API host
 }
 
 // ServerConfig holds HTTP server settings
@@ -221,6 +294,79 @@ type JWTConfig struct {
 	Issuer          string
 }
 
+// TenantConfig holds multi-tenancy settings
+type TenantConfig struct {
+	Enabled    bool
+	Header     string // header carrying the tenant id
+	Claim      string // access token claim carrying the tenant id
+	BaseDomain string // resolves <tenant>.BaseDomain when set
+	DefaultID  string // used when no tenant is resolved, and for every transaction when disabled; empty makes the tenant required
+}
+
+// PrivacyConfig holds LGPD data retention settings
+type PrivacyConfig struct {
+	PurgeEnabled    bool
+	RetentionPeriod time.Duration // soft-deleted users older than this are purged
+	PurgeInterval   time.Duration
+	PurgeBatchSize  int
+}
+
+// CryptoConfig holds field-level encryption settings
+type CryptoConfig struct {
+	DataKeys           map[int]string // key version -> base64 encoded 32-byte key
+	ActiveKeyVersion   int
+	BlindIndexKey      string // base64 encoded 32-byte key; never rotated
+	ReencryptEnabled   bool
+	ReencryptInterval  time.Duration
+	ReencryptBatchSize int
+}
+
+// PasswordConfig holds password hashing and policy settings. Raising the
+// Argon2 parameters rehashes each password on the user's next login.
+type PasswordConfig struct {
+	Argon2Memory       uint32 // KiB
+	Argon2Iterations   uint32
+	Argon2Parallelism  uint8
+	LegacyBcrypt       bool // accept bcrypt hashes of imported users until they log in
+	MinLength          int
+	MaxLength          int
+	RequireUppercase   bool
+	RequireLowercase   bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	MaxRepeated        int    // longest run of one character; 0 disables the check
+	ForbidPersonalInfo bool   // reject passwords containing the name or email local part
+	BreachedListPath   string // file of breached password SHA-1 hashes; empty disables the check
+}
+
+// I18nConfig holds localization settings
+type I18nConfig struct {
+	DefaultLocale string // used when neither the user nor Accept-Language picks one
+	CookieName    string // cookie holding the locale chosen by the user
+}
+
+// MetricsConfig holds the Prometheus metrics settings
+type MetricsConfig struct {
+	Enabled   bool
+	Path      string // where the API router serves the metrics, unless the admin listener is enabled
+	Namespace string // prefix of every metric name
+}
+
+// TracingConfig holds the OpenTelemetry tracing settings
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string  // OTLP/HTTP collector URL; empty exports to stdout
+	SampleRatio float64 // share of new traces sampled; remote parents decide for their traces
+}
+
+// AdminConfig holds the internal listener serving pprof, build info, the
+// redacted configuration, metrics and the log level
+type AdminConfig struct {
+	Enabled bool
+	Host    string // keep it on loopback or a private interface
+	Port    int
+}
+
 // Load reads configuration from environment variables using Viper
 // .env file is the source of truth, with defaults as fallback
 func Load() (*Config, error) {
@@ -242,6 +388,21 @@ func Load() (*Config, error) {
 	// Set defaults
 	setDefaults(v)
 
+	dataKeys, err := parseVersionedKeys(v.GetString("APP_CRYPTO_DATA_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	accessRoutes, err := parseRouteRatios(v.GetString("APP_LOGGER_SAMPLING_ACCESS_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	routeLimits, err := parseRouteLimits(v.GetString("APP_HTTP_RATELIMIT_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	// Build config struct
 	cfg := &Config{
 		General: GeneralConfig{
@@ -250,7 +411,21 @@ func Load() (*Config, error) {
 			ServiceName: v.GetString("APP_GENERAL_SERVICE_NAME"),
 		},
 		Logger: LoggerConfig{
-			Level: v.GetString("APP_LOGGER_LEVEL"),
+			Level:           v.GetString("APP_LOGGER_LEVEL"),
+			SensitiveFields: parseCommaSeparated(v.GetString("APP_LOGGER_SENSITIVE_FIELDS")),
+			Debug: DebugLoggingConfig{
+				Enabled:    v.GetBool("APP_LOGGER_DEBUG_ENABLED"),
+				HeaderName: v.GetString("APP_LOGGER_DEBUG_HEADER_NAME"),
+				Secret:     v.GetString("APP_LOGGER_DEBUG_SECRET"),
+				MaxTTL:     v.GetDuration("APP_LOGGER_DEBUG_MAX_TTL"),
+			},
+			Sampling: LogSamplingConfig{
+				Enabled:      v.GetBool("APP_LOGGER_SAMPLING_ENABLED"),
+				First:        v.GetInt("APP_LOGGER_SAMPLING_FIRST"),
+				Thereafter:   v.GetInt("APP_LOGGER_SAMPLING_THEREAFTER"),
+				Tick:         v.GetDuration("APP_LOGGER_SAMPLING_TICK"),
+				AccessRoutes: accessRoutes,
+			},
 		},
 		HTTP: HTTPConfig{
 			Host:            v.GetString("APP_HTTP_HOST"),
@@ -285,6 +460,54 @@ func Load() (*Config, error) {
 				Enabled: v.GetBool("APP_HTTP_COMPRESSION_ENABLED"),
 				Level:   v.GetInt("APP_HTTP_COMPRESSION_LEVEL"),
 			},
+			RateLimit: RateLimitConfig{
+				Enabled: v.GetBool("APP_HTTP_RATELIMIT_ENABLED"),
+				Global: RateConfig{
+					Limit:  v.GetInt("APP_HTTP_RATELIMIT_GLOBAL_LIMIT"),
+					Window: v.GetDuration("APP_HTTP_RATELIMIT_GLOBAL_WINDOW"),
+					Burst:  v.GetInt("APP_HTTP_RATELIMIT_GLOBAL_BURST"),
+				},
+				Routes:         routeLimits,
+				TrustedProxies: parseCommaSeparated(v.GetString("APP_HTTP_RATELIMIT_TRUSTED_PROXIES")),
+				Fallback: RateLimitFallbackConfig{
+					Enabled:  v.GetBool("APP_HTTP_RATELIMIT_FALLBACK_ENABLED"),
+					MaxKeys:  v.GetInt("APP_HTTP_RATELIMIT_FALLBACK_MAX_KEYS"),
+					Replicas: v.GetInt("APP_HTTP_RATELIMIT_FALLBACK_REPLICAS"),
+				},
+				Credentials: RateConfig{
+					Limit:  v.GetInt("APP_HTTP_RATELIMIT_CREDENTIALS_LIMIT"),
+					Window: v.GetDuration("APP_HTTP_RATELIMIT_CREDENTIALS_WINDOW"),
+					Burst:  v.GetInt("APP_HTTP_RATELIMIT_CREDENTIALS_BURST"),
+				},
+			},
+			LoadShedding: LoadSheddingConfig{
+				Enabled:          v.GetBool("APP_HTTP_LOADSHED_ENABLED"),
+				InitialLimit:     v.GetInt("APP_HTTP_LOADSHED_INITIAL_LIMIT"),
+				MinLimit:         v.GetInt("APP_HTTP_LOADSHED_MIN_LIMIT"),
+				MaxLimit:         v.GetInt("APP_HTTP_LOADSHED_MAX_LIMIT"),
+				Tolerance:        v.GetFloat64("APP_HTTP_LOADSHED_TOLERANCE"),
+				CriticalPaths:    parseCommaSeparated(v.GetString("APP_HTTP_LOADSHED_CRITICAL_PATHS")),
+				LowPriorityPaths: parseCommaSeparated(v.GetString("APP_HTTP_LOADSHED_LOW_PRIORITY_PATHS")),
+			},
+			Idempotency: IdempotencyConfig{
+				Enabled:    v.GetBool("APP_HTTP_IDEMPOTENCY_ENABLED"),
+				HeaderName: v.GetString("APP_HTTP_IDEMPOTENCY_HEADER_NAME"),
+				TTL:        v.GetDuration("APP_HTTP_IDEMPOTENCY_TTL"),
+				LockTTL:    v.GetDuration("APP_HTTP_IDEMPOTENCY_LOCK_TTL"),
+			},
+			CSRF: CSRFConfig{
+				Enabled:        v.GetBool("APP_HTTP_CSRF_ENABLED"),
+				Secret:         v.GetString("APP_HTTP_CSRF_SECRET"),
+				PreviousSecret: v.GetString("APP_HTTP_CSRF_PREVIOUS_SECRET"),
+				RotatedAt:      v.GetTime("APP_HTTP_CSRF_ROTATED_AT"),
+				CookieName:     v.GetString("APP_HTTP_CSRF_COOKIE_NAME"),
+				HeaderName:     v.GetString("APP_HTTP_CSRF_HEADER_NAME"),
+				TTL:            v.GetDuration("APP_HTTP_CSRF_TTL"),
+				Exempt:         parseCommaSeparated(v.GetString("APP_HTTP_CSRF_EXEMPT")),
+				SessionCookie:  v.GetString("APP_HTTP_CSRF_SESSION_COOKIE"),
+				CheckOrigin:    v.GetBool("APP_HTTP_CSRF_CHECK_ORIGIN"),
+				TrustedOrigins: parseCommaSeparated(v.GetString("APP_HTTP_CSRF_TRUSTED_ORIGINS")),
+			},
 			TLS: TLSConfig{
 				Enabled:     v.GetBool("APP_SERVER_API_TLS_ENABLED"),
 				CertFile:    v.GetString("APP_SERVER_API_TLS_CERT_FILE"),
@@ -379,6 +602,65 @@ func Load() (*Config, error) {
 			RefreshTokenTTL: v.GetDuration("APP_JWT_REFRESH_TOKEN_TTL"),
 			Issuer:          v.GetString("APP_JWT_ISSUER"),
 		},
+		Tenant: TenantConfig{
+			Enabled:    v.GetBool("APP_TENANT_ENABLED"),
+			Header:     v.GetString("APP_TENANT_HEADER"),
+			Claim:      v.GetString("APP_TENANT_CLAIM"),
+			BaseDomain: v.GetString("APP_TENANT_BASE_DOMAIN"),
+			DefaultID:  v.GetString("APP_TENANT_DEFAULT_ID"),
+		},
+		Privacy: PrivacyConfig{
+			PurgeEnabled:    v.GetBool("APP_PRIVACY_PURGE_ENABLED"),
+			RetentionPeriod: v.GetDuration("APP_PRIVACY_RETENTION_PERIOD"),
+			PurgeInterval:   v.GetDuration("APP_PRIVACY_PURGE_INTERVAL"),
+			PurgeBatchSize:  v.GetInt("APP_PRIVACY_PURGE_BATCH_SIZE"),
+		},
+		Crypto: CryptoConfig{
+			DataKeys:           dataKeys,
+			ActiveKeyVersion:   v.GetInt("APP_CRYPTO_ACTIVE_KEY_VERSION"),
+			BlindIndexKey:      v.GetString("APP_CRYPTO_BLIND_INDEX_KEY"),
+			ReencryptEnabled:   v.GetBool("APP_CRYPTO_REENCRYPT_ENABLED"),
+			ReencryptInterval:  v.GetDuration("APP_CRYPTO_REENCRYPT_INTERVAL"),
+			ReencryptBatchSize: v.GetInt("APP_CRYPTO_REENCRYPT_BATCH_SIZE"),
+		},
+		Password: PasswordConfig{
+			Argon2Memory:       v.GetUint32("APP_PASSWORD_ARGON2_MEMORY"),
+			Argon2Iterations:   v.GetUint32("APP_PASSWORD_ARGON2_ITERATIONS"),
+			Argon2Parallelism:  v.GetUint8("APP_PASSWORD_ARGON2_PARALLELISM"),
+			LegacyBcrypt:       v.GetBool("APP_PASSWORD_LEGACY_BCRYPT"),
+			MinLength:          v.GetInt("APP_PASSWORD_MIN_LENGTH"),
+			MaxLength:          v.GetInt("APP_PASSWORD_MAX_LENGTH"),
+			RequireUppercase:   v.GetBool("APP_PASSWORD_REQUIRE_UPPERCASE"),
+			RequireLowercase:   v.GetBool("APP_PASSWORD_REQUIRE_LOWERCASE"),
+			RequireDigit:       v.GetBool("APP_PASSWORD_REQUIRE_DIGIT"),
+			RequireSymbol:      v.GetBool("APP_PASSWORD_REQUIRE_SYMBOL"),
+			MaxRepeated:        v.GetInt("APP_PASSWORD_MAX_REPEATED"),
+			ForbidPersonalInfo: v.GetBool("APP_PASSWORD_FORBID_PERSONAL_INFO"),
+			BreachedListPath:   v.GetString("APP_PASSWORD_BREACHED_LIST_PATH"),
+		},
+		I18n: I18nConfig{
+			DefaultLocale: v.GetString("APP_I18N_DEFAULT_LOCALE"),
+			CookieName:    v.GetString("APP_I18N_COOKIE_NAME"),
+		},
+		Metrics: MetricsConfig{
+			Enabled:   v.GetBool("APP_METRICS_ENABLED"),
+			Path:      v.GetString("APP_METRICS_PATH"),
+			Namespace: v.GetString("APP_METRICS_NAMESPACE"),
+		},
+		Tracing: TracingConfig{
+			Enabled:     v.GetBool("APP_TRACING_ENABLED"),
+			Endpoint:    v.GetString("APP_TRACING_ENDPOINT"),
+			SampleRatio: v.GetFloat64("APP_TRACING_SAMPLE_RATIO"),
+		},
+		Admin: AdminConfig{
+			Enabled: v.GetBool("APP_ADMIN_ENABLED"),
+			Host:    v.GetString("APP_ADMIN_HOST"),
+			Port:    v.GetInt("APP_ADMIN_PORT"),
+		},
+	}
+
+	if cfg.IsDevelopment() {
+		cfg.Crypto.useDevelopmentCryptoKeys()
 	}
 
 	// Validate configuration
@@ -389,6 +671,31 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// developmentCryptoSeed derives the development crypto keys. It is public,
+// so the keys protect nothing: they only keep development data readable
+// across restarts.
+const developmentCryptoSeed = "course-development-crypto-keys"
+
+// useDevelopmentCryptoKeys lets development run without APP_CRYPTO_DATA_KEYS
+// and APP_CRYPTO_BLIND_INDEX_KEY by deriving them from a fixed seed, so rows
+// encrypted and indexed by earlier runs stay readable. Every other
+// environment must set both.
+func (c *CryptoConfig) useDevelopmentCryptoKeys() {
+	if len(c.DataKeys) == 0 {
+		c.DataKeys = map[int]string{c.ActiveKeyVersion: developmentKey("data-key-" + strconv.Itoa(c.ActiveKeyVersion))}
+	}
+	if c.BlindIndexKey == "" {
+		c.BlindIndexKey = developmentKey("blind-index-key")
+	}
+}
+
+// developmentKey derives a 32-byte base64 key for purpose from the
+// development seed
+func developmentKey(purpose string) string {
+	sum := sha256.Sum256([]byte(developmentCryptoSeed + ":" + purpose))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
 // setDefaults configures default values for all settings
 func setDefaults(v *viper.Viper) {
 	// General defaults
@@ -398,6 +705,16 @@ func setDefaults(v *viper.Viper) {
 
 	// Logger defaults
 	v.SetDefault("APP_LOGGER_LEVEL", "info")
+	v.SetDefault("APP_LOGGER_SENSITIVE_FIELDS", "")
+	v.SetDefault("APP_LOGGER_DEBUG_ENABLED", false)
+	v.SetDefault("APP_LOGGER_DEBUG_HEADER_NAME", "X-Debug-Token")
+	v.SetDefault("APP_LOGGER_DEBUG_SECRET", "")
+	v.SetDefault("APP_LOGGER_DEBUG_MAX_TTL", "1h")
+	v.SetDefault("APP_LOGGER_SAMPLING_ENABLED", false)
+	v.SetDefault("APP_LOGGER_SAMPLING_FIRST", 100)
+	v.SetDefault("APP_LOGGER_SAMPLING_THEREAFTER", 100)
+	v.SetDefault("APP_LOGGER_SAMPLING_TICK", "1s")
+	v.SetDefault("APP_LOGGER_SAMPLING_ACCESS_ROUTES", "")
 
 	// Server API defaults
 	v.SetDefault("APP_SERVER_API_HOST", "0.0.0.0")
@@ -456,6 +773,45 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("APP_HTTP_COMPRESSION_ENABLED", true)
 	v.SetDefault("APP_HTTP_COMPRESSION_LEVEL", 5)
 
+	// Rate limit defaults; no proxy is trusted unless configured, so
+	// forwarding headers are ignored by default
+	v.SetDefault("APP_HTTP_RATELIMIT_ENABLED", false)
+	v.SetDefault("APP_HTTP_RATELIMIT_GLOBAL_LIMIT", 1000)
+	v.SetDefault("APP_HTTP_RATELIMIT_GLOBAL_WINDOW", "1s")
+	v.SetDefault("APP_HTTP_RATELIMIT_GLOBAL_BURST", 1500)
+	v.SetDefault("APP_HTTP_RATELIMIT_ROUTES", "")
+	v.SetDefault("APP_HTTP_RATELIMIT_TRUSTED_PROXIES", "")
+	v.SetDefault("APP_HTTP_RATELIMIT_FALLBACK_ENABLED", true)
+	v.SetDefault("APP_HTTP_RATELIMIT_FALLBACK_MAX_KEYS", 100000)
+	v.SetDefault("APP_HTTP_RATELIMIT_FALLBACK_REPLICAS", 1)
+	v.SetDefault("APP_HTTP_RATELIMIT_CREDENTIALS_LIMIT", 5)
+	v.SetDefault("APP_HTTP_RATELIMIT_CREDENTIALS_WINDOW", "15m")
+	v.SetDefault("APP_HTTP_RATELIMIT_CREDENTIALS_BURST", 5)
+
+	// Load shedding defaults
+	v.SetDefault("APP_HTTP_LOADSHED_ENABLED", false)
+	v.SetDefault("APP_HTTP_LOADSHED_INITIAL_LIMIT", 100)
+	v.SetDefault("APP_HTTP_LOADSHED_MIN_LIMIT", 10)
+	v.SetDefault("APP_HTTP_LOADSHED_MAX_LIMIT", 1000)
+	v.SetDefault("APP_HTTP_LOADSHED_TOLERANCE", 2.0)
+	v.SetDefault("APP_HTTP_LOADSHED_CRITICAL_PATHS", "/health,/ping")
+	v.SetDefault("APP_HTTP_LOADSHED_LOW_PRIORITY_PATHS", "")
+
+	// Idempotency defaults
+	v.SetDefault("APP_HTTP_IDEMPOTENCY_ENABLED", false)
+	v.SetDefault("APP_HTTP_IDEMPOTENCY_HEADER_NAME", "Idempotency-Key")
+	v.SetDefault("APP_HTTP_IDEMPOTENCY_TTL", "24h")
+	v.SetDefault("APP_HTTP_IDEMPOTENCY_LOCK_TTL", "1m")
+
+	// CSRF defaults
+	v.SetDefault("APP_HTTP_CSRF_ENABLED", false)
+	v.SetDefault("APP_HTTP_CSRF_COOKIE_NAME", "csrf_token")
+	v.SetDefault("APP_HTTP_CSRF_HEADER_NAME", "X-CSRF-Token")
+	v.SetDefault("APP_HTTP_CSRF_TTL", "24h")
+	v.SetDefault("APP_HTTP_CSRF_EXEMPT", "/health,/ping")
+	v.SetDefault("APP_HTTP_CSRF_SESSION_COOKIE", "session_id")
+	v.SetDefault("APP_HTTP_CSRF_CHECK_ORIGIN", false)
+
 	// Database connect defaults
 	v.SetDefault("APP_DB_CONNECT_QUERY_TIMEOUT", "5s")
 	v.SetDefault("APP_DB_CONNECT_EXEC_TIMEOUT", "5s")
@@ -511,6 +867,59 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("APP_JWT_ACCESS_TOKEN_TTL", "15m")
 	v.SetDefault("APP_JWT_REFRESH_TOKEN_TTL", "168h") // 7 days
 	v.SetDefault("APP_JWT_ISSUER", "course-api")
+
+	// Tenant defaults
+	v.SetDefault("APP_TENANT_ENABLED", false)
+	v.SetDefault("APP_TENANT_HEADER", "X-Tenant-ID")
+	v.SetDefault("APP_TENANT_CLAIM", "tenant_id")
+	v.SetDefault("APP_TENANT_BASE_DOMAIN", "")
+	v.SetDefault("APP_TENANT_DEFAULT_ID", "00000000-0000-0000-0000-000000000000")
+
+	// Privacy defaults
+	v.SetDefault("APP_PRIVACY_PURGE_ENABLED", true)
+	v.SetDefault("APP_PRIVACY_RETENTION_PERIOD", "2160h") // 90 days
+	v.SetDefault("APP_PRIVACY_PURGE_INTERVAL", "1h")
+	v.SetDefault("APP_PRIVACY_PURGE_BATCH_SIZE", 500)
+
+	// Crypto defaults (keys have none; see useDevelopmentCryptoKeys)
+	v.SetDefault("APP_CRYPTO_ACTIVE_KEY_VERSION", 1)
+	v.SetDefault("APP_CRYPTO_REENCRYPT_ENABLED", true)
+	v.SetDefault("APP_CRYPTO_REENCRYPT_INTERVAL", "1h")
+	v.SetDefault("APP_CRYPTO_REENCRYPT_BATCH_SIZE", 500)
+
+	// Password defaults
+	v.SetDefault("APP_PASSWORD_ARGON2_MEMORY", 64*1024)
+	v.SetDefault("APP_PASSWORD_ARGON2_ITERATIONS", 3)
+	v.SetDefault("APP_PASSWORD_ARGON2_PARALLELISM", 4)
+	v.SetDefault("APP_PASSWORD_LEGACY_BCRYPT", false)
+	v.SetDefault("APP_PASSWORD_MIN_LENGTH", 8)
+	v.SetDefault("APP_PASSWORD_MAX_LENGTH", 72)
+	v.SetDefault("APP_PASSWORD_REQUIRE_UPPERCASE", true)
+	v.SetDefault("APP_PASSWORD_REQUIRE_LOWERCASE", true)
+	v.SetDefault("APP_PASSWORD_REQUIRE_DIGIT", true)
+	v.SetDefault("APP_PASSWORD_REQUIRE_SYMBOL", true)
+	v.SetDefault("APP_PASSWORD_MAX_REPEATED", 3)
+	v.SetDefault("APP_PASSWORD_FORBID_PERSONAL_INFO", true)
+	v.SetDefault("APP_PASSWORD_BREACHED_LIST_PATH", "")
+
+	// I18n defaults
+	v.SetDefault("APP_I18N_DEFAULT_LOCALE", "en")
+	v.SetDefault("APP_I18N_COOKIE_NAME", "locale")
+
+	// Metrics defaults
+	v.SetDefault("APP_METRICS_ENABLED", false)
+	v.SetDefault("APP_METRICS_PATH", "/metrics")
+	v.SetDefault("APP_METRICS_NAMESPACE", "course")
+
+	// Tracing defaults
+	v.SetDefault("APP_TRACING_ENABLED", false)
+	v.SetDefault("APP_TRACING_ENDPOINT", "")
+	v.SetDefault("APP_TRACING_SAMPLE_RATIO", 1.0)
+
+	// Admin defaults
+	v.SetDefault("APP_ADMIN_ENABLED", false)
+	v.SetDefault("APP_ADMIN_HOST", "127.0.0.1")
+	v.SetDefault("APP_ADMIN_PORT", 9090)
 }
 
 // Validate checks if the configuration is valid
@@ -537,6 +946,34 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.Logger.Level)
 	}
 
+	// Validate debug logging tokens
+	if c.Logger.Debug.Enabled {
+		if len(c.Logger.Debug.Secret) < 32 {
+			return fmt.Errorf("debug logging secret must have at least 32 characters")
+		}
+		if c.Logger.Debug.HeaderName == "" {
+			return fmt.Errorf("debug logging header name is required")
+		}
+		if c.Logger.Debug.MaxTTL <= 0 {
+			return fmt.Errorf("debug logging max ttl must be positive")
+		}
+	}
+
+	// Validate log sampling
+	if c.Logger.Sampling.Enabled {
+		if c.Logger.Sampling.First < 0 || c.Logger.Sampling.Thereafter < 0 {
+			return fmt.Errorf("log sampling first and thereafter must not be negative")
+		}
+		if c.Logger.Sampling.Tick <= 0 {
+			return fmt.Errorf("log sampling tick must be positive")
+		}
+		for route, ratio := range c.Logger.Sampling.AccessRoutes {
+			if ratio < 0 || ratio > 1 {
+				return fmt.Errorf("access log sampling ratio for %s must be between 0 and 1", route)
+			}
+		}
+	}
+
 	// Validate server port
 	if c.Server.API.Port < 1 || c.Server.API.Port > 65535 {
 		return fmt.Errorf("invalid API port: %d (must be between 1 and 65535)", c.Server.API.Port)
@@ -613,6 +1050,170 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT issuer is required")
 	}
 
+	// Validate tenant configuration
+	if c.Tenant.Enabled {
+		if c.Tenant.Header == "" && c.Tenant.Claim == "" && c.Tenant.BaseDomain == "" && c.Tenant.DefaultID == "" {
+			return fmt.Errorf("tenant resolution requires a header, claim, base domain or default id")
+		}
+	} else if c.Tenant.DefaultID == "" {
+		// Every transaction runs as the default tenant (see pkg/database BeginTx)
+		return fmt.Errorf("tenant default id is required when multi-tenancy is disabled")
+	}
+	if c.Tenant.DefaultID != "" {
+		if _, err := uuid.Parse(c.Tenant.DefaultID); err != nil {
+			return fmt.Errorf("invalid tenant default id: %s (must be a UUID)", c.Tenant.DefaultID)
+		}
+	}
+
+	// Validate rate limits
+	if c.HTTP.RateLimit.Enabled {
+		if err := validateRateConfig("global", c.HTTP.RateLimit.Global); err != nil {
+			return err
+		}
+		for route, rate := range c.HTTP.RateLimit.Routes {
+			if err := validateRateConfig(route, rate); err != nil {
+				return err
+			}
+		}
+		if c.HTTP.RateLimit.Fallback.Enabled {
+			if c.HTTP.RateLimit.Fallback.MaxKeys <= 0 {
+				return fmt.Errorf("rate limit fallback max keys must be positive")
+			}
+			if c.HTTP.RateLimit.Fallback.Replicas <= 0 {
+				return fmt.Errorf("rate limit fallback replicas must be positive")
+			}
+		}
+	}
+	if err := validateRateConfig("credentials", c.HTTP.RateLimit.Credentials); err != nil {
+		return err
+	}
+
+	// Validate load shedding
+	if ls := c.HTTP.LoadShedding; ls.Enabled {
+		if ls.MinLimit <= 0 || ls.MinLimit > ls.InitialLimit || ls.InitialLimit > ls.MaxLimit {
+			return fmt.Errorf("load shedding limits must satisfy 0 < min <= initial <= max")
+		}
+		if ls.Tolerance <= 1 {
+			return fmt.Errorf("load shedding tolerance must be greater than 1")
+		}
+	}
+
+	// Validate trusted proxies; an invalid entry would silently trust nothing
+	for _, proxy := range c.HTTP.RateLimit.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy: %s (must be an IP or CIDR)", proxy)
+		}
+	}
+
+	// Validate idempotency
+	if idem := c.HTTP.Idempotency; idem.Enabled {
+		if idem.HeaderName == "" {
+			return fmt.Errorf("idempotency header name is required")
+		}
+		if idem.LockTTL <= 0 || idem.TTL < idem.LockTTL {
+			return fmt.Errorf("idempotency ttls must satisfy 0 < lock ttl <= ttl")
+		}
+	}
+
+	// Validate CSRF configuration
+	if c.HTTP.CSRF.Enabled {
+		if len(c.HTTP.CSRF.Secret) < 32 {
+			return fmt.Errorf("csrf secret must have at least 32 characters")
+		}
+		if c.HTTP.CSRF.TTL <= 0 {
+			return fmt.Errorf("csrf ttl must be positive")
+		}
+		if c.HTTP.CSRF.SessionCookie == "" || c.HTTP.CSRF.SessionCookie == c.HTTP.CSRF.CookieName {
+			return fmt.Errorf("csrf session cookie is required and must differ from the csrf cookie")
+		}
+		if c.HTTP.CSRF.PreviousSecret != "" && c.HTTP.CSRF.RotatedAt.IsZero() {
+			return fmt.Errorf("csrf rotated at is required with a previous secret")
+		}
+	}
+
+	// Validate crypto configuration
+	if len(c.Crypto.DataKeys) == 0 {
+		return fmt.Errorf("crypto data keys are required")
+	}
+	if _, ok := c.Crypto.DataKeys[c.Crypto.ActiveKeyVersion]; !ok {
+		return fmt.Errorf("crypto active key version %d has no data key", c.Crypto.ActiveKeyVersion)
+	}
+	if c.Crypto.BlindIndexKey == "" {
+		return fmt.Errorf("crypto blind index key is required")
+	}
+	if c.Crypto.ReencryptEnabled {
+		if c.Crypto.ReencryptInterval <= 0 {
+			return fmt.Errorf("crypto re-encryption interval must be positive")
+		}
+		if c.Crypto.ReencryptBatchSize <= 0 {
+			return fmt.Errorf("crypto re-encryption batch size must be positive")
+		}
+	}
+
+	// Validate password configuration
+	if c.Password.Argon2Memory < 8*uint32(c.Password.Argon2Parallelism) {
+		return fmt.Errorf("password argon2 memory must be at least 8 KiB per lane")
+	}
+	if c.Password.Argon2Iterations == 0 {
+		return fmt.Errorf("password argon2 iterations must be positive")
+	}
+	if c.Password.Argon2Parallelism == 0 {
+		return fmt.Errorf("password argon2 parallelism must be positive")
+	}
+	if c.Password.MinLength <= 0 {
+		return fmt.Errorf("password min length must be positive")
+	}
+	// Argon2 accepts any length, but the register input caps it at 72
+	if c.Password.MaxLength < c.Password.MinLength || c.Password.MaxLength > 72 {
+		return fmt.Errorf("password max length must be between min length and 72")
+	}
+	if c.Password.MaxRepeated < 0 {
+		return fmt.Errorf("password max repeated must not be negative")
+	}
+
+	// Validate privacy configuration
+	if c.Privacy.PurgeEnabled {
+		if c.Privacy.RetentionPeriod <= 0 {
+			return fmt.Errorf("privacy retention period must be positive")
+		}
+		if c.Privacy.PurgeInterval <= 0 {
+			return fmt.Errorf("privacy purge interval must be positive")
+		}
+		if c.Privacy.PurgeBatchSize <= 0 {
+			return fmt.Errorf("privacy purge batch size must be positive")
+		}
+	}
+
+	// Validate metrics configuration
+	if c.Metrics.Enabled {
+		if !strings.HasPrefix(c.Metrics.Path, "/") {
+			return fmt.Errorf("metrics path must start with /")
+		}
+		if !isMetricName(c.Metrics.Namespace) {
+			return fmt.Errorf("invalid metrics namespace: %s (letters, digits and underscores only)", c.Metrics.Namespace)
+		}
+	}
+
+	// Validate tracing configuration
+	if c.Tracing.Enabled {
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+		}
+		if c.Tracing.Endpoint != "" && !strings.HasPrefix(c.Tracing.Endpoint, "http://") && !strings.HasPrefix(c.Tracing.Endpoint, "https://") {
+			return fmt.Errorf("invalid tracing endpoint: %s (must be an http or https URL)", c.Tracing.Endpoint)
+		}
+	}
+
+	// Validate admin configuration
+	if c.Admin.Enabled {
+		if c.Admin.Port < 1 || c.Admin.Port > 65535 {
+			return fmt.Errorf("invalid admin port: %d (must be between 1 and 65535)", c.Admin.Port)
+		}
+		if c.Admin.Port == c.HTTP.Port {
+			return fmt.Errorf("admin port must differ from the HTTP port %d", c.HTTP.Port)
+		}
+	}
+
 	return nil
 }
 
@@ -699,3 +1300,121 @@ func parseCommaSeparated(s string) []string {
 	}
 	return result
 }
+
+// parseRouteLimits parses "route=limit/window[/burst]" entries separated by
+// commas, e.g. "POST /api/v1/users=5/1m,/api/v1/users/{id}=100/1s/150".
+// Routes are chi patterns, optionally prefixed by a method.
+func parseRouteLimits(s string) (map[string]RateConfig, error) {
+	routes := make(map[string]RateConfig)
+	for _, entry := range parseCommaSeparated(s) {
+		route, rate, found := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !found || route == "" {
+			return nil, fmt.Errorf("invalid route rate limit %q (expected route=limit/window[/burst])", entry)
+		}
+
+		parts := strings.Split(strings.TrimSpace(rate), "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid route rate limit %q (expected route=limit/window[/burst])", entry)
+		}
+
+		limit, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit for route %s: %q", route, parts[0])
+		}
+		window, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate window for route %s: %q", route, parts[1])
+		}
+		burst := limit
+		if len(parts) == 3 {
+			if burst, err = strconv.Atoi(parts[2]); err != nil {
+				return nil, fmt.Errorf("invalid rate burst for route %s: %q", route, parts[2])
+			}
+		}
+
+		if _, exists := routes[route]; exists {
+			return nil, fmt.Errorf("duplicate route rate limit %s", route)
+		}
+		routes[route] = RateConfig{Limit: limit, Window: window, Burst: burst}
+	}
+	return routes, nil
+}
+
+// parseRouteRatios parses "route=ratio" entries separated by commas, e.g.
+// "/health=0,GET /api/v1/users/{id}=0.1". Routes are chi patterns,
+// optionally prefixed by a method.
+func parseRouteRatios(s string) (map[string]float64, error) {
+	routes := make(map[string]float64)
+	for _, entry := range parseCommaSeparated(s) {
+		route, ratioText, found := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !found || route == "" {
+			return nil, fmt.Errorf("invalid route ratio %q (expected route=ratio)", entry)
+		}
+
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(ratioText), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ratio for route %s: %q", route, ratioText)
+		}
+
+		if _, exists := routes[route]; exists {
+			return nil, fmt.Errorf("duplicate route ratio %s", route)
+		}
+		routes[route] = ratio
+	}
+	return routes, nil
+}
+
+func validateRateConfig(scope string, rate RateConfig) error {
+	if rate.Limit <= 0 {
+		return fmt.Errorf("rate limit for %s must be positive", scope)
+	}
+	if rate.Window <= 0 {
+		return fmt.Errorf("rate window for %s must be positive", scope)
+	}
+	if rate.Burst <= 0 {
+		return fmt.Errorf("rate burst for %s must be positive", scope)
+	}
+	return nil
+}
+
+// parseVersionedKeys parses "version:key" pairs separated by commas,
+// e.g. "1:base64key,2:base64key"
+func parseVersionedKeys(s string) (map[int]string, error) {
+	keys := make(map[int]string)
+	for _, pair := range parseCommaSeparated(s) {
+		versionText, key, found := strings.Cut(pair, ":")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid versioned key %q (expected version:key)", pair)
+		}
+
+		version, err := strconv.Atoi(strings.TrimSpace(versionText))
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid key version %q (must be a positive integer)", versionText)
+		}
+		if _, exists := keys[version]; exists {
+			return nil, fmt.Errorf("duplicate key version %d", version)
+		}
+
+		keys[version] = strings.TrimSpace(key)
+	}
+	return keys, nil
+}
+
+// isMetricName reports whether name is a valid Prometheus metric name
+// prefix: a letter or underscore followed by letters, digits or underscores
+func isMetricName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package config_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/marcelofabianov/course/config"
 )
@@ -43,15 +44,18 @@ func TestLoad(t *testing.T) {
 		{
 			name: "load with custom values",
 			envVars: map[string]string{
-				"APP_GENERAL_ENV":     "production",
-				"APP_SERVER_API_PORT": "9000",
-				"APP_LOGGER_LEVEL":    "warn",
-				"APP_DB_HOST":         "prod-db.example.com",
-				"APP_DB_PORT":         "5432",
-				"APP_DB_NAME":         "proddb",
-				"APP_DB_USER":         "produser",
-				"APP_DB_PASSWORD":     "prodpass",
-				"APP_REDIS_HOST":      "redis.example.com",
+				"APP_GENERAL_ENV":             "production",
+				"APP_SERVER_API_PORT":         "9000",
+				"APP_LOGGER_LEVEL":            "warn",
+				"APP_DB_HOST":                 "prod-db.example.com",
+				"APP_DB_PORT":                 "5432",
+				"APP_DB_NAME":                 "proddb",
+				"APP_DB_USER":                 "produser",
+				"APP_DB_PASSWORD":             "prodpass",
+				"APP_REDIS_HOST":              "redis.example.com",
+				"APP_LOGGER_SENSITIVE_FIELDS": "cpf, birth_date",
+				"APP_CRYPTO_DATA_KEYS":        "1:OR5eiHFkdjWXLue8DCZ9HMBqYKC9OYj29p2H+Buf2H8=",
+				"APP_CRYPTO_BLIND_INDEX_KEY":  "JyazSXxTKPxrF3vUxk8tuqJHiXVzOwTZyPIoYOIFzuk=",
 			},
 			wantErr: false,
 			check: func(t *testing.T, cfg *config.Config) {
@@ -64,6 +68,9 @@ func TestLoad(t *testing.T) {
 				if cfg.Logger.Level != "warn" {
 					t.Errorf("Expected log level 'warn', got '%s'", cfg.Logger.Level)
 				}
+				if len(cfg.Logger.SensitiveFields) != 2 || cfg.Logger.SensitiveFields[1] != "birth_date" {
+					t.Errorf("Expected sensitive fields [cpf birth_date], got %v", cfg.Logger.SensitiveFields)
+				}
 			},
 		},
 		{
@@ -86,6 +93,315 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid tenant default id",
+			envVars: map[string]string{
+				"APP_TENANT_ENABLED":    "true",
+				"APP_TENANT_DEFAULT_ID": "acme",
+				"APP_DB_USER":           "testuser",
+				"APP_DB_NAME":           "testdb",
+				"APP_REDIS_HOST":        "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tenant default id with tenancy disabled",
+			envVars: map[string]string{
+				"APP_TENANT_DEFAULT_ID": "acme",
+				"APP_DB_USER":           "testuser",
+				"APP_DB_NAME":           "testdb",
+				"APP_REDIS_HOST":        "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "tenant enabled with defaults",
+			envVars: map[string]string{
+				"APP_TENANT_ENABLED": "true",
+				"APP_DB_USER":        "testuser",
+				"APP_DB_NAME":        "testdb",
+				"APP_REDIS_HOST":     "localhost",
+			},
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				if !cfg.Tenant.Enabled {
+					t.Errorf("Expected tenant enabled")
+				}
+				if cfg.Tenant.Header != "X-Tenant-ID" {
+					t.Errorf("Expected tenant header 'X-Tenant-ID', got '%s'", cfg.Tenant.Header)
+				}
+			},
+		},
+		{
+			name: "crypto active key version without key",
+			envVars: map[string]string{
+				"APP_CRYPTO_DATA_KEYS":          "1:OR5eiHFkdjWXLue8DCZ9HMBqYKC9OYj29p2H+Buf2H8=",
+				"APP_CRYPTO_ACTIVE_KEY_VERSION": "2",
+				"APP_DB_USER":                   "testuser",
+				"APP_DB_NAME":                   "testdb",
+				"APP_REDIS_HOST":                "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "crypto rotated keys",
+			envVars: map[string]string{
+				"APP_CRYPTO_DATA_KEYS":          "1:OR5eiHFkdjWXLue8DCZ9HMBqYKC9OYj29p2H+Buf2H8=, 2:HZReP7yAgQV8KC9yc82I0EmWOINZhVZI8fJJ/O/hSpw=",
+				"APP_CRYPTO_ACTIVE_KEY_VERSION": "2",
+				"APP_DB_USER":                   "testuser",
+				"APP_DB_NAME":                   "testdb",
+				"APP_REDIS_HOST":                "localhost",
+			},
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				if len(cfg.Crypto.DataKeys) != 2 {
+					t.Errorf("Expected 2 data keys, got %d", len(cfg.Crypto.DataKeys))
+				}
+				if cfg.Crypto.DataKeys[2] != "HZReP7yAgQV8KC9yc82I0EmWOINZhVZI8fJJ/O/hSpw=" {
+					t.Errorf("Unexpected key for version 2: '%s'", cfg.Crypto.DataKeys[2])
+				}
+			},
+		},
+		{
+			name: "crypto keys are required outside development",
+			envVars: map[string]string{
+				"APP_GENERAL_ENV": "production",
+				"APP_DB_USER":     "testuser",
+				"APP_DB_NAME":     "testdb",
+				"APP_REDIS_HOST":  "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "crypto keys are derived in development",
+			envVars: map[string]string{
+				"APP_DB_USER":    "testuser",
+				"APP_DB_NAME":    "testdb",
+				"APP_REDIS_HOST": "localhost",
+			},
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.Crypto.DataKeys[cfg.Crypto.ActiveKeyVersion] == "" {
+					t.Errorf("Expected a data key for version %d", cfg.Crypto.ActiveKeyVersion)
+				}
+				if cfg.Crypto.BlindIndexKey == "" {
+					t.Errorf("Expected a blind index key")
+				}
+
+				// Data from earlier runs must stay readable
+				again, err := config.Load()
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if again.Crypto.DataKeys[1] != cfg.Crypto.DataKeys[1] || again.Crypto.BlindIndexKey != cfg.Crypto.BlindIndexKey {
+					t.Errorf("Expected the same development keys on every load")
+				}
+			},
+		},
+		{
+			name: "invalid password argon2 iterations",
+			envVars: map[string]string{
+				"APP_PASSWORD_ARGON2_ITERATIONS": "0",
+				"APP_DB_USER":                    "testuser",
+				"APP_DB_NAME":                    "testdb",
+				"APP_REDIS_HOST":                 "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid password length range",
+			envVars: map[string]string{
+				"APP_PASSWORD_MIN_LENGTH": "12",
+				"APP_PASSWORD_MAX_LENGTH": "10",
+				"APP_DB_USER":             "testuser",
+				"APP_DB_NAME":             "testdb",
+				"APP_REDIS_HOST":          "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "short csrf secret",
+			envVars: map[string]string{
+				"APP_HTTP_CSRF_ENABLED": "true",
+				"APP_HTTP_CSRF_SECRET":  "too-short",
+				"APP_DB_USER":           "testuser",
+				"APP_DB_NAME":           "testdb",
+				"APP_REDIS_HOST":        "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "csrf session cookie shared with the token cookie",
+			envVars: map[string]string{
+				"APP_HTTP_CSRF_ENABLED":        "true",
+				"APP_HTTP_CSRF_SECRET":         "a-csrf-secret-with-at-least-32-characters",
+				"APP_HTTP_CSRF_SESSION_COOKIE": "csrf_token",
+				"APP_DB_USER":                  "testuser",
+				"APP_DB_NAME":                  "testdb",
+				"APP_REDIS_HOST":               "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid trusted proxy",
+			envVars: map[string]string{
+				"APP_HTTP_RATELIMIT_TRUSTED_PROXIES": "10.0.0.0/8,not-an-ip",
+				"APP_DB_USER":                        "testuser",
+				"APP_DB_NAME":                        "testdb",
+				"APP_REDIS_HOST":                     "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "route rate limits",
+			envVars: map[string]string{
+				"APP_HTTP_RATELIMIT_ROUTES": "POST /api/v1/users=5/1m, /api/v1/users/{id}/export=10/1h/2",
+				"APP_DB_USER":               "testuser",
+				"APP_DB_NAME":               "testdb",
+				"APP_REDIS_HOST":            "localhost",
+			},
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				register := cfg.HTTP.RateLimit.Routes["POST /api/v1/users"]
+				if register.Limit != 5 || register.Window != time.Minute || register.Burst != 5 {
+					t.Errorf("Unexpected register rate limit: %+v", register)
+				}
+				export := cfg.HTTP.RateLimit.Routes["/api/v1/users/{id}/export"]
+				if export.Limit != 10 || export.Window != time.Hour || export.Burst != 2 {
+					t.Errorf("Unexpected export rate limit: %+v", export)
+				}
+			},
+		},
+		{
+			name: "invalid route rate limit",
+			envVars: map[string]string{
+				"APP_HTTP_RATELIMIT_ROUTES": "POST /api/v1/users=5",
+				"APP_DB_USER":               "testuser",
+				"APP_DB_NAME":               "testdb",
+				"APP_REDIS_HOST":            "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid credentials rate limit with rate limiting disabled",
+			envVars: map[string]string{
+				"APP_HTTP_RATELIMIT_ENABLED":           "false",
+				"APP_HTTP_RATELIMIT_CREDENTIALS_LIMIT": "0",
+				"APP_DB_USER":                          "testuser",
+				"APP_DB_NAME":                          "testdb",
+				"APP_REDIS_HOST":                       "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "access log sampling routes",
+			envVars: map[string]string{
+				"APP_LOGGER_SAMPLING_ENABLED":       "true",
+				"APP_LOGGER_SAMPLING_ACCESS_ROUTES": "/health=0, GET  /api/v1/users/{id}=0.1",
+				"APP_DB_USER":                       "testuser",
+				"APP_DB_NAME":                       "testdb",
+				"APP_REDIS_HOST":                    "localhost",
+			},
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				routes := cfg.Logger.Sampling.AccessRoutes
+				if len(routes) != 2 || routes["/health"] != 0 || routes["GET /api/v1/users/{id}"] != 0.1 {
+					t.Errorf("Unexpected access log sampling routes: %v", routes)
+				}
+				if cfg.Logger.Sampling.First != 100 || cfg.Logger.Sampling.Tick != time.Second {
+					t.Errorf("Unexpected log sampling defaults: %+v", cfg.Logger.Sampling)
+				}
+			},
+		},
+		{
+			name: "invalid access log sampling ratio",
+			envVars: map[string]string{
+				"APP_LOGGER_SAMPLING_ENABLED":       "true",
+				"APP_LOGGER_SAMPLING_ACCESS_ROUTES": "/health=2",
+				"APP_DB_USER":                       "testuser",
+				"APP_DB_NAME":                       "testdb",
+				"APP_REDIS_HOST":                    "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid load shedding limits",
+			envVars: map[string]string{
+				"APP_HTTP_LOADSHED_ENABLED":       "true",
+				"APP_HTTP_LOADSHED_INITIAL_LIMIT": "5",
+				"APP_DB_USER":                     "testuser",
+				"APP_DB_NAME":                     "testdb",
+				"APP_REDIS_HOST":                  "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid idempotency ttls",
+			envVars: map[string]string{
+				"APP_HTTP_IDEMPOTENCY_ENABLED":  "true",
+				"APP_HTTP_IDEMPOTENCY_TTL":      "30s",
+				"APP_HTTP_IDEMPOTENCY_LOCK_TTL": "1m",
+				"APP_DB_USER":                   "testuser",
+				"APP_DB_NAME":                   "testdb",
+				"APP_REDIS_HOST":                "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid metrics namespace",
+			envVars: map[string]string{
+				"APP_METRICS_ENABLED":   "true",
+				"APP_METRICS_NAMESPACE": "course-api",
+				"APP_DB_USER":           "testuser",
+				"APP_DB_NAME":           "testdb",
+				"APP_REDIS_HOST":        "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tracing sample ratio",
+			envVars: map[string]string{
+				"APP_TRACING_ENABLED":      "true",
+				"APP_TRACING_SAMPLE_RATIO": "1.5",
+				"APP_DB_USER":              "testuser",
+				"APP_DB_NAME":              "testdb",
+				"APP_REDIS_HOST":           "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "short debug logging secret",
+			envVars: map[string]string{
+				"APP_LOGGER_DEBUG_ENABLED": "true",
+				"APP_LOGGER_DEBUG_SECRET":  "too-short",
+				"APP_DB_USER":              "testuser",
+				"APP_DB_NAME":              "testdb",
+				"APP_REDIS_HOST":           "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "admin port same as HTTP port",
+			envVars: map[string]string{
+				"APP_ADMIN_ENABLED": "true",
+				"APP_ADMIN_PORT":    "8080",
+				"APP_DB_USER":       "testuser",
+				"APP_DB_NAME":       "testdb",
+				"APP_REDIS_HOST":    "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid privacy retention period",
+			envVars: map[string]string{
+				"APP_PRIVACY_PURGE_ENABLED":    "true",
+				"APP_PRIVACY_RETENTION_PERIOD": "0s",
+				"APP_DB_USER":                  "testuser",
+				"APP_DB_NAME":                  "testdb",
+				"APP_REDIS_HOST":               "localhost",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -211,6 +527,11 @@ func clearTestEnv() {
 		"APP_GENERAL_TZ",
 		"APP_GENERAL_SERVICE_NAME",
 		"APP_LOGGER_LEVEL",
+		"APP_LOGGER_SENSITIVE_FIELDS",
+		"APP_LOGGER_SAMPLING_ENABLED",
+		"APP_LOGGER_SAMPLING_ACCESS_ROUTES",
+		"APP_LOGGER_DEBUG_ENABLED",
+		"APP_LOGGER_DEBUG_SECRET",
 		"APP_SERVER_API_HOST",
 		"APP_SERVER_API_PORT",
 		"APP_SERVER_API_RATE_LIMIT",
@@ -224,6 +545,34 @@ func clearTestEnv() {
 		"APP_REDIS_PORT",
 		"APP_REDIS_PASSWORD",
 		"APP_REDIS_DB",
+		"APP_TENANT_ENABLED",
+		"APP_TENANT_DEFAULT_ID",
+		"APP_PRIVACY_PURGE_ENABLED",
+		"APP_PRIVACY_RETENTION_PERIOD",
+		"APP_CRYPTO_DATA_KEYS",
+		"APP_CRYPTO_ACTIVE_KEY_VERSION",
+		"APP_CRYPTO_BLIND_INDEX_KEY",
+		"APP_PASSWORD_ARGON2_ITERATIONS",
+		"APP_PASSWORD_MIN_LENGTH",
+		"APP_PASSWORD_MAX_LENGTH",
+		"APP_HTTP_CSRF_ENABLED",
+		"APP_HTTP_CSRF_SECRET",
+		"APP_HTTP_CSRF_SESSION_COOKIE",
+		"APP_HTTP_RATELIMIT_TRUSTED_PROXIES",
+		"APP_HTTP_RATELIMIT_ROUTES",
+		"APP_HTTP_RATELIMIT_ENABLED",
+		"APP_HTTP_RATELIMIT_CREDENTIALS_LIMIT",
+		"APP_HTTP_LOADSHED_ENABLED",
+		"APP_HTTP_LOADSHED_INITIAL_LIMIT",
+		"APP_HTTP_IDEMPOTENCY_ENABLED",
+		"APP_HTTP_IDEMPOTENCY_TTL",
+		"APP_HTTP_IDEMPOTENCY_LOCK_TTL",
+		"APP_METRICS_ENABLED",
+		"APP_METRICS_NAMESPACE",
+		"APP_TRACING_ENABLED",
+		"APP_TRACING_SAMPLE_RATIO",
+		"APP_ADMIN_ENABLED",
+		"APP_ADMIN_PORT",
 	}
 
 	for _, env := range envVars {
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces the value of the secret settings in Redacted
+const redactedValue = "[REDACTED]"
+
+// Redacted returns the configuration as maps keyed by field name, safe to
+// serve or log: passwords, secrets and keys are replaced by [REDACTED] when
+// set, so are the passwords inside connection strings such as
+// GOOSE_DBSTRING, and durations and times are formatted as text.
+func (c *Config) Redacted() map[string]any {
+	return redactStruct(reflect.ValueOf(*c))
+}
+
+func redactStruct(v reflect.Value) map[string]any {
+	t := v.Type()
+	out := make(map[string]any, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		out[field.Name] = redactValue(field.Name, v.Field(i))
+	}
+	return out
+}
+
+func redactValue(name string, v reflect.Value) any {
+	if isSecret(name, v) {
+		if v.IsZero() || (v.Kind() != reflect.String && v.Len() == 0) {
+			// An unset secret is worth seeing
+			return v.Interface()
+		}
+		return redactedValue
+	}
+
+	switch value := v.Interface().(type) {
+	case string:
+		return redactConnectionString(value)
+	case time.Duration:
+		return value.String()
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	}
+
+	if v.Kind() == reflect.Struct {
+		return redactStruct(v)
+	}
+	return v.Interface()
+}
+
+// isSecret matches the passwords, secrets and keys by field name; only text
+// and collections can hold one, so MaxKeys or ActiveKeyVersion are kept
+func isSecret(name string, v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice:
+	default:
+		return false
+	}
+	return strings.Contains(name, "Password") ||
+		strings.Contains(name, "Secret") ||
+		strings.HasSuffix(name, "Key") ||
+		strings.HasSuffix(name, "Keys")
+}
+
+// dsnPassword matches the password of a key/value connection string, such
+// as "host=db user=app password='s3 cret'"
+var dsnPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactConnectionString redacts the password of a URL, in its userinfo,
+// or of a key/value connection string; any other text is returned as is
+func redactConnectionString(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "REDACTED")
+			return u.String()
+		}
+	}
+	return dsnPassword.ReplaceAllString(s, "${1}"+redactedValue)
+}
@@ -1,6 +1,12 @@
 -- +goose Up
 -- +goose StatementBegin
 
+SELECT set_config('app.tenant_id', '00000000-0000-0000-0000-000000000000', true);
+
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+
 INSERT INTO users (id, name, email, phone, hashed_password, role, is_active, audit_created_by, audit_updated_by)
 VALUES
     (
@@ -42,6 +48,12 @@ VALUES
 -- +goose Down
 -- +goose StatementBegin
 
+SELECT set_config('app.tenant_id', '00000000-0000-0000-0000-000000000000', true);
+
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+
 DELETE FROM users
 WHERE id IN (
     'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11',
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Existing rows belong to the default tenant (APP_TENANT_DEFAULT_ID)
+ALTER TABLE users ADD COLUMN tenant_id UUID;
+
+UPDATE users SET tenant_id = '00000000-0000-0000-0000-000000000000';
+
+-- New rows take the tenant of the current transaction (see pkg/database BeginTx)
+ALTER TABLE users
+    ALTER COLUMN tenant_id SET DEFAULT NULLIF(current_setting('app.tenant_id', true), '')::uuid,
+    ALTER COLUMN tenant_id SET NOT NULL;
+
+-- Email and phone are unique per tenant
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+
+ALTER TABLE users DROP CONSTRAINT users_phone_key;
+
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+ALTER TABLE users ADD CONSTRAINT users_tenant_phone_key UNIQUE (tenant_id, phone);
+
+CREATE INDEX idx_users_tenant_id ON users (tenant_id)
+WHERE
+    audit_deleted_at IS NULL;
+
+-- Row-level security: a session only sees and writes rows of app.tenant_id.
+-- FORCE applies the policy to the table owner used by the application.
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY users_tenant_isolation ON users
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP POLICY IF EXISTS users_tenant_isolation ON users;
+
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_users_tenant_id;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_phone_key;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_email_key;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE users ADD CONSTRAINT users_phone_key UNIQUE (phone);
+
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+-- +goose StatementEnd
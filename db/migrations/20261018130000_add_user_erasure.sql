@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- LGPD erasure: personal data is replaced and contact columns become NULL,
+-- while id, role and audit columns are kept for audit integrity
+ALTER TABLE users ADD COLUMN erased_at TIMESTAMPTZ;
+
+ALTER TABLE users
+    ALTER COLUMN email DROP NOT NULL,
+    ALTER COLUMN phone DROP NOT NULL;
+
+ALTER TABLE users ADD CONSTRAINT users_contact_required_check
+    CHECK (erased_at IS NOT NULL OR (email IS NOT NULL AND phone IS NOT NULL));
+
+-- Retention purge runs without a tenant and removes soft-deleted users of
+-- every tenant. It only sees deleted rows and only while app.purge_deleted_users
+-- is on for the current transaction (see PostgresUserRepository.PurgeDeletedUsers).
+CREATE POLICY users_retention_purge_select ON users FOR SELECT
+    USING (current_setting('app.purge_deleted_users', true) = 'on' AND audit_deleted_at IS NOT NULL);
+
+CREATE POLICY users_retention_purge_delete ON users FOR DELETE
+    USING (current_setting('app.purge_deleted_users', true) = 'on' AND audit_deleted_at IS NOT NULL);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP POLICY IF EXISTS users_retention_purge_delete ON users;
+
+DROP POLICY IF EXISTS users_retention_purge_select ON users;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_contact_required_check;
+
+-- Fails while erased users remain; purge them before rolling back
+ALTER TABLE users
+    ALTER COLUMN email SET NOT NULL,
+    ALTER COLUMN phone SET NOT NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS erased_at;
+
+-- +goose StatementEnd
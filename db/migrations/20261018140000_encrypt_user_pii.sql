@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Email and phone hold AES-256-GCM envelopes ("v<key version>:<base64>"),
+-- longer than the plaintext limits
+ALTER TABLE users
+    ALTER COLUMN email TYPE TEXT,
+    ALTER COLUMN phone TYPE TEXT;
+
+-- Blind indexes (HMAC-SHA256) keep lookups and uniqueness on encrypted values.
+-- Rows written before this migration stay in plaintext without index until
+-- the re-encryption job rewrites them.
+ALTER TABLE users
+    ADD COLUMN email_bidx TEXT,
+    ADD COLUMN phone_bidx TEXT;
+
+DROP INDEX IF EXISTS idx_users_email;
+
+DROP INDEX IF EXISTS idx_users_phone;
+
+ALTER TABLE users DROP CONSTRAINT users_tenant_email_key;
+
+ALTER TABLE users DROP CONSTRAINT users_tenant_phone_key;
+
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_bidx_key UNIQUE (tenant_id, email_bidx);
+
+ALTER TABLE users ADD CONSTRAINT users_tenant_phone_bidx_key UNIQUE (tenant_id, phone_bidx);
+
+-- Re-encryption runs without a tenant and rewrites rows of every tenant, only
+-- while app.rotate_user_keys is on for the current transaction
+-- (see PostgresUserRepository.ReencryptUsers)
+CREATE POLICY users_key_rotation_select ON users FOR SELECT
+    USING (current_setting('app.rotate_user_keys', true) = 'on');
+
+CREATE POLICY users_key_rotation_update ON users FOR UPDATE
+    USING (current_setting('app.rotate_user_keys', true) = 'on')
+    WITH CHECK (current_setting('app.rotate_user_keys', true) = 'on');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Encrypted values are not decrypted here; restore plaintext with the
+-- application before rolling back or the unique constraints lose meaning
+DROP POLICY IF EXISTS users_key_rotation_update ON users;
+
+DROP POLICY IF EXISTS users_key_rotation_select ON users;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_phone_bidx_key;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_email_bidx_key;
+
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+ALTER TABLE users ADD CONSTRAINT users_tenant_phone_key UNIQUE (tenant_id, phone);
+
+CREATE INDEX idx_users_email ON users (email)
+WHERE
+    audit_deleted_at IS NULL;
+
+CREATE INDEX idx_users_phone ON users (phone)
+WHERE
+    audit_deleted_at IS NULL;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS phone_bidx,
+    DROP COLUMN IF EXISTS email_bidx;
+
+-- +goose StatementEnd
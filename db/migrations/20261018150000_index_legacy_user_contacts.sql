@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Rows stored before encryption keep plaintext email and phone without a
+-- blind index until the re-encryption job rewrites them. Login and the
+-- duplicate checks look them up in plaintext meanwhile
+-- (see PostgresUserRepository.FindUserByEmail and findLegacyConflicts).
+CREATE INDEX idx_users_legacy_email ON users (email)
+WHERE
+    email_bidx IS NULL;
+
+CREATE INDEX idx_users_legacy_phone ON users (phone)
+WHERE
+    phone_bidx IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_users_legacy_phone;
+
+DROP INDEX IF EXISTS idx_users_legacy_email;
+
+-- +goose StatementEnd
@@ -10,9 +10,13 @@ import (
 	"github.com/marcelofabianov/course/config"
 	"github.com/marcelofabianov/course/pkg/cache"
 	"github.com/marcelofabianov/course/pkg/database"
+	"github.com/marcelofabianov/course/pkg/i18n"
 	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/metrics"
 	"github.com/marcelofabianov/course/pkg/web"
+	"github.com/marcelofabianov/course/pkg/web/admin"
 	webchi "github.com/marcelofabianov/course/pkg/web/chi"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
 )
 
 type DatabaseHealthChecker struct {
@@ -47,22 +51,97 @@ func (c *CacheHealthChecker) Check(ctx context.Context) error {
 	return c.cache.Ping(ctx)
 }
 
+// ProvideLoadShedder is shared by the router and the readiness check, so an
+// instance shedding requests reports itself degraded
+func ProvideLoadShedder(cfg *config.Config) *middleware.LoadShedder {
+	return middleware.NewLoadShedder(cfg.HTTP.LoadShedding)
+}
+
+func NewLoadHealthChecker(shedder *middleware.LoadShedder) web.HealthChecker {
+	return shedder
+}
+
+// ProvideMetrics returns nil when metrics are disabled, which turns off the
+// middleware and the endpoint
+func ProvideMetrics(cfg *config.Config, log *logger.Logger, db *database.DB, c *cache.Cache, shedder *middleware.LoadShedder) (*metrics.Metrics, error) {
+	if !cfg.Metrics.Enabled {
+		return nil, nil
+	}
+
+	m := metrics.New(cfg.Metrics.Namespace)
+	if err := m.Register(
+		metrics.NewDatabaseCollector(m.Namespace(), db.Stats),
+		metrics.NewCacheCollector(m.Namespace(), c.Stats),
+	); err != nil {
+		return nil, err
+	}
+	if cfg.HTTP.LoadShedding.Enabled {
+		if err := m.Register(metrics.NewLoadShedCollector(m.Namespace(), shedder.Stats)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Logger.Sampling.Enabled {
+		if err := m.Register(metrics.NewLogSamplingCollector(m.Namespace(), log.SamplingStats)); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// ProvideDebugLogging returns nil when debug tokens are disabled
+func ProvideDebugLogging(cfg *config.Config, log *logger.Logger) *middleware.DebugLogging {
+	if !cfg.Logger.Debug.Enabled {
+		return nil
+	}
+	return middleware.NewDebugLogging(cfg.Logger.Debug, cfg.JWT.AccessSecret, middleware.NewSecurityLogger(log))
+}
+
+// ProvideAuthenticator verifies the access tokens of the routes that
+// require authentication
+func ProvideAuthenticator(cfg *config.Config, log *logger.Logger) *middleware.Authenticator {
+	return middleware.NewAuthenticator(cfg.JWT, cfg.Tenant.Claim, middleware.NewSecurityLogger(log))
+}
+
+// ProvideCredentialLimiter limits the routes that check a password with a
+// limiter of their own, enabled whatever the rate limiting configuration
+func ProvideCredentialLimiter(cfg *config.Config, c *cache.Cache, log *logger.Logger) *middleware.CredentialLimiter {
+	limiter := middleware.NewRateLimiter(
+		c.Client(),
+		true,
+		cfg.HTTP.RateLimit.TrustedProxies,
+		middleware.NewSecurityLogger(log),
+	)
+	if fallback := cfg.HTTP.RateLimit.Fallback; fallback.Enabled {
+		limiter.SetFallback(middleware.NewLocalLimiter(fallback.MaxKeys, fallback.Replicas))
+	}
+	return middleware.NewCredentialLimiter(limiter, cfg.HTTP.RateLimit.Credentials)
+}
+
 type RouterParams struct {
 	fx.In
 
 	Config         *config.Config
 	Logger         *logger.Logger
 	Cache          *cache.Cache
+	I18n           *i18n.Bundle
+	LoadShedder    *middleware.LoadShedder
+	Metrics        *metrics.Metrics
+	DebugLogging   *middleware.DebugLogging
 	Routers        []web.Router        `group:"routers"`
 	HealthCheckers []web.HealthChecker `group:"health_checkers"`
 }
 
 func ProvideRouter(params RouterParams) *chi.Mux {
 	router := webchi.NewRouter(webchi.RouterConfig{
-		Config:  params.Config,
-		Logger:  params.Logger,
-		Cache:   params.Cache,
-		Routers: params.Routers,
+		Config:       params.Config,
+		Logger:       params.Logger,
+		Cache:        params.Cache,
+		I18n:         params.I18n,
+		LoadShedder:  params.LoadShedder,
+		Metrics:      params.Metrics,
+		DebugLogging: params.DebugLogging,
+		Routers:      params.Routers,
 	})
 
 	router.Get("/health/ready", web.ReadinessHandler(params.HealthCheckers...))
@@ -96,6 +175,37 @@ func ProvideServer(cfg *config.Config, log *logger.Logger, router *chi.Mux, lc f
 	return server
 }
 
+// StartAdminServer serves the admin router on its own listener, when
+// enabled, with the same lifecycle as the API server
+func StartAdminServer(cfg *config.Config, log *logger.Logger, m *metrics.Metrics, debugLogging *middleware.DebugLogging, lc fx.Lifecycle) {
+	if !cfg.Admin.Enabled {
+		return
+	}
+
+	server := web.NewAdminServer(cfg, log, admin.NewRouter(admin.RouterConfig{
+		Config:       cfg,
+		Logger:       log,
+		Metrics:      m,
+		DebugLogging: debugLogging,
+	}))
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			log.Info("starting admin server", "addr", server.Addr())
+			go func() {
+				if err := server.Start(); err != nil && err != http.ErrServerClosed {
+					log.Error("admin server error", "error", err.Error())
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Info("shutting down admin server")
+			return server.Shutdown(ctx)
+		},
+	})
+}
+
 func AsRouter(f any) any {
 	return fx.Annotate(
 		f,
@@ -116,7 +226,14 @@ var AppModule = fx.Module("app",
 	fx.Provide(
 		ProvideRouter,
 		ProvideServer,
+		ProvideLoadShedder,
+		ProvideMetrics,
+		ProvideDebugLogging,
+		ProvideAuthenticator,
+		ProvideCredentialLimiter,
 		AsHealthChecker(NewDatabaseHealthChecker),
 		AsHealthChecker(NewCacheHealthChecker),
+		AsHealthChecker(NewLoadHealthChecker),
 	),
+	fx.Invoke(StartAdminServer),
 )
@@ -8,9 +8,14 @@ import (
 
 	"github.com/marcelofabianov/course/config"
 	"github.com/marcelofabianov/course/pkg/cache"
+	"github.com/marcelofabianov/course/pkg/crypto"
 	"github.com/marcelofabianov/course/pkg/database"
+	"github.com/marcelofabianov/course/pkg/i18n"
 	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/password"
+	"github.com/marcelofabianov/course/pkg/tracing"
 	"github.com/marcelofabianov/course/pkg/validation"
+	"github.com/marcelofabianov/course/pkg/web"
 )
 
 func ProvideConfig() (*config.Config, error) {
@@ -18,11 +23,24 @@ func ProvideConfig() (*config.Config, error) {
 }
 
 func ProvideLogger(cfg *config.Config) *logger.Logger {
+	var sampling *logger.SamplingConfig
+	if cfg.Logger.Sampling.Enabled {
+		sampling = &logger.SamplingConfig{
+			First:      cfg.Logger.Sampling.First,
+			Thereafter: cfg.Logger.Sampling.Thereafter,
+			Tick:       cfg.Logger.Sampling.Tick,
+		}
+	}
+
 	return logger.New(&logger.Config{
 		Level:       logger.LogLevel(cfg.Logger.Level),
 		Format:      logger.FormatJSON,
 		ServiceName: cfg.General.ServiceName,
 		Environment: cfg.General.Env,
+		Redaction: &logger.RedactionConfig{
+			Keys: cfg.Logger.SensitiveFields,
+		},
+		Sampling: sampling,
 	})
 }
 
@@ -59,6 +77,47 @@ func ProvideDatabase(cfg *config.Config, log *logger.Logger, lc fx.Lifecycle) (*
 	return db, nil
 }
 
+func ProvideListener(db *database.DB, log *logger.Logger, lc fx.Lifecycle) *database.Listener {
+	listener := database.NewListener(db)
+	listener.SetLogger(log.Slog())
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			// The listener outlives the start hook, so it must not inherit its deadline
+			return listener.Start(context.Background())
+		},
+		OnStop: func(ctx context.Context) error {
+			if !listener.IsRunning() {
+				return nil
+			}
+			log.Info("stopping database listener")
+			return listener.Stop(ctx)
+		},
+	})
+
+	return listener
+}
+
+func ProvideLeaderElector(cfg *config.Config, db *database.DB, log *logger.Logger, lc fx.Lifecycle) *database.LeaderElector {
+	elector := database.NewLeaderElector(db, database.LeaderElectorConfig{
+		Name: cfg.General.ServiceName + ":leader",
+	})
+	elector.SetLogger(log.Slog())
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			// The election loop outlives the start hook, so it must not inherit its deadline
+			return elector.Start(context.Background())
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Info("leaving leader election")
+			return elector.Stop(ctx)
+		},
+	})
+
+	return elector
+}
+
 func ProvideCache(cfg *config.Config, log *logger.Logger, lc fx.Lifecycle) (*cache.Cache, error) {
 	cacheClient, err := cache.New(cfg)
 	if err != nil {
@@ -92,8 +151,70 @@ func ProvideCache(cfg *config.Config, log *logger.Logger, lc fx.Lifecycle) (*cac
 	return cacheClient, nil
 }
 
-func ProvideValidation(log *logger.Logger) validation.Validator {
-	return validation.New(log, nil)
+// ProvideEnvelope also registers the envelope used by crypto.Encrypted columns
+func ProvideEnvelope(cfg *config.Config) (*crypto.Envelope, error) {
+	envelope, err := crypto.NewEnvelope(crypto.EnvelopeConfig{
+		Keys:          cfg.Crypto.DataKeys,
+		ActiveVersion: cfg.Crypto.ActiveKeyVersion,
+		BlindIndexKey: cfg.Crypto.BlindIndexKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	crypto.SetFieldEnvelope(envelope)
+
+	return envelope, nil
+}
+
+// ProvideI18n creates the translations bundle with the messages of the
+// shared packages; modules add their own errors
+func ProvideI18n(cfg *config.Config) (*i18n.Bundle, error) {
+	bundle, err := i18n.NewBundle(cfg.I18n.DefaultLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bundle.AddCatalog(password.Messages); err != nil {
+		return nil, err
+	}
+	bundle.AddErrors(password.ErrorMessages)
+	bundle.AddErrors(validation.ErrorMessages)
+	bundle.AddErrors(web.ErrorMessages)
+
+	return bundle, nil
+}
+
+func ProvideValidation(cfg *config.Config, log *logger.Logger, bundle *i18n.Bundle) (validation.Validator, error) {
+	validationCfg := validation.DefaultConfig()
+	validationCfg.AdditionalSensitiveFields = cfg.Logger.SensitiveFields
+
+	v := validation.New(log, validationCfg)
+	if err := validation.RegisterTranslations(v, bundle); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// SetupTracing installs the tracer provider when tracing is enabled and
+// flushes pending spans on stop. Disabled, spans go to the no-op provider.
+func SetupTracing(cfg *config.Config, log *logger.Logger, lc fx.Lifecycle) error {
+	if !cfg.Tracing.Enabled {
+		return nil
+	}
+
+	provider, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			log.Info("flushing traces")
+			return provider.Shutdown(ctx)
+		},
+	})
+
+	return nil
 }
 
 var PkgModule = fx.Module("pkg",
@@ -101,7 +222,12 @@ var PkgModule = fx.Module("pkg",
 		ProvideConfig,
 		ProvideLogger,
 		ProvideDatabase,
+		ProvideListener,
+		ProvideLeaderElector,
 		ProvideCache,
+		ProvideEnvelope,
+		ProvideI18n,
 		ProvideValidation,
 	),
+	fx.Invoke(SetupTracing),
 )
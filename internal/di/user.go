@@ -1,26 +1,224 @@
 package di
 
 import (
+	"context"
+
 	"go.uber.org/fx"
 
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/internal/user/domain"
 	"github.com/marcelofabianov/course/internal/user/handler"
 	"github.com/marcelofabianov/course/internal/user/port"
 	"github.com/marcelofabianov/course/internal/user/storage"
 	"github.com/marcelofabianov/course/internal/user/usecase"
 	"github.com/marcelofabianov/course/pkg/crypto"
+	"github.com/marcelofabianov/course/pkg/database"
+	"github.com/marcelofabianov/course/pkg/i18n"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/password"
+	"github.com/marcelofabianov/course/pkg/validation"
 )
 
 var UserModule = fx.Module("user",
 	fx.Provide(
-		crypto.NewArgon2Hasher,
+		ProvidePasswordHasher,
 		func(h *crypto.Argon2Hasher) port.PasswordHasherPort { return h },
+		func(h *crypto.Argon2Hasher) port.PasswordVerifierPort { return h },
+		fx.Annotate(
+			ProvidePasswordPolicy,
+			fx.As(new(port.PasswordPolicyPort)),
+		),
 		storage.NewPostgresUserRepository,
 		func(r *storage.PostgresUserRepository) port.CreateUserRepositoryPort { return r },
+		func(r *storage.PostgresUserRepository) port.BulkCreateUserRepositoryPort { return r },
+		func(r *storage.PostgresUserRepository) port.FindUserRepositoryPort { return r },
+		func(r *storage.PostgresUserRepository) port.UpdateUserRepositoryPort { return r },
+		func(r *storage.PostgresUserRepository) port.AuthenticateUserRepositoryPort { return r },
+		func(r *storage.PostgresUserRepository) port.PurgeUserRepositoryPort { return r },
+		func(r *storage.PostgresUserRepository) port.ReencryptUserRepositoryPort { return r },
+		fx.Annotate(
+			storage.NewCacheImportJobStore,
+			fx.As(new(port.ImportJobStorePort)),
+		),
 		fx.Annotate(
 			usecase.NewRegisterUserUseCase,
 			fx.As(new(port.RegisterUserUseCase)),
 		),
+		fx.Annotate(
+			ProvideImportUsersUseCase,
+			fx.As(new(port.ImportUsersUseCase)),
+		),
+		fx.Annotate(
+			ProvideAuthenticateUserUseCase,
+			fx.As(new(port.AuthenticateUserUseCase)),
+		),
+		fx.Annotate(
+			usecase.NewChangePasswordUseCase,
+			fx.As(new(port.ChangePasswordUseCase)),
+		),
+		fx.Annotate(
+			usecase.NewExportUserUseCase,
+			fx.As(new(port.ExportUserUseCase)),
+		),
+		fx.Annotate(
+			usecase.NewEraseUserUseCase,
+			fx.As(new(port.EraseUserUseCase)),
+		),
+		ProvidePurgeDeletedUsersUseCase,
+		ProvideReencryptUsersUseCase,
 		handler.NewRegisterUserHandler,
+		handler.NewImportUsersHandler,
+		handler.NewUserPrivacyHandler,
+		handler.NewChangePasswordHandler,
+		handler.NewLoginHandler,
 		AsRouter(handler.NewUserRouter),
 	),
+	fx.Invoke(RegisterUserJobs),
+	fx.Invoke(RegisterUserMessages),
 )
+
+// RegisterUserMessages adds the translations of the user errors
+func RegisterUserMessages(bundle *i18n.Bundle) {
+	bundle.AddErrors(domain.ErrorMessages)
+}
+
+// ProvidePasswordHasher builds the hasher from the configured Argon2
+// parameters; hashes made with other parameters are rehashed on login
+func ProvidePasswordHasher(cfg *config.Config) *crypto.Argon2Hasher {
+	params := crypto.DefaultArgon2Params()
+	params.Memory = cfg.Password.Argon2Memory
+	params.Iterations = cfg.Password.Argon2Iterations
+	params.Parallelism = cfg.Password.Argon2Parallelism
+
+	hasher := crypto.NewArgon2HasherWithParams(params)
+	hasher.SetLegacyBcrypt(cfg.Password.LegacyBcrypt)
+	return hasher
+}
+
+// ProvidePasswordPolicy builds the policy from configuration, loading the
+// breached password list when one is configured
+func ProvidePasswordPolicy(cfg *config.Config) (*password.Policy, error) {
+	var breached *password.BreachedSet
+	if cfg.Password.BreachedListPath != "" {
+		var err error
+		breached, err = password.LoadBreachedSet(cfg.Password.BreachedListPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return password.NewPolicy(password.PolicyConfig{
+		MinLength:          cfg.Password.MinLength,
+		MaxLength:          cfg.Password.MaxLength,
+		RequireUppercase:   cfg.Password.RequireUppercase,
+		RequireLowercase:   cfg.Password.RequireLowercase,
+		RequireDigit:       cfg.Password.RequireDigit,
+		RequireSymbol:      cfg.Password.RequireSymbol,
+		MaxRepeated:        cfg.Password.MaxRepeated,
+		ForbidPersonalInfo: cfg.Password.ForbidPersonalInfo,
+	}, breached), nil
+}
+
+func ProvideAuthenticateUserUseCase(
+	repo port.AuthenticateUserRepositoryPort,
+	hasher port.PasswordVerifierPort,
+	log *logger.Logger,
+) *usecase.AuthenticateUserUseCase {
+	uc := usecase.NewAuthenticateUserUseCase(repo, hasher)
+	uc.SetLogger(log.Slog())
+	return uc
+}
+
+func ProvideImportUsersUseCase(
+	repo port.BulkCreateUserRepositoryPort,
+	hasher port.PasswordHasherPort,
+	validator validation.Validator,
+	policy port.PasswordPolicyPort,
+	jobs port.ImportJobStorePort,
+	log *logger.Logger,
+) *usecase.ImportUsersUseCase {
+	uc := usecase.NewImportUsersUseCase(repo, hasher, validator, policy, jobs)
+	uc.SetLogger(log.Slog())
+	return uc
+}
+
+func ProvidePurgeDeletedUsersUseCase(
+	cfg *config.Config,
+	repo port.PurgeUserRepositoryPort,
+	log *logger.Logger,
+) *usecase.PurgeDeletedUsersUseCase {
+	uc := usecase.NewPurgeDeletedUsersUseCase(repo, cfg.Privacy.RetentionPeriod, cfg.Privacy.PurgeBatchSize)
+	uc.SetLogger(log.Slog())
+	return uc
+}
+
+func ProvideReencryptUsersUseCase(
+	cfg *config.Config,
+	repo port.ReencryptUserRepositoryPort,
+	log *logger.Logger,
+) *usecase.ReencryptUsersUseCase {
+	uc := usecase.NewReencryptUsersUseCase(repo, cfg.Crypto.ReencryptBatchSize)
+	uc.SetLogger(log.Slog())
+	return uc
+}
+
+// RegisterUserJobs schedules the user maintenance jobs: the LGPD retention
+// purge and the re-encryption after key rotation. Every replica runs the
+// schedule, but only the elected leader does the work; the startup
+// re-encryption runs on every replica, which skip each other's rows.
+func RegisterUserJobs(
+	cfg *config.Config,
+	purge *usecase.PurgeDeletedUsersUseCase,
+	reencrypt *usecase.ReencryptUsersUseCase,
+	elector *database.LeaderElector,
+	log *logger.Logger,
+	lc fx.Lifecycle,
+) {
+	if cfg.Privacy.PurgeEnabled {
+		scheduleJob(lc, log, "deleted users purge", func(ctx context.Context) {
+			purge.Run(ctx, cfg.Privacy.PurgeInterval, elector.IsLeader)
+		})
+	}
+
+	// Users stored before encryption have no blind index, so the first
+	// re-encryption runs before the server starts. FindUserByEmail falls
+	// back to plaintext for the rows a slow backfill leaves behind.
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			reencrypt.RunOnce(ctx)
+			return nil
+		},
+	})
+
+	if cfg.Crypto.ReencryptEnabled {
+		scheduleJob(lc, log, "users re-encryption", func(ctx context.Context) {
+			reencrypt.Run(ctx, cfg.Crypto.ReencryptInterval, elector.IsLeader)
+		})
+	}
+}
+
+// scheduleJob starts run with the application and cancels it on stop
+func scheduleJob(lc fx.Lifecycle, log *logger.Logger, name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			log.Info("scheduling job", "job", name)
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
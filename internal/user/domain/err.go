@@ -14,15 +14,34 @@ var (
 	ErrUserInvalidEmail    = errors.New("invalid email")
 	ErrUserInvalidPassword = errors.New("invalid password")
 	ErrUserInvalidRole     = errors.New("invalid role")
+	ErrUserRoleNotAllowed  = errors.New("role cannot be chosen at registration")
 	ErrUserInvalidPhone    = errors.New("invalid phone")
 	ErrUserAlreadyInactive = errors.New("user is already inactive")
+	ErrUserAlreadyErased   = errors.New("user data is already erased")
+
+	// --- Authentication ---
+	ErrUserInvalidCredentials = errors.New("invalid email or password")
 
 	// --- Infrastructure ---
-	ErrUserFailedGenerateUuid = errors.New("failed to generate user ID")
-	ErrUserEmailAlreadyExists = errors.New("email already exists")
-	ErrUserPhoneAlreadyExists = errors.New("phone already exists")
-	ErrUserFailedHashPassword = errors.New("failed to hash password")
-	ErrUserFailedCreateUser   = errors.New("failed to create user")
+	ErrUserFailedGenerateUuid   = errors.New("failed to generate user ID")
+	ErrUserEmailAlreadyExists   = errors.New("email already exists")
+	ErrUserPhoneAlreadyExists   = errors.New("phone already exists")
+	ErrUserAlreadyExists        = errors.New("user already exists")
+	ErrUserFailedHashPassword   = errors.New("failed to hash password")
+	ErrUserFailedCreateUser     = errors.New("failed to create user")
+	ErrUserNotFound             = errors.New("user not found")
+	ErrUserFailedFindUser       = errors.New("failed to find user")
+	ErrUserFailedUpdateUser     = errors.New("failed to update user")
+	ErrUserVersionConflict      = errors.New("user was changed by another request")
+	ErrUserFailedPurgeUsers     = errors.New("failed to purge deleted users")
+	ErrUserFailedReencryptUsers = errors.New("failed to re-encrypt users")
+
+	// --- Import ---
+	ErrUserImportInvalidFormat = errors.New("unsupported import format")
+	ErrUserImportMalformedFile = errors.New("malformed import file")
+	ErrUserImportTooManyRows   = errors.New("import exceeds maximum number of rows")
+	ErrUserImportJobNotFound   = errors.New("import job not found")
+	ErrUserImportFailed        = errors.New("import failed unexpectedly")
 )
 
 // --- Validation ---
@@ -66,6 +85,18 @@ func NewErrUserInvalidRole(role string) error {
 	)
 }
 
+// NewErrUserRoleNotAllowed is returned when a user registering themselves
+// asks for a role only admins can grant
+func NewErrUserRoleNotAllowed(role string) error {
+	return fault.Wrap(
+		ErrUserRoleNotAllowed,
+		ErrUserRoleNotAllowed.Error(),
+		fault.WithCode(fault.Forbidden),
+		fault.WithContext("role", role),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
 func NewErrUserInvalidPhone(phone string) error {
 	return fault.Wrap(
 		ErrUserInvalidPhone,
@@ -85,6 +116,15 @@ func NewErrUserAlreadyInactive() error {
 	)
 }
 
+func NewErrUserAlreadyErased() error {
+	return fault.Wrap(
+		ErrUserAlreadyErased,
+		ErrUserAlreadyErased.Error(),
+		fault.WithCode(fault.Conflict),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
 // --- Infrastructure ---
 
 func NewErrUserFailedGenerateUuid(err error) error {
@@ -115,6 +155,17 @@ func NewErrUserPhoneAlreadyExists() error {
 	)
 }
 
+// NewErrUserAlreadyExists is returned when a user collides with an existing
+// one on a unique key that cannot be told apart
+func NewErrUserAlreadyExists() error {
+	return fault.Wrap(
+		ErrUserAlreadyExists,
+		ErrUserAlreadyExists.Error(),
+		fault.WithCode(fault.Conflict),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
 func NewErrUserFailedHashPassword() error {
 	return fault.Wrap(
 		ErrUserFailedHashPassword,
@@ -132,3 +183,127 @@ func NewErrUserFailedCreateUser() error {
 		fault.WithContext("aggregate", USER_AGGREGATE),
 	)
 }
+
+func NewErrUserNotFound(id string) error {
+	return fault.Wrap(
+		ErrUserNotFound,
+		ErrUserNotFound.Error(),
+		fault.WithCode(fault.NotFound),
+		fault.WithContext("user_id", id),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserFailedFindUser() error {
+	return fault.Wrap(
+		ErrUserFailedFindUser,
+		ErrUserFailedFindUser.Error(),
+		fault.WithCode(fault.Internal),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserFailedUpdateUser() error {
+	return fault.Wrap(
+		ErrUserFailedUpdateUser,
+		ErrUserFailedUpdateUser.Error(),
+		fault.WithCode(fault.Internal),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+// NewErrUserVersionConflict is returned when the stored user is no longer
+// the version the update was made from
+func NewErrUserVersionConflict(id string) error {
+	return fault.Wrap(
+		ErrUserVersionConflict,
+		ErrUserVersionConflict.Error(),
+		fault.WithCode(fault.Conflict),
+		fault.WithContext("user_id", id),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserFailedPurgeUsers() error {
+	return fault.Wrap(
+		ErrUserFailedPurgeUsers,
+		ErrUserFailedPurgeUsers.Error(),
+		fault.WithCode(fault.Internal),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserFailedReencryptUsers() error {
+	return fault.Wrap(
+		ErrUserFailedReencryptUsers,
+		ErrUserFailedReencryptUsers.Error(),
+		fault.WithCode(fault.Internal),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+// --- Authentication ---
+
+// NewErrUserInvalidCredentials is returned for unknown emails, wrong
+// passwords and users that cannot log in alike, so callers cannot tell
+// which accounts exist
+func NewErrUserInvalidCredentials() error {
+	return fault.Wrap(
+		ErrUserInvalidCredentials,
+		ErrUserInvalidCredentials.Error(),
+		fault.WithCode(fault.Unauthorized),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+// --- Import ---
+
+func NewErrUserImportInvalidFormat(format string) error {
+	return fault.Wrap(
+		ErrUserImportInvalidFormat,
+		ErrUserImportInvalidFormat.Error(),
+		fault.WithCode(fault.Invalid),
+		fault.WithContext("format", format),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserImportMalformedFile(reason string) error {
+	return fault.Wrap(
+		ErrUserImportMalformedFile,
+		ErrUserImportMalformedFile.Error(),
+		fault.WithCode(fault.Invalid),
+		fault.WithContext("reason", reason),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserImportTooManyRows(max int) error {
+	return fault.Wrap(
+		ErrUserImportTooManyRows,
+		ErrUserImportTooManyRows.Error(),
+		fault.WithCode(fault.Invalid),
+		fault.WithContext("max_rows", max),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserImportJobNotFound(id string) error {
+	return fault.Wrap(
+		ErrUserImportJobNotFound,
+		ErrUserImportJobNotFound.Error(),
+		fault.WithCode(fault.NotFound),
+		fault.WithContext("job_id", id),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
+
+func NewErrUserImportFailed(reason string) error {
+	return fault.Wrap(
+		ErrUserImportFailed,
+		ErrUserImportFailed.Error(),
+		fault.WithCode(fault.Internal),
+		fault.WithContext("reason", reason),
+		fault.WithContext("aggregate", USER_AGGREGATE),
+	)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "github.com/marcelofabianov/course/pkg/i18n"
+
+// ErrorMessages translates the user errors
+var ErrorMessages = i18n.ErrorCatalog{
+	// --- Validation ---
+	ErrUserInvalidName:     {i18n.LocalePtBR: "nome inválido"},
+	ErrUserInvalidEmail:    {i18n.LocalePtBR: "email inválido"},
+	ErrUserInvalidPassword: {i18n.LocalePtBR: "senha inválida"},
+	ErrUserInvalidRole:     {i18n.LocalePtBR: "perfil inválido"},
+	ErrUserRoleNotAllowed:  {i18n.LocalePtBR: "o perfil não pode ser escolhido no cadastro"},
+	ErrUserInvalidPhone:    {i18n.LocalePtBR: "telefone inválido"},
+	ErrUserAlreadyInactive: {i18n.LocalePtBR: "o usuário já está inativo"},
+	ErrUserAlreadyErased:   {i18n.LocalePtBR: "os dados do usuário já foram apagados"},
+
+	// --- Authentication ---
+	ErrUserInvalidCredentials: {i18n.LocalePtBR: "email ou senha inválidos"},
+
+	// --- Infrastructure ---
+	ErrUserFailedGenerateUuid:   {i18n.LocalePtBR: "falha ao gerar o ID do usuário"},
+	ErrUserEmailAlreadyExists:   {i18n.LocalePtBR: "o email já está cadastrado"},
+	ErrUserPhoneAlreadyExists:   {i18n.LocalePtBR: "o telefone já está cadastrado"},
+	ErrUserAlreadyExists:        {i18n.LocalePtBR: "o usuário já está cadastrado"},
+	ErrUserFailedHashPassword:   {i18n.LocalePtBR: "falha ao processar a senha"},
+	ErrUserFailedCreateUser:     {i18n.LocalePtBR: "falha ao criar o usuário"},
+	ErrUserNotFound:             {i18n.LocalePtBR: "usuário não encontrado"},
+	ErrUserFailedFindUser:       {i18n.LocalePtBR: "falha ao buscar o usuário"},
+	ErrUserFailedUpdateUser:     {i18n.LocalePtBR: "falha ao atualizar o usuário"},
+	ErrUserVersionConflict:      {i18n.LocalePtBR: "o usuário foi alterado por outra requisição"},
+	ErrUserFailedPurgeUsers:     {i18n.LocalePtBR: "falha ao remover os usuários excluídos"},
+	ErrUserFailedReencryptUsers: {i18n.LocalePtBR: "falha ao recriptografar os usuários"},
+
+	// --- Import ---
+	ErrUserImportInvalidFormat: {i18n.LocalePtBR: "formato de importação não suportado"},
+	ErrUserImportMalformedFile: {i18n.LocalePtBR: "arquivo de importação inválido"},
+	ErrUserImportTooManyRows:   {i18n.LocalePtBR: "a importação excede o número máximo de linhas"},
+	ErrUserImportJobNotFound:   {i18n.LocalePtBR: "importação não encontrada"},
+	ErrUserImportFailed:        {i18n.LocalePtBR: "a importação falhou inesperadamente"},
+}
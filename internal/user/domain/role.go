@@ -44,6 +44,12 @@ func (r UserRole) IsGuest() bool {
 	return r == RoleGuest
 }
 
+// CanSelfAssign reports whether users may choose the role when they
+// register themselves; admins are only created by other admins
+func (r UserRole) CanSelfAssign() bool {
+	return r.IsCommon() || r.IsGuest()
+}
+
 func (r UserRole) CanLogin() bool {
 	return r.IsAdmin() || r.IsCommon()
 }
@@ -31,13 +31,22 @@ type User struct {
 	ID           wisp.UUID           `db:"id"`
 	Name         wisp.NonEmptyString `db:"name"`
 	Email        wisp.Email          `db:"email"`
-	PasswordHash wisp.NonEmptyString `db:"password"`
+	PasswordHash wisp.NonEmptyString `db:"hashed_password"`
 	Phone        wisp.Phone          `db:"phone"`
 	Role         UserRole            `db:"role"`
 	IsActive     bool                `db:"is_active"`
+	ErasedAt     wisp.NullableTime   `db:"erased_at"`
 	wisp.Audit
 }
 
+const (
+	// ErasedUserName replaces the name of erased users
+	ErasedUserName = "erased user"
+
+	// erasedPasswordHash is not a valid hash, so no password ever matches it
+	erasedPasswordHash = "!erased"
+)
+
 func NewUser(input *NewUserInput, passwordHash wisp.NonEmptyString, createdBy wisp.AuditUser) (*User, error) {
 	id, err := wisp.NewUUID()
 	if err != nil {
@@ -127,3 +136,29 @@ func (u *User) Delete(deletedBy wisp.AuditUser) {
 	u.Audit.DeletedAt = wisp.NewNullableTime(time.Now())
 	u.Audit.Touch(deletedBy)
 }
+
+// Erase irreversibly removes the personal data of the user (LGPD right to
+// deletion). The id, role and audit trail are kept; the user is deactivated
+// and soft deleted so the retention purge removes the row later.
+func (u *User) Erase(erasedBy wisp.AuditUser) error {
+	if u.IsErased() {
+		return NewErrUserAlreadyErased()
+	}
+
+	u.Name = wisp.NonEmptyString(ErasedUserName)
+	u.Email = wisp.EmptyEmail
+	u.Phone = wisp.EmptyPhone
+	u.PasswordHash = wisp.NonEmptyString(erasedPasswordHash)
+	u.IsActive = false
+	u.ErasedAt = wisp.NewNullableTime(time.Now())
+	if !u.Audit.DeletedAt.Valid {
+		u.Audit.DeletedAt = u.ErasedAt
+	}
+	u.Audit.Touch(erasedBy)
+
+	return nil
+}
+
+func (u *User) IsErased() bool {
+	return u.ErasedAt.Valid
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/validation"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+type ChangePasswordHandler struct {
+	useCase   port.ChangePasswordUseCase
+	validator validation.Validator
+}
+
+func NewChangePasswordHandler(useCase port.ChangePasswordUseCase, validator validation.Validator) *ChangePasswordHandler {
+	return &ChangePasswordHandler{
+		useCase:   useCase,
+		validator: validator,
+	}
+}
+
+func (h *ChangePasswordHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	input, err := web.DecodeJSON[port.ChangePasswordInput](r, h.validator)
+	if err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	if err := h.useCase.Execute(r.Context(), chi.URLParam(r, "id"), &input); err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	web.NoContent(w, r)
+}
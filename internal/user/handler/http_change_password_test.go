@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/password"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+type mockChangePasswordUseCase struct {
+	id    string
+	input *port.ChangePasswordInput
+	err   error
+}
+
+func (m *mockChangePasswordUseCase) Execute(_ context.Context, id string, input *port.ChangePasswordInput) error {
+	m.id = id
+	m.input = input
+	return m.err
+}
+
+func newChangePasswordRequest(id, body string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req := httptest.NewRequest(http.MethodPut, "/users/"+id+"/password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestChangePasswordHandler_Handle(t *testing.T) {
+	const body = `{"old_password":"Test@123!","new_password":"N3w#Secret"}`
+
+	t.Run("returns 204 when the password is changed", func(t *testing.T) {
+		uc := &mockChangePasswordUseCase{}
+		handler := NewChangePasswordHandler(uc, newTestValidator(t))
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, newChangePasswordRequest("user-1", body))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "user-1", uc.id)
+		assert.Equal(t, "N3w#Secret", uc.input.NewPassword)
+	})
+
+	t.Run("returns 400 with the broken rules", func(t *testing.T) {
+		policyErr := password.NewPolicy(password.DefaultPolicyConfig(), nil).Validate("weak", password.UserInfo{})
+		handler := NewChangePasswordHandler(&mockChangePasswordUseCase{err: policyErr}, newTestValidator(t))
+		w := httptest.NewRecorder()
+		req := newChangePasswordRequest("user-1", body)
+		req.Header.Set("Accept", web.ProblemContentType)
+
+		handler.Handle(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), password.RuleMinLength)
+		assert.Contains(t, w.Body.String(), password.RuleDigit)
+	})
+
+	t.Run("returns 401 for wrong current password", func(t *testing.T) {
+		uc := &mockChangePasswordUseCase{err: domain.NewErrUserInvalidCredentials()}
+		handler := NewChangePasswordHandler(uc, newTestValidator(t))
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, newChangePasswordRequest("user-1", body))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns 400 for missing fields", func(t *testing.T) {
+		uc := &mockChangePasswordUseCase{}
+		handler := NewChangePasswordHandler(uc, newTestValidator(t))
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, newChangePasswordRequest("user-1", `{"new_password":"N3w#Secret"}`))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Nil(t, uc.input)
+	})
+}
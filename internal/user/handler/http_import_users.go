@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+// asyncImportThreshold is the body size above which imports run as a job
+const asyncImportThreshold = 256 * 1024
+
+type ImportUsersHandler struct {
+	useCase port.ImportUsersUseCase
+}
+
+func NewImportUsersHandler(useCase port.ImportUsersUseCase) *ImportUsersHandler {
+	return &ImportUsersHandler{useCase: useCase}
+}
+
+// Handle imports users from a CSV or JSONL body.
+// Query parameters: format (csv|jsonl, defaults to the Content-Type),
+// dry_run=true to only validate, async=true to force a background job.
+func (h *ImportUsersHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+	async, _ := strconv.ParseBool(query.Get("async"))
+
+	input := &port.ImportUsersInput{
+		Format: importFormat(r),
+		DryRun: dryRun,
+		Data:   r.Body,
+	}
+
+	if async || r.ContentLength > asyncImportThreshold {
+		job, err := h.useCase.Start(r.Context(), input)
+		if err != nil {
+			web.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Location", r.URL.Path+"/"+job.ID)
+		web.Accepted(w, r, job)
+		return
+	}
+
+	report, err := h.useCase.Execute(r.Context(), input)
+	if err != nil && report != nil {
+		// Some rows were written before the failure: the report tells the
+		// client which ones
+		web.Success(w, r, fault.ToResponse(err).StatusCode, report)
+		return
+	}
+	if err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	web.Success(w, r, http.StatusOK, report)
+}
+
+// HandleJob returns the status of a background import
+func (h *ImportUsersHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.useCase.Job(r.Context(), chi.URLParam(r, "jobID"))
+	if err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	web.Success(w, r, http.StatusOK, job)
+}
+
+func importFormat(r *http.Request) port.ImportFormat {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return port.ImportFormat(format)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return port.ImportFormatCSV
+	case "application/x-ndjson", "application/jsonl":
+		return port.ImportFormatJSONL
+	}
+
+	return port.ImportFormat(mediaType)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+)
+
+type mockImportUseCase struct {
+	input  *port.ImportUsersInput
+	report *port.ImportUsersReport
+	job    *port.ImportJob
+	err    error
+}
+
+func (m *mockImportUseCase) Execute(_ context.Context, input *port.ImportUsersInput) (*port.ImportUsersReport, error) {
+	m.input = input
+	return m.report, m.err
+}
+
+func (m *mockImportUseCase) Start(_ context.Context, input *port.ImportUsersInput) (*port.ImportJob, error) {
+	m.input = input
+	return m.job, m.err
+}
+
+func (m *mockImportUseCase) Job(_ context.Context, _ string) (*port.ImportJob, error) {
+	return m.job, m.err
+}
+
+func TestImportUsersHandler_Handle(t *testing.T) {
+	t.Run("returns 200 with the report", func(t *testing.T) {
+		uc := &mockImportUseCase{report: &port.ImportUsersReport{Total: 1, Created: 1}}
+		handler := NewImportUsersHandler(uc)
+
+		req := httptest.NewRequest(http.MethodPost, "/users/import?dry_run=true", strings.NewReader("name\n"))
+		req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, port.ImportFormatCSV, uc.input.Format)
+		assert.True(t, uc.input.DryRun)
+		assert.Contains(t, w.Body.String(), `"created":1`)
+	})
+
+	t.Run("format query overrides content type", func(t *testing.T) {
+		uc := &mockImportUseCase{report: &port.ImportUsersReport{}}
+		handler := NewImportUsersHandler(uc)
+
+		req := httptest.NewRequest(http.MethodPost, "/users/import?format=jsonl", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, req)
+
+		assert.Equal(t, port.ImportFormatJSONL, uc.input.Format)
+	})
+
+	t.Run("returns 202 with location when async", func(t *testing.T) {
+		uc := &mockImportUseCase{job: &port.ImportJob{ID: "job-1", Status: port.ImportJobPending}}
+		handler := NewImportUsersHandler(uc)
+
+		req := httptest.NewRequest(http.MethodPost, "/users/import?async=true", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "/users/import/job-1", w.Header().Get("Location"))
+		assert.Equal(t, port.ImportFormatJSONL, uc.input.Format)
+	})
+
+	t.Run("returns the partial report when writing fails", func(t *testing.T) {
+		uc := &mockImportUseCase{
+			report: &port.ImportUsersReport{Total: 2, Created: 1, Skipped: 1, Error: "failed to create user"},
+			err:    domain.NewErrUserFailedCreateUser(),
+		}
+		handler := NewImportUsersHandler(uc)
+
+		req := httptest.NewRequest(http.MethodPost, "/users/import", strings.NewReader("name\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), `"created":1`)
+		assert.Contains(t, w.Body.String(), `"skipped":1`)
+	})
+
+	t.Run("returns 400 for invalid format", func(t *testing.T) {
+		uc := &mockImportUseCase{err: domain.NewErrUserImportInvalidFormat("xml")}
+		handler := NewImportUsersHandler(uc)
+
+		req := httptest.NewRequest(http.MethodPost, "/users/import", strings.NewReader("<xml/>"))
+		req.Header.Set("Content-Type", "application/xml")
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestImportUsersHandler_HandleJob(t *testing.T) {
+	newRequest := func() *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("jobID", "job-1")
+		req := httptest.NewRequest(http.MethodGet, "/users/import/job-1", nil)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	t.Run("returns the job", func(t *testing.T) {
+		uc := &mockImportUseCase{job: &port.ImportJob{ID: "job-1", Status: port.ImportJobRunning}}
+		w := httptest.NewRecorder()
+
+		NewImportUsersHandler(uc).HandleJob(w, newRequest())
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"running"`)
+	})
+
+	t.Run("returns 404 for unknown job", func(t *testing.T) {
+		uc := &mockImportUseCase{err: domain.NewErrUserImportJobNotFound("job-1")}
+		w := httptest.NewRecorder()
+
+		NewImportUsersHandler(uc).HandleJob(w, newRequest())
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/validation"
+	"github.com/marcelofabianov/course/pkg/web"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+// LoginHandler exchanges valid credentials for an access token, bound to
+// the tenant of the request when there is one. The token carries the role
+// stored for the user, which the server assigned: registration grants only
+// the roles users may choose for themselves, and admins come from admin
+// imports. Nothing in the login request affects it.
+type LoginHandler struct {
+	useCase       port.AuthenticateUserUseCase
+	validator     validation.Validator
+	authenticator *middleware.Authenticator
+}
+
+func NewLoginHandler(useCase port.AuthenticateUserUseCase, validator validation.Validator, authenticator *middleware.Authenticator) *LoginHandler {
+	return &LoginHandler{
+		useCase:       useCase,
+		validator:     validator,
+		authenticator: authenticator,
+	}
+}
+
+func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	input, err := web.DecodeJSON[port.AuthenticateUserInput](r, h.validator)
+	if err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	user, err := h.useCase.Execute(r.Context(), &input)
+	if err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	// A stored role the domain does not know was not assigned by the server
+	role, err := domain.NewUserRole(user.Role.String())
+	if err != nil {
+		web.Error(w, r, domain.NewErrUserInvalidCredentials())
+		return
+	}
+
+	tenantID, _ := tenant.FromContext(r.Context())
+	token, err := h.authenticator.Issue(user.ID.String(), role.String(), tenantID)
+	if err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	web.Success(w, r, http.StatusOK, token)
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marcelofabianov/wisp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+type mockAuthenticateUseCase struct {
+	user  *domain.User
+	input *port.AuthenticateUserInput
+}
+
+func (m *mockAuthenticateUseCase) Execute(_ context.Context, input *port.AuthenticateUserInput) (*domain.User, error) {
+	m.input = input
+	if m.user == nil {
+		return nil, domain.NewErrUserInvalidCredentials()
+	}
+	return m.user, nil
+}
+
+func newLoginRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestLoginHandler_Handle(t *testing.T) {
+	t.Run("returns an access token for valid credentials", func(t *testing.T) {
+		hash, _ := wisp.NewNonEmptyString("$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5")
+		user, err := domain.NewUser(&domain.NewUserInput{
+			Name:  "John Doe",
+			Email: "john@example.com",
+			Phone: "+5511999999999",
+			Role:  "admin",
+		}, hash, wisp.AuditUser("system"))
+		require.NoError(t, err)
+		uc := &mockAuthenticateUseCase{user: user}
+		handler := NewLoginHandler(uc, newTestValidator(t), newTestAuthenticator())
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, newLoginRequest(`{"email":"john@example.com","password":"Test@123!"}`))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "john@example.com", uc.input.Email)
+
+		var token middleware.AccessToken
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &token))
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Equal(t, 900, token.ExpiresIn)
+
+		// The token authenticates the user it was issued for
+		var status int
+		protected := newTestAuthenticator().Authenticate()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status = http.StatusOK
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		protected.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("signs the role stored for the user", func(t *testing.T) {
+		user := newLoginUser(t, "common")
+		handler := NewLoginHandler(&mockAuthenticateUseCase{user: user}, newTestValidator(t), newTestAuthenticator())
+
+		w := httptest.NewRecorder()
+		handler.Handle(w, newLoginRequest(`{"email":"john@example.com","password":"Test@123!","role":"admin"}`))
+		assert.Equal(t, http.StatusBadRequest, w.Code, "expected the login request not to carry a role")
+
+		w = httptest.NewRecorder()
+		handler.Handle(w, newLoginRequest(`{"email":"john@example.com","password":"Test@123!"}`))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "common", tokenRole(t, w))
+	})
+
+	t.Run("returns 401 for a stored role the server does not know", func(t *testing.T) {
+		user := newLoginUser(t, "common")
+		user.Role = "superadmin"
+		handler := NewLoginHandler(&mockAuthenticateUseCase{user: user}, newTestValidator(t), newTestAuthenticator())
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, newLoginRequest(`{"email":"john@example.com","password":"Test@123!"}`))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns 401 for invalid credentials", func(t *testing.T) {
+		handler := NewLoginHandler(&mockAuthenticateUseCase{}, newTestValidator(t), newTestAuthenticator())
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, newLoginRequest(`{"email":"john@example.com","password":"wrong"}`))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("returns 400 for invalid body", func(t *testing.T) {
+		uc := &mockAuthenticateUseCase{}
+		handler := NewLoginHandler(uc, newTestValidator(t), newTestAuthenticator())
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, newLoginRequest(`{"email":"not-an-email"}`))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Nil(t, uc.input)
+	})
+}
+
+func newLoginUser(t *testing.T, role string) *domain.User {
+	t.Helper()
+
+	hash, _ := wisp.NewNonEmptyString("$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5")
+	user, err := domain.NewUser(&domain.NewUserInput{
+		Name:  "John Doe",
+		Email: "john@example.com",
+		Phone: "+5511999999999",
+		Role:  role,
+	}, hash, wisp.AuditUser("system"))
+	require.NoError(t, err)
+	return user
+}
+
+// tokenRole returns the role claim of the access token in the response
+func tokenRole(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var token middleware.AccessToken
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &token))
+	parts := strings.Split(token.AccessToken, ".")
+	require.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims struct {
+		Role string `json:"role"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims.Role
+}
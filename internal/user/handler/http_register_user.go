@@ -1,11 +1,8 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"github.com/marcelofabianov/fault"
-
 	"github.com/marcelofabianov/course/internal/user/port"
 	"github.com/marcelofabianov/course/pkg/validation"
 	"github.com/marcelofabianov/course/pkg/web"
@@ -24,15 +21,8 @@ func NewRegisterUserHandler(useCase port.RegisterUserUseCase, validator validati
 }
 
 func (h *RegisterUserHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	var input port.RegisterUserInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		web.Error(w, r, fault.New("invalid request body",
-			fault.WithCode(fault.Invalid),
-		))
-		return
-	}
-
-	if err := h.validator.Struct(r.Context(), &input); err != nil {
+	input, err := web.DecodeJSON[port.RegisterUserInput](r, h.validator)
+	if err != nil {
 		web.Error(w, r, err)
 		return
 	}
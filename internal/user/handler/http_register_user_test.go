@@ -99,6 +99,22 @@ func TestRegisterUserHandler_Handle(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 
+	t.Run("returns 400 for unknown fields", func(t *testing.T) {
+		uc := &mockUseCase{}
+		handler := NewRegisterUserHandler(uc, newTestValidator(t))
+
+		body := bytes.TrimSuffix(validRequestBody(), []byte("}"))
+		body = append(body, []byte(`,"is_admin":true}`)...)
+		req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.Handle(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "is_admin")
+	})
+
 	t.Run("returns 400 for validation error", func(t *testing.T) {
 		uc := &mockUseCase{}
 		handler := NewRegisterUserHandler(uc, newTestValidator(t))
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+// UserPrivacyHandler serves the LGPD data subject rights: access (export)
+// and deletion (erasure)
+type UserPrivacyHandler struct {
+	exportUseCase port.ExportUserUseCase
+	eraseUseCase  port.EraseUserUseCase
+}
+
+func NewUserPrivacyHandler(exportUseCase port.ExportUserUseCase, eraseUseCase port.EraseUserUseCase) *UserPrivacyHandler {
+	return &UserPrivacyHandler{
+		exportUseCase: exportUseCase,
+		eraseUseCase:  eraseUseCase,
+	}
+}
+
+func (h *UserPrivacyHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	export, err := h.exportUseCase.Execute(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.json"`, export.User.ID))
+	w.Header().Set("Cache-Control", "no-store")
+	web.Success(w, r, http.StatusOK, export)
+}
+
+func (h *UserPrivacyHandler) HandleErase(w http.ResponseWriter, r *http.Request) {
+	if err := h.eraseUseCase.Execute(r.Context(), chi.URLParam(r, "id")); err != nil {
+		web.Error(w, r, err)
+		return
+	}
+
+	web.NoContent(w, r)
+}
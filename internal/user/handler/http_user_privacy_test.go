@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+)
+
+type mockExportUseCase struct {
+	export *port.UserDataExport
+	err    error
+}
+
+func (m *mockExportUseCase) Execute(_ context.Context, _ string) (*port.UserDataExport, error) {
+	return m.export, m.err
+}
+
+type mockEraseUseCase struct {
+	id  string
+	err error
+}
+
+func (m *mockEraseUseCase) Execute(_ context.Context, id string) error {
+	m.id = id
+	return m.err
+}
+
+func newUserRequest(method, target, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req := httptest.NewRequest(method, target, nil)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestUserPrivacyHandler_HandleExport(t *testing.T) {
+	t.Run("returns the export as an attachment", func(t *testing.T) {
+		export := &port.UserDataExport{SchemaVersion: 1, User: port.UserExportData{ID: "user-1", Email: "john@example.com"}}
+		handler := NewUserPrivacyHandler(&mockExportUseCase{export: export}, &mockEraseUseCase{})
+		w := httptest.NewRecorder()
+
+		handler.HandleExport(w, newUserRequest(http.MethodGet, "/users/user-1/export", "user-1"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `attachment; filename="user-user-1.json"`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), "john@example.com")
+	})
+
+	t.Run("returns 404 for unknown user", func(t *testing.T) {
+		handler := NewUserPrivacyHandler(&mockExportUseCase{err: domain.NewErrUserNotFound("user-1")}, &mockEraseUseCase{})
+		w := httptest.NewRecorder()
+
+		handler.HandleExport(w, newUserRequest(http.MethodGet, "/users/user-1/export", "user-1"))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestUserPrivacyHandler_HandleErase(t *testing.T) {
+	t.Run("returns 204 when erased", func(t *testing.T) {
+		erase := &mockEraseUseCase{}
+		handler := NewUserPrivacyHandler(&mockExportUseCase{}, erase)
+		w := httptest.NewRecorder()
+
+		handler.HandleErase(w, newUserRequest(http.MethodPost, "/users/user-1/erase", "user-1"))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "user-1", erase.id)
+	})
+
+	t.Run("returns 409 when already erased", func(t *testing.T) {
+		handler := NewUserPrivacyHandler(&mockExportUseCase{}, &mockEraseUseCase{err: domain.NewErrUserAlreadyErased()})
+		w := httptest.NewRecorder()
+
+		handler.HandleErase(w, newUserRequest(http.MethodPost, "/users/user-1/erase", "user-1"))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
@@ -1,17 +1,66 @@
 package handler
 
-import "github.com/go-chi/chi/v5"
+import (
+	"github.com/go-chi/chi/v5"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
 
 type UserRouter struct {
 	registerHandler *RegisterUserHandler
+	importHandler   *ImportUsersHandler
+	privacyHandler  *UserPrivacyHandler
+	passwordHandler *ChangePasswordHandler
+	loginHandler    *LoginHandler
+	authenticator   *middleware.Authenticator
+	credentials     *middleware.CredentialLimiter
 }
 
-func NewUserRouter(registerHandler *RegisterUserHandler) *UserRouter {
-	return &UserRouter{registerHandler: registerHandler}
+func NewUserRouter(
+	registerHandler *RegisterUserHandler,
+	importHandler *ImportUsersHandler,
+	privacyHandler *UserPrivacyHandler,
+	passwordHandler *ChangePasswordHandler,
+	loginHandler *LoginHandler,
+	authenticator *middleware.Authenticator,
+	credentials *middleware.CredentialLimiter,
+) *UserRouter {
+	return &UserRouter{
+		registerHandler: registerHandler,
+		importHandler:   importHandler,
+		privacyHandler:  privacyHandler,
+		passwordHandler: passwordHandler,
+		loginHandler:    loginHandler,
+		authenticator:   authenticator,
+		credentials:     credentials,
+	}
 }
 
 func (ur *UserRouter) RegisterRoutes(r chi.Router) {
+	admin := string(domain.RoleAdmin)
+
+	r.With(ur.credentials.Limit("login")).Post("/auth/login", ur.loginHandler.Handle)
+
 	r.Route("/users", func(r chi.Router) {
+		// Anyone registers as common or guest; admins come from admin imports
 		r.Post("/", ur.registerHandler.Handle)
+
+		r.Group(func(r chi.Router) {
+			r.Use(ur.authenticator.Authenticate())
+
+			r.With(middleware.RequireRole(admin)).Post("/import", ur.importHandler.Handle)
+			r.With(middleware.RequireRole(admin)).Get("/import/{jobID}", ur.importHandler.HandleJob)
+
+			// Personal data is exported and erased by its owner or an admin
+			r.With(middleware.RequireSelfOrRole("id", admin)).Get("/{id}/export", ur.privacyHandler.HandleExport)
+			r.With(middleware.RequireSelfOrRole("id", admin)).Post("/{id}/erase", ur.privacyHandler.HandleErase)
+
+			// Only users change their own password, which needs the current one
+			r.With(
+				middleware.RequireSelfOrRole("id"),
+				ur.credentials.Limit("password"),
+			).Put("/{id}/password", ur.passwordHandler.Handle)
+		})
 	})
 }
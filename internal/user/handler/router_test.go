@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/internal/user/usecase"
+	"github.com/marcelofabianov/course/pkg/password"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+const (
+	routerSecret = "test-access-secret-with-32-bytes!!"
+	routerUserID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+	routerOther  = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+)
+
+func newTestUserRouter(t *testing.T) *chi.Mux {
+	t.Helper()
+	return newUserRouter(t, &mockUseCase{}, &mockAuthenticateUseCase{})
+}
+
+func newUserRouter(t *testing.T, register port.RegisterUserUseCase, login port.AuthenticateUserUseCase) *chi.Mux {
+	t.Helper()
+
+	router := NewUserRouter(
+		NewRegisterUserHandler(register, newTestValidator(t)),
+		NewImportUsersHandler(&mockImportUseCase{job: &port.ImportJob{ID: "job-1", Status: port.ImportJobRunning}}),
+		NewUserPrivacyHandler(&mockExportUseCase{export: &port.UserDataExport{SchemaVersion: 1}}, &mockEraseUseCase{}),
+		NewChangePasswordHandler(&mockChangePasswordUseCase{}, newTestValidator(t)),
+		NewLoginHandler(login, newTestValidator(t), newTestAuthenticator()),
+		newTestAuthenticator(),
+		newTestCredentialLimiter(),
+	)
+
+	r := chi.NewRouter()
+	router.RegisterRoutes(r)
+	return r
+}
+
+func newTestAuthenticator() *middleware.Authenticator {
+	return middleware.NewAuthenticator(config.JWTConfig{
+		AccessSecret:   routerSecret,
+		Issuer:         "course-api",
+		AccessTokenTTL: 15 * time.Minute,
+	}, "tenant_id", &middleware.SecurityLogger{})
+}
+
+func newTestCredentialLimiter() *middleware.CredentialLimiter {
+	limiter := middleware.NewRateLimiter(nil, true, []string{}, &middleware.SecurityLogger{})
+	limiter.SetFallback(middleware.NewLocalLimiter(100, 1))
+	return middleware.NewCredentialLimiter(limiter, config.RateConfig{Limit: 1, Window: time.Minute, Burst: 1})
+}
+
+func signAccessToken(t *testing.T, sub, role string) string {
+	t.Helper()
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payloadJSON, err := json.Marshal(map[string]any{
+		"sub":  sub,
+		"role": role,
+		"iss":  "course-api",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+	payload := base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	mac := hmac.New(sha256.New, []byte(routerSecret))
+	mac.Write([]byte(header + "." + payload))
+
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestUserRouter_Authorization(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		target string
+		role   string
+		status int
+	}{
+		{"export requires authentication", http.MethodGet, "/users/" + routerUserID + "/export", "", http.StatusUnauthorized},
+		{"erase requires authentication", http.MethodPost, "/users/" + routerUserID + "/erase", "", http.StatusUnauthorized},
+		{"users export their own data", http.MethodGet, "/users/" + routerUserID + "/export", "common", http.StatusOK},
+		{"users erase their own data", http.MethodPost, "/users/" + routerUserID + "/erase", "common", http.StatusNoContent},
+		{"users cannot export other users", http.MethodGet, "/users/" + routerOther + "/export", "common", http.StatusForbidden},
+		{"users cannot erase other users", http.MethodPost, "/users/" + routerOther + "/erase", "common", http.StatusForbidden},
+		{"admins export other users", http.MethodGet, "/users/" + routerOther + "/export", "admin", http.StatusOK},
+		{"admins erase other users", http.MethodPost, "/users/" + routerOther + "/erase", "admin", http.StatusNoContent},
+		{"import requires authentication", http.MethodGet, "/users/import/job-1", "", http.StatusUnauthorized},
+		{"import is admin only", http.MethodGet, "/users/import/job-1", "common", http.StatusForbidden},
+		{"admins read import jobs", http.MethodGet, "/users/import/job-1", "admin", http.StatusOK},
+		{"password change requires authentication", http.MethodPut, "/users/" + routerUserID + "/password", "", http.StatusUnauthorized},
+		{"users cannot change other users passwords", http.MethodPut, "/users/" + routerOther + "/password", "common", http.StatusForbidden},
+		{"admins cannot change other users passwords", http.MethodPut, "/users/" + routerOther + "/password", "admin", http.StatusForbidden},
+	}
+
+	router := newTestUserRouter(t)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			if tc.role != "" {
+				req.Header.Set("Authorization", "Bearer "+signAccessToken(t, routerUserID, tc.role))
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestUserRouter_ChangePasswordRateLimit(t *testing.T) {
+	router := newTestUserRouter(t)
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodPut, "/users/"+routerUserID+"/password",
+			strings.NewReader(`{"old_password":"Test@123!","new_password":"N3w#Secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+signAccessToken(t, routerUserID, "common"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, request())
+	assert.Equal(t, http.StatusTooManyRequests, request())
+}
+
+func TestUserRouter_LoginRateLimit(t *testing.T) {
+	router := newTestUserRouter(t)
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login",
+			strings.NewReader(`{"email":"john@example.com","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request())
+	assert.Equal(t, http.StatusTooManyRequests, request())
+}
+
+// registeredUsers stores the users created through registration and logs
+// them in by email, standing in for the repository and the password check
+type registeredUsers struct {
+	byEmail map[string]*domain.User
+}
+
+func (u *registeredUsers) CreateUser(_ context.Context, user *domain.User) error {
+	u.byEmail[user.Email.String()] = user
+	return nil
+}
+
+func (u *registeredUsers) Execute(_ context.Context, input *port.AuthenticateUserInput) (*domain.User, error) {
+	if user, ok := u.byEmail[input.Email]; ok {
+		return user, nil
+	}
+	return nil, domain.NewErrUserInvalidCredentials()
+}
+
+type fixedHasher struct{}
+
+func (fixedHasher) Hash(string) (string, error) {
+	return "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5", nil
+}
+
+func TestUserRouter_SelfRegisteredAdmin(t *testing.T) {
+	users := &registeredUsers{byEmail: map[string]*domain.User{}}
+	policy := password.NewPolicy(password.DefaultPolicyConfig(), nil)
+	router := newUserRouter(t, usecase.NewRegisterUserUseCase(users, fixedHasher{}, policy), users)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	register := func(role string) int {
+		body := `{"name":"Mallory","email":"mallory@example.com","password":"Test@123!","phone":"+5511999999999","role":"` + role + `"}`
+		return serve(httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))).Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, register("admin"))
+	require.Equal(t, http.StatusCreated, register("common"))
+
+	login := serve(httptest.NewRequest(http.MethodPost, "/auth/login",
+		strings.NewReader(`{"email":"mallory@example.com","password":"Test@123!"}`)))
+	require.Equal(t, http.StatusOK, login.Code)
+	assert.Equal(t, "common", tokenRole(t, login))
+	var token middleware.AccessToken
+	require.NoError(t, json.Unmarshal(login.Body.Bytes(), &token))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+routerOther+"/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	assert.Equal(t, http.StatusForbidden, serve(req).Code)
+}
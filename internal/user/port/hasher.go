@@ -1,5 +1,21 @@
 package port
 
+import "github.com/marcelofabianov/course/pkg/password"
+
 type PasswordHasherPort interface {
 	Hash(password string) (string, error)
 }
+
+// PasswordVerifierPort checks passwords against stored hashes and tells
+// when a hash is outdated and should be replaced
+type PasswordVerifierPort interface {
+	PasswordHasherPort
+	Verify(password, hash string) (bool, error)
+	NeedsRehash(hash string) bool
+}
+
+// PasswordPolicyPort validates new passwords, returning
+// password.ErrPolicyViolation with one detail per broken rule
+type PasswordPolicyPort interface {
+	Validate(plain string, user password.UserInfo) error
+}
@@ -0,0 +1,84 @@
+package port
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type ImportFormat string
+
+const (
+	ImportFormatCSV   ImportFormat = "csv"
+	ImportFormatJSONL ImportFormat = "jsonl"
+)
+
+type ImportRowStatus string
+
+const (
+	ImportRowCreated  ImportRowStatus = "created"
+	ImportRowValid    ImportRowStatus = "valid" // dry-run: would be created
+	ImportRowConflict ImportRowStatus = "conflict"
+	ImportRowInvalid  ImportRowStatus = "invalid"
+	ImportRowSkipped  ImportRowStatus = "skipped" // not written: the import stopped on an error
+)
+
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+)
+
+type ImportUsersInput struct {
+	Format ImportFormat
+	DryRun bool
+	Data   io.Reader
+}
+
+type ImportRowResult struct {
+	Line   int             `json:"line"`
+	Status ImportRowStatus `json:"status"`
+	Email  string          `json:"email,omitempty"`
+	UserID string          `json:"user_id,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type ImportUsersReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Total     int               `json:"total"`
+	Created   int               `json:"created"`
+	Valid     int               `json:"valid"`
+	Conflicts int               `json:"conflicts"`
+	Invalid   int               `json:"invalid"`
+	Skipped   int               `json:"skipped"`
+	Error     string            `json:"error,omitempty"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+type ImportJob struct {
+	ID         string             `json:"id"`
+	Status     ImportJobStatus    `json:"status"`
+	DryRun     bool               `json:"dry_run"`
+	Report     *ImportUsersReport `json:"report,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+type ImportUsersUseCase interface {
+	// Execute imports the file synchronously and returns the per-row report.
+	// When writing stops halfway, the partial report comes with the error.
+	Execute(ctx context.Context, input *ImportUsersInput) (*ImportUsersReport, error)
+	// Start buffers the file and imports it in background, returning the pending job
+	Start(ctx context.Context, input *ImportUsersInput) (*ImportJob, error)
+	// Job returns the current state of a background import
+	Job(ctx context.Context, id string) (*ImportJob, error)
+}
+
+type ImportJobStorePort interface {
+	SaveImportJob(ctx context.Context, job *ImportJob) error
+	FindImportJob(ctx context.Context, id string) (*ImportJob, error)
+}
@@ -0,0 +1,45 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/marcelofabianov/wisp"
+)
+
+// UserDataExportSchemaVersion is bumped whenever the export bundle changes shape
+const UserDataExportSchemaVersion = 1
+
+// UserDataExport is the LGPD data access bundle with everything stored about a user
+type UserDataExport struct {
+	SchemaVersion int            `json:"schema_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	TenantID      string         `json:"tenant_id,omitempty"`
+	User          UserExportData `json:"user"`
+}
+
+type UserExportData struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+	Role     string `json:"role"`
+	IsActive bool   `json:"is_active"`
+	// HasPassword reports that a credential is stored; the hash is never exported
+	HasPassword bool       `json:"has_password"`
+	ErasedAt    *time.Time `json:"erased_at,omitempty"`
+	Audit       wisp.Audit `json:"audit"`
+}
+
+type ExportUserUseCase interface {
+	Execute(ctx context.Context, id string) (*UserDataExport, error)
+}
+
+type EraseUserUseCase interface {
+	Execute(ctx context.Context, id string) error
+}
+
+type PurgeDeletedUsersUseCase interface {
+	// Execute purges every user past the retention period, returning how many were removed
+	Execute(ctx context.Context) (int64, error)
+}
@@ -2,6 +2,9 @@ package port
 
 import (
 	"context"
+	"time"
+
+	"github.com/marcelofabianov/wisp"
 
 	"github.com/marcelofabianov/course/internal/user/domain"
 )
@@ -10,6 +13,54 @@ type CreateUserRepositoryPort interface {
 	CreateUser(ctx context.Context, user *domain.User) error
 }
 
+// BulkCreateUserRepositoryPort returns one entry per user, aligned by index:
+// nil when the user can be (or was) created, or the uniqueness error it hit.
+type BulkCreateUserRepositoryPort interface {
+	FindConflicts(ctx context.Context, users []*domain.User) ([]error, error)
+	CreateUsers(ctx context.Context, users []*domain.User) ([]error, error)
+}
+
+type FindUserRepositoryPort interface {
+	FindUserByID(ctx context.Context, id wisp.UUID) (*domain.User, error)
+}
+
+type UpdateUserRepositoryPort interface {
+	FindUserRepositoryPort
+	UpdateUser(ctx context.Context, user *domain.User) error
+}
+
+type AuthenticateUserRepositoryPort interface {
+	// FindUserByEmail returns the user, not soft deleted, with the email
+	FindUserByEmail(ctx context.Context, email wisp.Email) (*domain.User, error)
+	UpdateUser(ctx context.Context, user *domain.User) error
+}
+
+type PurgeUserRepositoryPort interface {
+	// PurgeDeletedUsers hard deletes up to limit users of every tenant that
+	// were soft deleted before deletedBefore, returning how many were removed
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (int64, error)
+}
+
+// ReencryptUsersResult is the outcome of re-encrypting a batch of users
+type ReencryptUsersResult struct {
+	Rewritten int64
+	// Conflicts are users stored before encryption whose blind index is
+	// already taken by another user of the tenant. They are left untouched
+	// and need to be reviewed.
+	Conflicts []wisp.UUID
+}
+
+type ReencryptUserRepositoryPort interface {
+	// ReencryptUsers rewrites up to limit users not yet encrypted with the
+	// active key, ignoring the users in skip
+	ReencryptUsers(ctx context.Context, limit int, skip []wisp.UUID) (*ReencryptUsersResult, error)
+}
+
 type UserRepositoryPort interface {
 	CreateUserRepositoryPort
+	BulkCreateUserRepositoryPort
+	UpdateUserRepositoryPort
+	AuthenticateUserRepositoryPort
+	PurgeUserRepositoryPort
+	ReencryptUserRepositoryPort
 }
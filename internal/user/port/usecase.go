@@ -33,3 +33,23 @@ type RegisterUserOutput struct {
 type RegisterUserUseCase interface {
 	Execute(ctx context.Context, input *RegisterUserInput) (*RegisterUserOutput, error)
 }
+
+type AuthenticateUserInput struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+type AuthenticateUserUseCase interface {
+	// Execute returns the user whose credentials match, or
+	// domain.ErrUserInvalidCredentials
+	Execute(ctx context.Context, input *AuthenticateUserInput) (*domain.User, error)
+}
+
+type ChangePasswordInput struct {
+	OldPassword string `json:"old_password" validate:"required,max=72"`
+	NewPassword string `json:"new_password" validate:"required,max=72"`
+}
+
+type ChangePasswordUseCase interface {
+	Execute(ctx context.Context, id string, input *ChangePasswordInput) error
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/cache"
+)
+
+const importJobTTL = 24 * time.Hour
+
+// CacheImportJobStore keeps import jobs in Redis so that any replica can
+// answer status polls. Keys are tenant scoped by the cache.
+type CacheImportJobStore struct {
+	cache *cache.Cache
+}
+
+func NewCacheImportJobStore(c *cache.Cache) *CacheImportJobStore {
+	return &CacheImportJobStore{cache: c}
+}
+
+func importJobKey(id string) string {
+	return "user:import:" + id
+}
+
+func (s *CacheImportJobStore) SaveImportJob(ctx context.Context, job *port.ImportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fault.Wrap(err, "failed to encode import job",
+			fault.WithCode(fault.Internal),
+			fault.WithContext("job_id", job.ID),
+		)
+	}
+
+	return s.cache.Set(ctx, importJobKey(job.ID), data, importJobTTL)
+}
+
+func (s *CacheImportJobStore) FindImportJob(ctx context.Context, id string) (*port.ImportJob, error) {
+	data, err := s.cache.Get(ctx, importJobKey(id))
+	if err != nil {
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			return nil, domain.NewErrUserImportJobNotFound(id)
+		}
+		return nil, err
+	}
+
+	var job port.ImportJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fault.Wrap(err, "failed to decode import job",
+			fault.WithCode(fault.Internal),
+			fault.WithContext("job_id", id),
+		)
+	}
+
+	return &job, nil
+}
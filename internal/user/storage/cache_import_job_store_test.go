@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/cache"
+)
+
+func TestCacheImportJobStore(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Skip("Config not available")
+	}
+
+	mr := miniredis.RunT(t)
+	host, redisPort, _ := net.SplitHostPort(mr.Addr())
+	cfg.Redis.Credentials.Host = host
+	cfg.Redis.Credentials.Port, _ = strconv.Atoi(redisPort)
+
+	c, err := cache.New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, c.Connect(context.Background()))
+	defer c.Close()
+
+	store := NewCacheImportJobStore(c)
+	ctx := context.Background()
+
+	t.Run("saves and finds a job", func(t *testing.T) {
+		job := &port.ImportJob{
+			ID:        "job-1",
+			Status:    port.ImportJobCompleted,
+			Report:    &port.ImportUsersReport{Total: 1, Created: 1},
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+		}
+		require.NoError(t, store.SaveImportJob(ctx, job))
+
+		found, err := store.FindImportJob(ctx, "job-1")
+		require.NoError(t, err)
+		assert.Equal(t, job.Status, found.Status)
+		assert.Equal(t, 1, found.Report.Created)
+		assert.True(t, job.CreatedAt.Equal(found.CreatedAt))
+		assert.Equal(t, importJobTTL, mr.TTL("user:import:job-1"))
+	})
+
+	t.Run("returns not found for unknown job", func(t *testing.T) {
+		_, err := store.FindImportJob(ctx, "missing")
+		assert.True(t, errors.Is(err, domain.ErrUserImportJobNotFound))
+	})
+}
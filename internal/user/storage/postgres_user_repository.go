@@ -2,68 +2,540 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/marcelofabianov/wisp"
 
 	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/crypto"
 	"github.com/marcelofabianov/course/pkg/database"
 )
 
 const defaultExecTimeout = 5 * time.Second
 
-const createUserQuery = `
-	INSERT INTO users (
-		id, name, email, phone, hashed_password, role, is_active,
-		audit_created_at, audit_created_by,
-		audit_updated_at, audit_updated_by,
-		audit_archived_at, audit_deleted_at, audit_version
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-`
+const usersTable = "users"
+
+var userConstraintErrors = database.ConstraintErrors{
+	"users_tenant_email_bidx_key": domain.NewErrUserEmailAlreadyExists,
+	"users_tenant_phone_bidx_key": domain.NewErrUserPhoneAlreadyExists,
+}
 
 type PostgresUserRepository struct {
-	db *database.DB
+	db       *database.DB
+	envelope *crypto.Envelope
+	errors   *database.ErrorTranslator
 }
 
-func NewPostgresUserRepository(db *database.DB) *PostgresUserRepository {
-	return &PostgresUserRepository{db: db}
+func NewPostgresUserRepository(db *database.DB, envelope *crypto.Envelope) *PostgresUserRepository {
+	return &PostgresUserRepository{
+		db:       db,
+		envelope: envelope,
+		errors:   database.NewErrorTranslator(userConstraintErrors),
+	}
 }
 
 func (r *PostgresUserRepository) CreateUser(ctx context.Context, user *domain.User) error {
 	execCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
 	defer cancel()
 
-	_, err := r.db.DB().ExecContext(execCtx, createUserQuery,
-		user.ID,
-		user.Name,
-		user.Email,
-		user.Phone,
-		user.PasswordHash,
-		user.Role,
-		user.IsActive,
-		user.Audit.CreatedAt,
-		user.Audit.CreatedBy,
-		user.Audit.UpdatedAt,
-		user.Audit.UpdatedBy,
-		user.Audit.ArchivedAt,
-		user.Audit.DeletedAt,
-		user.Audit.Version,
+	record := r.toRecord(user)
+	err := r.db.WithTx(execCtx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		// The blind index constraints do not cover rows still in plaintext
+		conflicts, err := r.findLegacyConflicts(ctx, tx, []*domain.User{user})
+		if err != nil {
+			return err
+		}
+		if conflicts[0] != nil {
+			return conflicts[0]
+		}
+
+		return database.Insert(ctx, tx, usersTable, record)
+	})
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, domain.ErrUserEmailAlreadyExists) || errors.Is(err, domain.ErrUserPhoneAlreadyExists) {
+		return err
+	}
+
+	if translated := r.errors.Translate(err); translated != err {
+		return translated
+	}
+
+	return domain.NewErrUserFailedCreateUser()
+}
+
+const findUserConflictsQuery = `
+	SELECT email_bidx, phone_bidx FROM users
+	WHERE email_bidx = ANY($1) OR phone_bidx = ANY($2)
+`
+
+// findLegacyUserConflictsQuery matches rows stored before encryption, which
+// have no blind index until the re-encryption job rewrites them
+const findLegacyUserConflictsQuery = `
+	SELECT
+		CASE WHEN email_bidx IS NULL THEN email END AS email,
+		CASE WHEN phone_bidx IS NULL THEN phone END AS phone
+	FROM users
+	WHERE (email_bidx IS NULL AND email = ANY($1))
+		OR (phone_bidx IS NULL AND phone = ANY($2))
+`
+
+type legacyUserKeys struct {
+	Email sql.NullString `db:"email"`
+	Phone sql.NullString `db:"phone"`
+}
+
+type userUniqueKeys struct {
+	EmailIndex sql.NullString `db:"email_bidx"`
+	PhoneIndex sql.NullString `db:"phone_bidx"`
+}
+
+type insertedUser struct {
+	ID string `db:"id"`
+}
+
+func (r *PostgresUserRepository) FindConflicts(ctx context.Context, users []*domain.User) ([]error, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	var conflicts []error
+	err := r.db.WithTx(queryCtx, &sql.TxOptions{ReadOnly: true}, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		conflicts, err = r.findConflicts(ctx, tx, users, r.toRecords(users))
+		return err
+	})
+	if err != nil {
+		return nil, r.errors.Translate(err)
+	}
+
+	return conflicts, nil
+}
+
+// CreateUsers inserts users with a single multi-row INSERT. Users that
+// collide with existing rows are skipped and reported as conflicts.
+func (r *PostgresUserRepository) CreateUsers(ctx context.Context, users []*domain.User) ([]error, error) {
+	execCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	records := r.toRecords(users)
+
+	var conflicts []error
+	err := r.db.WithTx(execCtx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		conflicts, err = r.findConflicts(ctx, tx, users, records)
+		if err != nil {
+			return err
+		}
+
+		pending := make([]*userRecord, 0, len(records))
+		for i, record := range records {
+			if conflicts[i] == nil {
+				pending = append(pending, record)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		query, args, err := database.BuildInsertMany(usersTable, pending)
+		if err != nil {
+			return err
+		}
+
+		// Rows inserted concurrently by another request are skipped here
+		// and reported as conflicts below
+		inserted, err := database.Select[insertedUser](ctx, tx, query+" ON CONFLICT DO NOTHING RETURNING id", args...)
+		if err != nil {
+			return err
+		}
+
+		ids := make(map[string]bool, len(inserted))
+		for _, row := range inserted {
+			ids[row.ID] = true
+		}
+
+		var skipped []int
+		for i, user := range users {
+			if conflicts[i] == nil && !ids[user.ID.String()] {
+				skipped = append(skipped, i)
+			}
+		}
+		if len(skipped) == 0 {
+			return nil
+		}
+
+		return r.reportSkipped(ctx, tx, users, records, skipped, conflicts)
+	})
+	if err == nil {
+		return conflicts, nil
+	}
+
+	if translated := r.errors.Translate(err); translated != err {
+		return nil, translated
+	}
+
+	return nil, domain.NewErrUserFailedCreateUser()
+}
+
+// reportSkipped looks the skipped users up again, now that the rows which
+// beat them to the unique indexes are visible, so each one is reported with
+// the key it actually collided on
+func (r *PostgresUserRepository) reportSkipped(ctx context.Context, q database.Querier, users []*domain.User, records []*userRecord, skipped []int, conflicts []error) error {
+	skippedUsers := make([]*domain.User, len(skipped))
+	skippedRecords := make([]*userRecord, len(skipped))
+	for j, i := range skipped {
+		skippedUsers[j] = users[i]
+		skippedRecords[j] = records[i]
+	}
+
+	found, err := r.findConflicts(ctx, q, skippedUsers, skippedRecords)
+	if err != nil {
+		return err
+	}
+
+	for j, i := range skipped {
+		conflicts[i] = found[j]
+		if conflicts[i] == nil {
+			conflicts[i] = domain.NewErrUserAlreadyExists()
+		}
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) toRecords(users []*domain.User) []*userRecord {
+	records := make([]*userRecord, len(users))
+	for i, user := range users {
+		records[i] = r.toRecord(user)
+	}
+	return records
+}
+
+// findConflicts reports, for each user, an existing user with the same
+// email or phone, either by blind index or, for legacy rows, by plaintext
+func (r *PostgresUserRepository) findConflicts(ctx context.Context, q database.Querier, users []*domain.User, records []*userRecord) ([]error, error) {
+	emails := make([]string, len(records))
+	phones := make([]string, len(records))
+	for i, record := range records {
+		emails[i] = record.EmailIndex.String
+		phones[i] = record.PhoneIndex.String
+	}
+
+	existing, err := database.Select[userUniqueKeys](ctx, q, findUserConflictsQuery, emails, phones)
+	if err != nil {
+		return nil, err
+	}
+
+	takenEmails := make(map[string]bool, len(existing))
+	takenPhones := make(map[string]bool, len(existing))
+	for _, keys := range existing {
+		takenEmails[keys.EmailIndex.String] = keys.EmailIndex.Valid
+		takenPhones[keys.PhoneIndex.String] = keys.PhoneIndex.Valid
+	}
+
+	conflicts, err := r.findLegacyConflicts(ctx, q, users)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, record := range records {
+		switch {
+		case conflicts[i] != nil:
+		case takenEmails[record.EmailIndex.String]:
+			conflicts[i] = domain.NewErrUserEmailAlreadyExists()
+		case takenPhones[record.PhoneIndex.String]:
+			conflicts[i] = domain.NewErrUserPhoneAlreadyExists()
+		}
+	}
+
+	return conflicts, nil
+}
+
+func (r *PostgresUserRepository) findLegacyConflicts(ctx context.Context, q database.Querier, users []*domain.User) ([]error, error) {
+	emails := make([]string, len(users))
+	phones := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = user.Email.String()
+		phones[i] = user.Phone.String()
+	}
+
+	existing, err := database.Select[legacyUserKeys](ctx, q, findLegacyUserConflictsQuery, emails, phones)
+	if err != nil {
+		return nil, err
+	}
+
+	takenEmails := make(map[string]bool, len(existing))
+	takenPhones := make(map[string]bool, len(existing))
+	for _, keys := range existing {
+		takenEmails[keys.Email.String] = keys.Email.Valid
+		takenPhones[keys.Phone.String] = keys.Phone.Valid
+	}
+
+	conflicts := make([]error, len(users))
+	for i, user := range users {
+		switch {
+		case !user.Email.IsEmpty() && takenEmails[user.Email.String()]:
+			conflicts[i] = domain.NewErrUserEmailAlreadyExists()
+		case !user.Phone.IsZero() && takenPhones[user.Phone.String()]:
+			conflicts[i] = domain.NewErrUserPhoneAlreadyExists()
+		}
+	}
+
+	return conflicts, nil
+}
+
+func (r *PostgresUserRepository) FindUserByID(ctx context.Context, id wisp.UUID) (*domain.User, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	columns, err := database.Columns[userRecord]()
+	if err != nil {
+		return nil, domain.NewErrUserFailedFindUser()
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", strings.Join(columns, ", "), usersTable)
+
+	var record *userRecord
+	err = r.db.WithTx(queryCtx, &sql.TxOptions{ReadOnly: true}, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		record, err = database.Get[userRecord](ctx, tx, query, id)
+		return err
+	})
+	if err == nil {
+		return record.toDomain(), nil
+	}
+
+	if database.IsNoRows(err) {
+		return nil, domain.NewErrUserNotFound(id.String())
+	}
+
+	return nil, domain.NewErrUserFailedFindUser()
+}
+
+func (r *PostgresUserRepository) FindUserByEmail(ctx context.Context, email wisp.Email) (*domain.User, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	columns, err := database.Columns[userRecord]()
+	if err != nil {
+		return nil, domain.NewErrUserFailedFindUser()
+	}
+	// Rows stored before encryption have no blind index until the
+	// re-encryption job rewrites them, so they are matched in plaintext
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE (email_bidx = $1 OR (email_bidx IS NULL AND email = $2)) AND audit_deleted_at IS NULL",
+		strings.Join(columns, ", "), usersTable,
 	)
+
+	var record *userRecord
+	err = r.db.WithTx(queryCtx, &sql.TxOptions{ReadOnly: true}, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		record, err = database.Get[userRecord](ctx, tx, query, r.emailIndex(email), email.String())
+		return err
+	})
 	if err == nil {
+		return record.toDomain(), nil
+	}
+
+	if database.IsNoRows(err) {
+		return nil, domain.NewErrUserNotFound("")
+	}
+
+	return nil, domain.NewErrUserFailedFindUser()
+}
+
+const userExistsQuery = `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`
+
+// UpdateUser stores user only while the stored row is still the version it
+// was loaded at: every change touches the audit once, so the stored version
+// must be the previous one. Otherwise it returns ErrUserVersionConflict.
+func (r *PostgresUserRepository) UpdateUser(ctx context.Context, user *domain.User) error {
+	execCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	query, args, err := database.BuildUpdate(usersTable, r.toRecord(user), "id")
+	if err != nil {
+		return domain.NewErrUserFailedUpdateUser()
+	}
+	query += fmt.Sprintf(" AND audit_version = $%d", len(args)+1)
+	args = append(args, user.Audit.Version.Previous())
+
+	var affected int64
+	var exists bool
+	err = r.db.WithTx(execCtx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if affected, err = result.RowsAffected(); err != nil || affected > 0 {
+			return err
+		}
+		return tx.QueryRowContext(ctx, userExistsQuery, user.ID).Scan(&exists)
+	})
+	if err != nil {
+		if translated := r.errors.Translate(err); translated != err {
+			return translated
+		}
+		return domain.NewErrUserFailedUpdateUser()
+	}
+
+	if affected == 0 {
+		if exists {
+			return domain.NewErrUserVersionConflict(user.ID.String())
+		}
+		return domain.NewErrUserNotFound(user.ID.String())
+	}
+
+	return nil
+}
+
+// enablePurgeQuery turns on the users_retention_purge_* RLS policies for
+// the current transaction only
+const enablePurgeQuery = "SELECT set_config('app.purge_deleted_users', 'on', true)"
+
+const purgeDeletedUsersQuery = `
+	DELETE FROM users WHERE id IN (
+		SELECT id FROM users
+		WHERE audit_deleted_at < $1
+		ORDER BY audit_deleted_at
+		LIMIT $2
+	)
+`
+
+func (r *PostgresUserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	execCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	var purged int64
+	err := r.db.WithTx(execCtx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, enablePurgeQuery); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, purgeDeletedUsersQuery, deletedBefore, limit)
+		if err != nil {
+			return err
+		}
+
+		purged, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, domain.NewErrUserFailedPurgeUsers()
+	}
+
+	return purged, nil
+}
+
+// enableKeyRotationQuery turns on the users_key_rotation_* RLS policies for
+// the current transaction only
+const enableKeyRotationQuery = "SELECT set_config('app.rotate_user_keys', 'on', true)"
+
+// selectStaleUsersQuery finds rows in plaintext, encrypted with a retired
+// key ($1 is the active key prefix pattern) or missing a blind index,
+// except the ones in $3
+const selectStaleUsersQuery = `
+	SELECT id, email, phone FROM users
+	WHERE ((email IS NOT NULL AND (email NOT LIKE $1 OR email_bidx IS NULL))
+		OR (phone IS NOT NULL AND (phone NOT LIKE $1 OR phone_bidx IS NULL)))
+		AND NOT (id = ANY($3::uuid[]))
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+`
+
+const reencryptUserQuery = `
+	UPDATE users SET email = $2, email_bidx = $3, phone = $4, phone_bidx = $5
+	WHERE id = $1
+`
+
+// Each user is rewritten under its own savepoint, so a unique violation
+// rolls back only that user
+const (
+	reencryptSavepointQuery         = "SAVEPOINT reencrypt_user"
+	reencryptReleaseSavepointQuery  = "RELEASE SAVEPOINT reencrypt_user"
+	reencryptRollbackSavepointQuery = "ROLLBACK TO SAVEPOINT reencrypt_user"
+)
+
+type staleUser struct {
+	ID    wisp.UUID             `db:"id"`
+	Email crypto.EncryptedEmail `db:"email"`
+	Phone crypto.EncryptedPhone `db:"phone"`
+}
+
+// ReencryptUsers rewrites up to limit users of every tenant with the active
+// key and fills missing blind indexes. Audit columns are not touched, as
+// the stored data does not change. A legacy user whose blind index is
+// already taken by another user is left as is and reported as a conflict.
+func (r *PostgresUserRepository) ReencryptUsers(ctx context.Context, limit int, skip []wisp.UUID) (*port.ReencryptUsersResult, error) {
+	execCtx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	skipIDs := make([]string, len(skip))
+	for i, id := range skip {
+		skipIDs[i] = id.String()
+	}
+
+	result := &port.ReencryptUsersResult{}
+	err := r.db.WithTx(execCtx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, enableKeyRotationQuery); err != nil {
+			return err
+		}
+
+		stale, err := database.Select[staleUser](ctx, tx, selectStaleUsersQuery, r.envelope.ActivePrefix()+"%", limit, skipIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range stale {
+			conflict, err := r.reencryptUser(ctx, tx, user)
+			if err != nil {
+				return err
+			}
+
+			if conflict {
+				result.Conflicts = append(result.Conflicts, user.ID)
+				continue
+			}
+			result.Rewritten++
+		}
+
 		return nil
+	})
+	if err != nil {
+		return nil, domain.NewErrUserFailedReencryptUsers()
 	}
 
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-		if strings.Contains(pgErr.ConstraintName, "email") {
-			return domain.NewErrUserEmailAlreadyExists()
+	return result, nil
+}
+
+// reencryptUser rewrites one user, reporting a unique violation as a
+// conflict instead of failing the batch
+func (r *PostgresUserRepository) reencryptUser(ctx context.Context, tx *sql.Tx, user staleUser) (bool, error) {
+	if _, err := tx.ExecContext(ctx, reencryptSavepointQuery); err != nil {
+		return false, err
+	}
+
+	_, err := tx.ExecContext(ctx, reencryptUserQuery,
+		user.ID,
+		user.Email, r.emailIndex(user.Email.Val),
+		user.Phone, r.phoneIndex(user.Phone.Val),
+	)
+	if err != nil {
+		if !errors.Is(database.TranslateError(err), database.ErrUniqueViolation) {
+			return false, err
 		}
-		if strings.Contains(pgErr.ConstraintName, "phone") {
-			return domain.NewErrUserPhoneAlreadyExists()
+		if _, err := tx.ExecContext(ctx, reencryptRollbackSavepointQuery); err != nil {
+			return false, err
 		}
+		return true, nil
 	}
 
-	return domain.NewErrUserFailedCreateUser()
+	_, err = tx.ExecContext(ctx, reencryptReleaseSavepointQuery)
+	return false, err
 }
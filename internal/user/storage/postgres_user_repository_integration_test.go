@@ -4,6 +4,7 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
 	"time"
@@ -14,7 +15,9 @@ import (
 
 	"github.com/marcelofabianov/course/config"
 	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/pkg/crypto"
 	"github.com/marcelofabianov/course/pkg/database"
+	"github.com/marcelofabianov/course/pkg/tenant"
 )
 
 func setupRepository(t *testing.T) *PostgresUserRepository {
@@ -36,7 +39,10 @@ func setupRepository(t *testing.T) *PostgresUserRepository {
 		_ = db.Close()
 	})
 
-	return NewPostgresUserRepository(db)
+	envelope := newTestEnvelope(t)
+	crypto.SetFieldEnvelope(envelope)
+
+	return NewPostgresUserRepository(db, envelope)
 }
 
 func createTestUser(t *testing.T, email, phone string) *domain.User {
@@ -62,7 +68,17 @@ func cleanupUser(t *testing.T, repo *PostgresUserRepository, userID wisp.UUID) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, _ = repo.db.DB().ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
+	_ = repo.db.WithTx(tenant.WithID(ctx, testTenant), nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
+		return err
+	})
+}
+
+// testTenant is the default tenant; RLS requires app.tenant_id on every write
+const testTenant tenant.ID = "00000000-0000-0000-0000-000000000000"
+
+func tenantContext() context.Context {
+	return tenant.WithID(context.Background(), testTenant)
 }
 
 func TestPostgresUserRepository_CreateUser(t *testing.T) {
@@ -71,7 +87,7 @@ func TestPostgresUserRepository_CreateUser(t *testing.T) {
 		user := createTestUser(t, "integration-test@example.com", "+5511900000001")
 		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
 
-		err := repo.CreateUser(context.Background(), user)
+		err := repo.CreateUser(tenantContext(), user)
 
 		assert.NoError(t, err)
 	})
@@ -81,13 +97,13 @@ func TestPostgresUserRepository_CreateUser(t *testing.T) {
 		user1 := createTestUser(t, "duplicate-email@example.com", "+5511900000002")
 		t.Cleanup(func() { cleanupUser(t, repo, user1.ID) })
 
-		err := repo.CreateUser(context.Background(), user1)
+		err := repo.CreateUser(tenantContext(), user1)
 		require.NoError(t, err)
 
 		user2 := createTestUser(t, "duplicate-email@example.com", "+5511900000003")
 		t.Cleanup(func() { cleanupUser(t, repo, user2.ID) })
 
-		err = repo.CreateUser(context.Background(), user2)
+		err = repo.CreateUser(tenantContext(), user2)
 
 		assert.Error(t, err)
 		assert.True(t, errors.Is(err, domain.ErrUserEmailAlreadyExists))
@@ -98,13 +114,13 @@ func TestPostgresUserRepository_CreateUser(t *testing.T) {
 		user1 := createTestUser(t, "phone-test-1@example.com", "+5511900000004")
 		t.Cleanup(func() { cleanupUser(t, repo, user1.ID) })
 
-		err := repo.CreateUser(context.Background(), user1)
+		err := repo.CreateUser(tenantContext(), user1)
 		require.NoError(t, err)
 
 		user2 := createTestUser(t, "phone-test-2@example.com", "+5511900000004")
 		t.Cleanup(func() { cleanupUser(t, repo, user2.ID) })
 
-		err = repo.CreateUser(context.Background(), user2)
+		err = repo.CreateUser(tenantContext(), user2)
 
 		assert.Error(t, err)
 		assert.True(t, errors.Is(err, domain.ErrUserPhoneAlreadyExists))
@@ -114,7 +130,7 @@ func TestPostgresUserRepository_CreateUser(t *testing.T) {
 		repo := setupRepository(t)
 		user := createTestUser(t, "canceled-ctx@example.com", "+5511900000005")
 
-		ctx, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithCancel(tenantContext())
 		cancel()
 
 		err := repo.CreateUser(ctx, user)
@@ -123,3 +139,260 @@ func TestPostgresUserRepository_CreateUser(t *testing.T) {
 		assert.True(t, errors.Is(err, domain.ErrUserFailedCreateUser))
 	})
 }
+
+func TestPostgresUserRepository_CreateUsers(t *testing.T) {
+	t.Run("creates new users and reports conflicts", func(t *testing.T) {
+		repo := setupRepository(t)
+		existing := createTestUser(t, "bulk-existing@example.com", "+5511900000010")
+		t.Cleanup(func() { cleanupUser(t, repo, existing.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), existing))
+
+		fresh := createTestUser(t, "bulk-fresh@example.com", "+5511900000011")
+		t.Cleanup(func() { cleanupUser(t, repo, fresh.ID) })
+		sameEmail := createTestUser(t, "bulk-existing@example.com", "+5511900000012")
+		samePhone := createTestUser(t, "bulk-phone@example.com", "+5511900000010")
+
+		conflicts, err := repo.CreateUsers(tenantContext(), []*domain.User{fresh, sameEmail, samePhone})
+
+		require.NoError(t, err)
+		require.Len(t, conflicts, 3)
+		assert.NoError(t, conflicts[0])
+		assert.True(t, errors.Is(conflicts[1], domain.ErrUserEmailAlreadyExists))
+		assert.True(t, errors.Is(conflicts[2], domain.ErrUserPhoneAlreadyExists))
+	})
+
+	t.Run("reports the key a row skipped at insert collided on", func(t *testing.T) {
+		repo := setupRepository(t)
+		first := createTestUser(t, "bulk-first@example.com", "+5511900000014")
+		t.Cleanup(func() { cleanupUser(t, repo, first.ID) })
+		samePhone := createTestUser(t, "bulk-second@example.com", "+5511900000014")
+
+		conflicts, err := repo.CreateUsers(tenantContext(), []*domain.User{first, samePhone})
+
+		require.NoError(t, err)
+		require.Len(t, conflicts, 2)
+		assert.NoError(t, conflicts[0])
+		assert.True(t, errors.Is(conflicts[1], domain.ErrUserPhoneAlreadyExists))
+	})
+
+	t.Run("finds conflicts without writing", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "bulk-dry-run@example.com", "+5511900000013")
+
+		conflicts, err := repo.FindConflicts(tenantContext(), []*domain.User{user})
+
+		require.NoError(t, err)
+		assert.NoError(t, conflicts[0])
+	})
+}
+
+func TestPostgresUserRepository_Erasure(t *testing.T) {
+	t.Run("finds, erases and purges a user", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "erasure@example.com", "+5511900000020")
+		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), user))
+
+		found, err := repo.FindUserByID(tenantContext(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.Email, found.Email)
+
+		require.NoError(t, found.Erase(wisp.AuditUser("system")))
+		found.Audit.DeletedAt = wisp.NewNullableTime(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, repo.UpdateUser(tenantContext(), found))
+
+		erased, err := repo.FindUserByID(tenantContext(), user.ID)
+		require.NoError(t, err)
+		assert.True(t, erased.IsErased())
+		assert.True(t, erased.Email.IsEmpty())
+		assert.True(t, erased.Phone.IsZero())
+
+		purged, err := repo.PurgeDeletedUsers(context.Background(), time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		_, err = repo.FindUserByID(tenantContext(), user.ID)
+		assert.True(t, errors.Is(err, domain.ErrUserNotFound))
+	})
+
+	t.Run("returns not found for unknown user", func(t *testing.T) {
+		repo := setupRepository(t)
+
+		_, err := repo.FindUserByID(tenantContext(), wisp.MustNewUUID())
+
+		assert.True(t, errors.Is(err, domain.ErrUserNotFound))
+	})
+}
+
+func TestPostgresUserRepository_UpdateUser(t *testing.T) {
+	t.Run("rejects updates made from a stale version", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "stale@example.com", "+5511900000021")
+		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), user))
+
+		first, err := repo.FindUserByID(tenantContext(), user.ID)
+		require.NoError(t, err)
+		second, err := repo.FindUserByID(tenantContext(), user.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, first.Erase(wisp.AuditUser("system")))
+		require.NoError(t, repo.UpdateUser(tenantContext(), first))
+
+		require.NoError(t, second.Deactivate(wisp.AuditUser("system")))
+		err = repo.UpdateUser(tenantContext(), second)
+
+		assert.True(t, errors.Is(err, domain.ErrUserVersionConflict))
+	})
+
+	t.Run("returns not found for unknown user", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "missing@example.com", "+5511900000022")
+		user.Audit.Touch(wisp.AuditUser("system"))
+
+		err := repo.UpdateUser(tenantContext(), user)
+
+		assert.True(t, errors.Is(err, domain.ErrUserNotFound))
+	})
+}
+
+func TestPostgresUserRepository_Encryption(t *testing.T) {
+	t.Run("stores contact data encrypted", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "encrypted@example.com", "+5511900000030")
+		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), user))
+
+		var email, emailIndex string
+		err := repo.db.WithTx(tenantContext(), nil, func(ctx context.Context, tx *sql.Tx) error {
+			return tx.QueryRowContext(ctx, "SELECT email, email_bidx FROM users WHERE id = $1", user.ID).Scan(&email, &emailIndex)
+		})
+		require.NoError(t, err)
+
+		assert.True(t, crypto.IsCiphertext(email))
+		assert.NotContains(t, email, "encrypted@example.com")
+		assert.Equal(t, repo.envelope.BlindIndex(emailIndexPurpose, "encrypted@example.com"), emailIndex)
+	})
+
+	t.Run("re-encrypts plaintext rows", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "legacy@example.com", "+5511900000031")
+		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), user))
+		makeLegacyUser(t, repo, user)
+
+		_, err := repo.ReencryptUsers(context.Background(), 100, nil)
+		require.NoError(t, err)
+
+		var email string
+		err = repo.db.WithTx(tenantContext(), nil, func(ctx context.Context, tx *sql.Tx) error {
+			return tx.QueryRowContext(ctx, "SELECT email FROM users WHERE id = $1", user.ID).Scan(&email)
+		})
+		require.NoError(t, err)
+		assert.True(t, crypto.IsCiphertext(email))
+
+		found, err := repo.FindUserByID(tenantContext(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "legacy@example.com", found.Email.String())
+	})
+}
+
+func TestPostgresUserRepository_LegacyUsers(t *testing.T) {
+	t.Run("finds plaintext rows by email", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "legacy-login@example.com", "+5511900000032")
+		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), user))
+		makeLegacyUser(t, repo, user)
+
+		found, err := repo.FindUserByEmail(tenantContext(), user.Email)
+
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+	})
+
+	t.Run("rejects duplicates of plaintext rows", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "legacy-dup@example.com", "+5511900000033")
+		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), user))
+		makeLegacyUser(t, repo, user)
+
+		duplicate := createTestUser(t, "legacy-dup@example.com", "+5511900000034")
+		t.Cleanup(func() { cleanupUser(t, repo, duplicate.ID) })
+		err := repo.CreateUser(tenantContext(), duplicate)
+
+		assert.True(t, errors.Is(err, domain.ErrUserEmailAlreadyExists))
+	})
+
+	t.Run("reports users whose blind index is taken", func(t *testing.T) {
+		repo := setupRepository(t)
+		legacy := createTestUser(t, "legacy-conflict@example.com", "+5511900000035")
+		other := createTestUser(t, "other-conflict@example.com", "+5511900000036")
+		t.Cleanup(func() {
+			cleanupUser(t, repo, legacy.ID)
+			cleanupUser(t, repo, other.ID)
+		})
+		require.NoError(t, repo.CreateUser(tenantContext(), legacy))
+		makeLegacyUser(t, repo, legacy)
+		require.NoError(t, repo.CreateUser(tenantContext(), other))
+
+		// A row written while the legacy one had no index holds its email index
+		err := repo.db.WithTx(tenantContext(), nil, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE users SET email_bidx = $2 WHERE id = $1",
+				other.ID, repo.emailIndex(legacy.Email))
+			return err
+		})
+		require.NoError(t, err)
+
+		result, err := repo.ReencryptUsers(context.Background(), 100, nil)
+
+		require.NoError(t, err)
+		assert.Contains(t, result.Conflicts, legacy.ID)
+
+		result, err = repo.ReencryptUsers(context.Background(), 100, result.Conflicts)
+
+		require.NoError(t, err)
+		assert.NotContains(t, result.Conflicts, legacy.ID)
+	})
+}
+
+// makeLegacyUser rewrites user as stored before encryption: plaintext
+// email without blind index
+func makeLegacyUser(t *testing.T, repo *PostgresUserRepository, user *domain.User) {
+	t.Helper()
+
+	err := repo.db.WithTx(tenantContext(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE users SET email = $2, email_bidx = NULL WHERE id = $1",
+			user.ID, user.Email.String(),
+		)
+		return err
+	})
+	require.NoError(t, err)
+}
+
+func TestPostgresUserRepository_FindUserByEmail(t *testing.T) {
+	t.Run("finds user by email blind index", func(t *testing.T) {
+		repo := setupRepository(t)
+		user := createTestUser(t, "find-by-email@example.com", "+5511900000040")
+		t.Cleanup(func() { cleanupUser(t, repo, user.ID) })
+		require.NoError(t, repo.CreateUser(tenantContext(), user))
+
+		found, err := repo.FindUserByEmail(tenantContext(), user.Email)
+
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+		assert.Equal(t, user.PasswordHash, found.PasswordHash)
+	})
+
+	t.Run("returns not found for unknown email", func(t *testing.T) {
+		repo := setupRepository(t)
+		email, err := wisp.NewEmail("unknown-email@example.com")
+		require.NoError(t, err)
+
+		_, err = repo.FindUserByEmail(tenantContext(), email)
+
+		assert.True(t, errors.Is(err, domain.ErrUserNotFound))
+	})
+}
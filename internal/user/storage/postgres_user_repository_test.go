@@ -1,17 +1,42 @@
 package storage
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/marcelofabianov/course/internal/user/domain"
 	"github.com/marcelofabianov/course/pkg/database"
 )
 
 func TestNewPostgresUserRepository(t *testing.T) {
 	t.Run("creates repository with valid database", func(t *testing.T) {
-		repo := NewPostgresUserRepository(&database.DB{})
+		repo := NewPostgresUserRepository(&database.DB{}, nil)
 
 		assert.NotNil(t, repo)
 	})
 }
+
+func TestPostgresUserRepository_ConstraintErrors(t *testing.T) {
+	repo := NewPostgresUserRepository(&database.DB{}, nil)
+
+	t.Run("maps email unique constraint", func(t *testing.T) {
+		err := repo.errors.Translate(&pgconn.PgError{
+			Code:           database.SQLStateUniqueViolation,
+			ConstraintName: "users_tenant_email_bidx_key",
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserEmailAlreadyExists))
+	})
+
+	t.Run("maps phone unique constraint", func(t *testing.T) {
+		err := repo.errors.Translate(&pgconn.PgError{
+			Code:           database.SQLStateUniqueViolation,
+			ConstraintName: "users_tenant_phone_bidx_key",
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserPhoneAlreadyExists))
+	})
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/marcelofabianov/wisp"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/pkg/crypto"
+)
+
+// Blind index purposes; changing them invalidates every stored index
+const (
+	emailIndexPurpose = "users.email"
+	phoneIndexPurpose = "users.phone"
+)
+
+// userRecord is a row of users. Email and phone are stored encrypted, with
+// a blind index for lookups and uniqueness.
+type userRecord struct {
+	ID           wisp.UUID             `db:"id"`
+	Name         wisp.NonEmptyString   `db:"name"`
+	Email        crypto.EncryptedEmail `db:"email"`
+	EmailIndex   sql.NullString        `db:"email_bidx"`
+	PasswordHash wisp.NonEmptyString   `db:"hashed_password"`
+	Phone        crypto.EncryptedPhone `db:"phone"`
+	PhoneIndex   sql.NullString        `db:"phone_bidx"`
+	Role         domain.UserRole       `db:"role"`
+	IsActive     bool                  `db:"is_active"`
+	ErasedAt     wisp.NullableTime     `db:"erased_at"`
+	wisp.Audit
+}
+
+func (r *PostgresUserRepository) toRecord(user *domain.User) *userRecord {
+	return &userRecord{
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        crypto.EncryptedEmail{Val: user.Email},
+		EmailIndex:   r.emailIndex(user.Email),
+		PasswordHash: user.PasswordHash,
+		Phone:        crypto.EncryptedPhone{Val: user.Phone},
+		PhoneIndex:   r.phoneIndex(user.Phone),
+		Role:         user.Role,
+		IsActive:     user.IsActive,
+		ErasedAt:     user.ErasedAt,
+		Audit:        user.Audit,
+	}
+}
+
+func (rec *userRecord) toDomain() *domain.User {
+	return &domain.User{
+		ID:           rec.ID,
+		Name:         rec.Name,
+		Email:        rec.Email.Val,
+		PasswordHash: rec.PasswordHash,
+		Phone:        rec.Phone.Val,
+		Role:         rec.Role,
+		IsActive:     rec.IsActive,
+		ErasedAt:     rec.ErasedAt,
+		Audit:        rec.Audit,
+	}
+}
+
+func (r *PostgresUserRepository) emailIndex(email wisp.Email) sql.NullString {
+	if email.IsEmpty() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: r.envelope.BlindIndex(emailIndexPurpose, email.String()), Valid: true}
+}
+
+func (r *PostgresUserRepository) phoneIndex(phone wisp.Phone) sql.NullString {
+	if phone.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: r.envelope.BlindIndex(phoneIndexPurpose, phone.String()), Valid: true}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/marcelofabianov/wisp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/pkg/crypto"
+	"github.com/marcelofabianov/course/pkg/database"
+)
+
+func newTestEnvelope(t *testing.T) *crypto.Envelope {
+	t.Helper()
+	envelope, err := crypto.NewEnvelope(crypto.EnvelopeConfig{
+		Keys:          map[int]string{1: "OR5eiHFkdjWXLue8DCZ9HMBqYKC9OYj29p2H+Buf2H8="},
+		ActiveVersion: 1,
+		BlindIndexKey: "JyazSXxTKPxrF3vUxk8tuqJHiXVzOwTZyPIoYOIFzuk=",
+	})
+	require.NoError(t, err)
+	return envelope
+}
+
+func newRecordUser(t *testing.T) *domain.User {
+	t.Helper()
+	hash, _ := wisp.NewNonEmptyString("$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5")
+	user, err := domain.NewUser(&domain.NewUserInput{
+		Name:  "John Doe",
+		Email: "john@example.com",
+		Phone: "+5511999999999",
+		Role:  "common",
+	}, hash, wisp.AuditUser("system"))
+	require.NoError(t, err)
+	return user
+}
+
+func TestUserRecord(t *testing.T) {
+	envelope := newTestEnvelope(t)
+	repo := NewPostgresUserRepository(&database.DB{}, envelope)
+
+	t.Run("indexes contact data and round trips to the domain", func(t *testing.T) {
+		user := newRecordUser(t)
+
+		record := repo.toRecord(user)
+
+		assert.Equal(t, envelope.BlindIndex(emailIndexPurpose, user.Email.String()), record.EmailIndex.String)
+		assert.Equal(t, envelope.BlindIndex(phoneIndexPurpose, user.Phone.String()), record.PhoneIndex.String)
+		assert.Equal(t, user, record.toDomain())
+	})
+
+	t.Run("same email gets the same index", func(t *testing.T) {
+		first := repo.toRecord(newRecordUser(t))
+		second := repo.toRecord(newRecordUser(t))
+
+		assert.Equal(t, first.EmailIndex, second.EmailIndex)
+	})
+
+	t.Run("erased users have no index", func(t *testing.T) {
+		user := newRecordUser(t)
+		require.NoError(t, user.Erase(wisp.AuditUser("system")))
+
+		record := repo.toRecord(user)
+
+		assert.False(t, record.EmailIndex.Valid)
+		assert.False(t, record.PhoneIndex.Valid)
+	})
+}
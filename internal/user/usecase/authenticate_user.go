@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/marcelofabianov/wisp"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+type AuthenticateUserUseCase struct {
+	repo   port.AuthenticateUserRepositoryPort
+	hasher port.PasswordVerifierPort
+	logger *slog.Logger
+}
+
+func NewAuthenticateUserUseCase(repo port.AuthenticateUserRepositoryPort, hasher port.PasswordVerifierPort) *AuthenticateUserUseCase {
+	return &AuthenticateUserUseCase{
+		repo:   repo,
+		hasher: hasher,
+		logger: slog.Default(),
+	}
+}
+
+// SetLogger sets the logger used to report failed rehashes
+func (uc *AuthenticateUserUseCase) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		uc.logger = logger
+	}
+}
+
+func (uc *AuthenticateUserUseCase) Execute(ctx context.Context, input *port.AuthenticateUserInput) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "user.AuthenticateUser")
+	defer func() { tracing.End(span, err) }()
+
+	email, err := wisp.NewEmail(input.Email)
+	if err != nil {
+		return nil, domain.NewErrUserInvalidCredentials()
+	}
+
+	user, err := uc.repo.FindUserByEmail(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		// Spend the time of a verification so response times do not
+		// reveal which emails are registered
+		_, _ = uc.hasher.Hash(input.Password)
+		return nil, domain.NewErrUserInvalidCredentials()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := uc.hasher.Verify(input.Password, user.PasswordHash.String())
+	if err != nil || !match || !user.IsActiveAndNotArchived() || user.IsErased() {
+		return nil, domain.NewErrUserInvalidCredentials()
+	}
+
+	if uc.hasher.NeedsRehash(user.PasswordHash.String()) {
+		uc.rehash(ctx, user, input.Password)
+	}
+
+	return user, nil
+}
+
+// rehash replaces an outdated hash while the plain password is at hand.
+// Failures are only logged: the login succeeded and the next one retries.
+// The update is versioned, so a concurrent change of the user, such as a
+// password change, wins over the rehash.
+func (uc *AuthenticateUserUseCase) rehash(ctx context.Context, user *domain.User, password string) {
+	passwordHash, err := uc.hasher.Hash(password)
+	if err != nil || passwordHash == "" {
+		uc.logger.WarnContext(ctx, "Failed to rehash password", "user_id", user.ID.String())
+		return
+	}
+
+	hash, _ := wisp.NewNonEmptyString(passwordHash)
+	user.ChangePassword(hash, wisp.AuditUser("system"))
+
+	err = uc.repo.UpdateUser(ctx, user)
+	if errors.Is(err, domain.ErrUserVersionConflict) {
+		uc.logger.InfoContext(ctx, "Skipped password rehash of a user changed concurrently",
+			"user_id", user.ID.String(),
+		)
+		return
+	}
+	if err != nil {
+		uc.logger.WarnContext(ctx, "Failed to store rehashed password",
+			"user_id", user.ID.String(),
+			"error", err.Error(),
+		)
+	}
+}
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+)
+
+type mockVerifier struct {
+	mockHasher
+	match       bool
+	needsRehash bool
+	hashed      int
+}
+
+func (m *mockVerifier) Hash(password string) (string, error) {
+	m.hashed++
+	return m.mockHasher.Hash(password)
+}
+
+func (m *mockVerifier) Verify(_, _ string) (bool, error) {
+	return m.match, nil
+}
+
+func (m *mockVerifier) NeedsRehash(_ string) bool {
+	return m.needsRehash
+}
+
+const rehashedPassword = "$argon2id$v=19$m=131072,t=4,p=4$c2FsdA$a2V5"
+
+func newVerifier(match, needsRehash bool) *mockVerifier {
+	return &mockVerifier{
+		mockHasher:  mockHasher{hash: rehashedPassword},
+		match:       match,
+		needsRehash: needsRehash,
+	}
+}
+
+func credentials() *port.AuthenticateUserInput {
+	return &port.AuthenticateUserInput{Email: "john@example.com", Password: "Test@123!"}
+}
+
+func TestAuthenticateUserUseCase_Execute(t *testing.T) {
+	t.Run("returns the user for valid credentials", func(t *testing.T) {
+		store := &mockUserStore{user: newStoredUser(t)}
+		uc := NewAuthenticateUserUseCase(store, newVerifier(true, false))
+
+		user, err := uc.Execute(context.Background(), credentials())
+
+		require.NoError(t, err)
+		assert.Equal(t, store.user.ID, user.ID)
+		assert.Nil(t, store.updated)
+	})
+
+	t.Run("rehashes and stores outdated hashes", func(t *testing.T) {
+		store := &mockUserStore{user: newStoredUser(t)}
+		uc := NewAuthenticateUserUseCase(store, newVerifier(true, true))
+
+		user, err := uc.Execute(context.Background(), credentials())
+
+		require.NoError(t, err)
+		require.NotNil(t, store.updated)
+		assert.Equal(t, rehashedPassword, store.updated.PasswordHash.String())
+		assert.Equal(t, 2, user.Audit.Version.Int())
+	})
+
+	t.Run("logs in even when the rehash cannot be stored", func(t *testing.T) {
+		store := &mockUserStore{user: newStoredUser(t), updateErr: domain.NewErrUserFailedUpdateUser()}
+		uc := NewAuthenticateUserUseCase(store, newVerifier(true, true))
+
+		_, err := uc.Execute(context.Background(), credentials())
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("logs in when a concurrent change wins over the rehash", func(t *testing.T) {
+		user := newStoredUser(t)
+		store := &mockUserStore{user: user, updateErr: domain.NewErrUserVersionConflict(user.ID.String())}
+		uc := NewAuthenticateUserUseCase(store, newVerifier(true, true))
+
+		_, err := uc.Execute(context.Background(), credentials())
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects wrong password", func(t *testing.T) {
+		store := &mockUserStore{user: newStoredUser(t)}
+		uc := NewAuthenticateUserUseCase(store, newVerifier(false, true))
+
+		_, err := uc.Execute(context.Background(), credentials())
+
+		assert.True(t, errors.Is(err, domain.ErrUserInvalidCredentials))
+		assert.Nil(t, store.updated)
+	})
+
+	t.Run("rejects unknown email after hashing", func(t *testing.T) {
+		verifier := newVerifier(true, false)
+		uc := NewAuthenticateUserUseCase(&mockUserStore{}, verifier)
+
+		_, err := uc.Execute(context.Background(), credentials())
+
+		assert.True(t, errors.Is(err, domain.ErrUserInvalidCredentials))
+		assert.Equal(t, 1, verifier.hashed)
+	})
+
+	t.Run("rejects inactive users", func(t *testing.T) {
+		user := newStoredUser(t)
+		user.IsActive = false
+		uc := NewAuthenticateUserUseCase(&mockUserStore{user: user}, newVerifier(true, false))
+
+		_, err := uc.Execute(context.Background(), credentials())
+
+		assert.True(t, errors.Is(err, domain.ErrUserInvalidCredentials))
+	})
+
+	t.Run("returns repository failures", func(t *testing.T) {
+		store := &mockUserStore{findErr: domain.NewErrUserFailedFindUser()}
+		uc := NewAuthenticateUserUseCase(store, newVerifier(true, false))
+
+		_, err := uc.Execute(context.Background(), credentials())
+
+		assert.True(t, errors.Is(err, domain.ErrUserFailedFindUser))
+	})
+}
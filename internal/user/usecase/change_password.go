@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/marcelofabianov/wisp"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/password"
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+type ChangePasswordUseCase struct {
+	repo   port.UpdateUserRepositoryPort
+	hasher port.PasswordVerifierPort
+	policy port.PasswordPolicyPort
+}
+
+func NewChangePasswordUseCase(
+	repo port.UpdateUserRepositoryPort,
+	hasher port.PasswordVerifierPort,
+	policy port.PasswordPolicyPort,
+) *ChangePasswordUseCase {
+	return &ChangePasswordUseCase{
+		repo:   repo,
+		hasher: hasher,
+		policy: policy,
+	}
+}
+
+// Execute replaces the password of the user after checking the current one
+func (uc *ChangePasswordUseCase) Execute(ctx context.Context, id string, input *port.ChangePasswordInput) (err error) {
+	ctx, span := tracing.Start(ctx, "user.ChangePassword")
+	defer func() { tracing.End(span, err) }()
+
+	userID, err := wisp.ParseUUID(id)
+	if err != nil {
+		return domain.NewErrUserNotFound(id)
+	}
+
+	user, err := uc.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	match, err := uc.hasher.Verify(input.OldPassword, user.PasswordHash.String())
+	if err != nil || !match || !user.IsActiveAndNotArchived() || user.IsErased() {
+		return domain.NewErrUserInvalidCredentials()
+	}
+
+	if err := uc.policy.Validate(input.NewPassword, password.UserInfo{
+		Name:  user.Name.String(),
+		Email: user.Email.String(),
+	}); err != nil {
+		return err
+	}
+
+	passwordHash, err := uc.hasher.Hash(input.NewPassword)
+	if err != nil || passwordHash == "" {
+		return domain.NewErrUserFailedHashPassword()
+	}
+
+	hash, _ := wisp.NewNonEmptyString(passwordHash)
+	user.ChangePassword(hash, wisp.AuditUser(user.ID.String()))
+
+	return uc.repo.UpdateUser(ctx, user)
+}
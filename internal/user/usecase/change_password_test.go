@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/password"
+)
+
+func passwordChange(newPassword string) *port.ChangePasswordInput {
+	return &port.ChangePasswordInput{OldPassword: "Test@123!", NewPassword: newPassword}
+}
+
+func TestChangePasswordUseCase_Execute(t *testing.T) {
+	t.Run("stores the new hash", func(t *testing.T) {
+		store := &mockUserStore{user: newStoredUser(t)}
+		uc := NewChangePasswordUseCase(store, newVerifier(true, false), testPolicy)
+
+		err := uc.Execute(context.Background(), store.user.ID.String(), passwordChange("N3w#Secret"))
+
+		require.NoError(t, err)
+		require.NotNil(t, store.updated)
+		assert.Equal(t, rehashedPassword, store.updated.PasswordHash.String())
+	})
+
+	t.Run("rejects wrong current password", func(t *testing.T) {
+		store := &mockUserStore{user: newStoredUser(t)}
+		uc := NewChangePasswordUseCase(store, newVerifier(false, false), testPolicy)
+
+		err := uc.Execute(context.Background(), store.user.ID.String(), passwordChange("N3w#Secret"))
+
+		assert.True(t, errors.Is(err, domain.ErrUserInvalidCredentials))
+		assert.Nil(t, store.updated)
+	})
+
+	t.Run("rejects new password that breaks the policy", func(t *testing.T) {
+		store := &mockUserStore{user: newStoredUser(t)}
+		uc := NewChangePasswordUseCase(store, newVerifier(true, false), testPolicy)
+
+		err := uc.Execute(context.Background(), store.user.ID.String(), passwordChange("doe#Secret1"))
+
+		assert.True(t, errors.Is(err, password.ErrPolicyViolation))
+		assert.Nil(t, store.updated)
+	})
+
+	t.Run("returns not found for invalid id", func(t *testing.T) {
+		uc := NewChangePasswordUseCase(&mockUserStore{}, newVerifier(true, false), testPolicy)
+
+		err := uc.Execute(context.Background(), "not-a-uuid", passwordChange("N3w#Secret"))
+
+		assert.True(t, errors.Is(err, domain.ErrUserNotFound))
+	})
+}
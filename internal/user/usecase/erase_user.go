@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/marcelofabianov/wisp"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+type EraseUserUseCase struct {
+	repo port.UpdateUserRepositoryPort
+}
+
+func NewEraseUserUseCase(repo port.UpdateUserRepositoryPort) *EraseUserUseCase {
+	return &EraseUserUseCase{repo: repo}
+}
+
+func (uc *EraseUserUseCase) Execute(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "user.EraseUser")
+	defer func() { tracing.End(span, err) }()
+
+	userID, err := wisp.ParseUUID(id)
+	if err != nil {
+		return domain.NewErrUserNotFound(id)
+	}
+
+	user, err := uc.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := user.Erase(wisp.AuditUser("system")); err != nil {
+		return err
+	}
+
+	return uc.repo.UpdateUser(ctx, user)
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/marcelofabianov/wisp"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+type ExportUserUseCase struct {
+	repo port.FindUserRepositoryPort
+}
+
+func NewExportUserUseCase(repo port.FindUserRepositoryPort) *ExportUserUseCase {
+	return &ExportUserUseCase{repo: repo}
+}
+
+func (uc *ExportUserUseCase) Execute(ctx context.Context, id string) (_ *port.UserDataExport, err error) {
+	ctx, span := tracing.Start(ctx, "user.ExportUser")
+	defer func() { tracing.End(span, err) }()
+
+	userID, err := wisp.ParseUUID(id)
+	if err != nil {
+		return nil, domain.NewErrUserNotFound(id)
+	}
+
+	user, err := uc.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &port.UserDataExport{
+		SchemaVersion: port.UserDataExportSchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		User: port.UserExportData{
+			ID:          user.ID.String(),
+			Name:        user.Name.String(),
+			Email:       user.Email.String(),
+			Phone:       user.Phone.String(),
+			Role:        user.Role.String(),
+			IsActive:    user.IsActive,
+			HasPassword: !user.IsErased(),
+			Audit:       user.Audit,
+		},
+	}
+
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		export.TenantID = tenantID.String()
+	}
+
+	if user.IsErased() {
+		erasedAt := user.ErasedAt.Time
+		export.User.ErasedAt = &erasedAt
+	}
+
+	return export, nil
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+)
+
+const maxImportLineSize = 64 * 1024
+
+var importColumns = []string{"name", "email", "password", "phone", "role"}
+
+// importRow is a parsed line of an import file. err is set when the line
+// itself could not be decoded; the import continues with the next line.
+type importRow struct {
+	line  int
+	input *port.RegisterUserInput
+	err   error
+}
+
+type importReader interface {
+	// Next returns the next row or io.EOF when the file ends
+	Next() (*importRow, error)
+}
+
+func newImportReader(format port.ImportFormat, data io.Reader) (importReader, error) {
+	switch format {
+	case port.ImportFormatCSV:
+		return newCSVImportReader(data)
+	case port.ImportFormatJSONL:
+		scanner := bufio.NewScanner(data)
+		scanner.Buffer(make([]byte, 0, 4096), maxImportLineSize)
+		return &jsonlImportReader{scanner: scanner}, nil
+	default:
+		return nil, domain.NewErrUserImportInvalidFormat(string(format))
+	}
+}
+
+// csvImportReader reads CSV files whose first line names the columns
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVImportReader(data io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, domain.NewErrUserImportMalformedFile("missing CSV header")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, domain.NewErrUserImportMalformedFile(fmt.Sprintf("missing CSV column %q", name))
+		}
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvImportReader) Next() (*importRow, error) {
+	record, err := r.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		// FieldPos panics after a failed read, so the line of a malformed
+		// record comes from the parse error
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return &importRow{line: parseErr.StartLine, err: parseErr.Err}, nil
+		}
+		return nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+
+	field := func(name string) string {
+		if i := r.columns[name]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	return &importRow{
+		line: line,
+		input: &port.RegisterUserInput{
+			Name:     field("name"),
+			Email:    field("email"),
+			Password: field("password"),
+			Phone:    field("phone"),
+			Role:     field("role"),
+		},
+	}, nil
+}
+
+// jsonlImportReader reads one JSON object per line, skipping blank lines
+type jsonlImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlImportReader) Next() (*importRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		text := bytes.TrimSpace(r.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+
+		var input port.RegisterUserInput
+		if err := decoder.Decode(&input); err != nil {
+			return &importRow{line: r.line, err: errors.New("invalid JSON object")}, nil
+		}
+
+		return &importRow{line: r.line, input: &input}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, domain.NewErrUserImportMalformedFile(err.Error())
+	}
+
+	return nil, io.EOF
+}
@@ -0,0 +1,351 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marcelofabianov/fault"
+	"github.com/marcelofabianov/wisp"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/password"
+	"github.com/marcelofabianov/course/pkg/tracing"
+	"github.com/marcelofabianov/course/pkg/validation"
+)
+
+const (
+	importBatchSize  = 500
+	importMaxRows    = 10000
+	importJobTimeout = 30 * time.Minute
+
+	// dryRunPasswordHash stands in for the real hash so dry runs skip hashing
+	dryRunPasswordHash = "dry-run"
+)
+
+type ImportUsersUseCase struct {
+	repo      port.BulkCreateUserRepositoryPort
+	hasher    port.PasswordHasherPort
+	validator validation.Validator
+	policy    port.PasswordPolicyPort
+	jobs      port.ImportJobStorePort
+	logger    *slog.Logger
+}
+
+func NewImportUsersUseCase(
+	repo port.BulkCreateUserRepositoryPort,
+	hasher port.PasswordHasherPort,
+	validator validation.Validator,
+	policy port.PasswordPolicyPort,
+	jobs port.ImportJobStorePort,
+) *ImportUsersUseCase {
+	return &ImportUsersUseCase{
+		repo:      repo,
+		hasher:    hasher,
+		validator: validator,
+		policy:    policy,
+		jobs:      jobs,
+		logger:    slog.Default(),
+	}
+}
+
+// SetLogger sets the logger used by background jobs
+func (uc *ImportUsersUseCase) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		uc.logger = logger
+	}
+}
+
+// pendingUser is a valid row waiting for its batch to be written
+type pendingUser struct {
+	row  int
+	user *domain.User
+}
+
+// Execute reads and validates the whole file before the first write, so
+// a malformed or oversized file creates no users. When a batch write
+// fails, the partial report is returned together with the error: rows
+// already written keep their status and the remaining ones are skipped.
+func (uc *ImportUsersUseCase) Execute(ctx context.Context, input *port.ImportUsersInput) (_ *port.ImportUsersReport, err error) {
+	ctx, span := tracing.Start(ctx, "user.ImportUsers")
+	defer func() { tracing.End(span, err) }()
+
+	reader, err := newImportReader(input.Format, input.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &port.ImportUsersReport{DryRun: input.DryRun, Rows: []port.ImportRowResult{}}
+	seenEmails := make(map[string]bool)
+	seenPhones := make(map[string]bool)
+	var pending []pendingUser
+
+	for {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if report.Total >= importMaxRows {
+			return nil, domain.NewErrUserImportTooManyRows(importMaxRows)
+		}
+		report.Total++
+
+		result := port.ImportRowResult{Line: row.line}
+		if row.input != nil {
+			result.Email = row.input.Email
+		}
+
+		user, err := uc.buildUser(ctx, row, input.DryRun)
+		switch {
+		case err != nil:
+			result.Status = port.ImportRowInvalid
+			result.Error = rowError(err)
+		case seenEmails[user.Email.String()]:
+			result.Status = port.ImportRowConflict
+			result.Error = domain.ErrUserEmailAlreadyExists.Error()
+		case seenPhones[user.Phone.String()]:
+			result.Status = port.ImportRowConflict
+			result.Error = domain.ErrUserPhoneAlreadyExists.Error()
+		default:
+			seenEmails[user.Email.String()] = true
+			seenPhones[user.Phone.String()] = true
+			pending = append(pending, pendingUser{row: len(report.Rows), user: user})
+		}
+
+		report.Rows = append(report.Rows, result)
+	}
+
+	for start := 0; start < len(pending); start += importBatchSize {
+		batch := pending[start:min(start+importBatchSize, len(pending))]
+		if err := uc.flush(ctx, report, batch, input.DryRun); err != nil {
+			report.Error = rowError(err)
+			for _, skipped := range pending[start:] {
+				report.Rows[skipped.row].Status = port.ImportRowSkipped
+			}
+			countRows(report)
+			return report, err
+		}
+	}
+
+	countRows(report)
+	return report, nil
+}
+
+func countRows(report *port.ImportUsersReport) {
+	for _, row := range report.Rows {
+		switch row.Status {
+		case port.ImportRowCreated:
+			report.Created++
+		case port.ImportRowValid:
+			report.Valid++
+		case port.ImportRowConflict:
+			report.Conflicts++
+		case port.ImportRowInvalid:
+			report.Invalid++
+		case port.ImportRowSkipped:
+			report.Skipped++
+		}
+	}
+}
+
+func (uc *ImportUsersUseCase) buildUser(ctx context.Context, row *importRow, dryRun bool) (*domain.User, error) {
+	if row.err != nil {
+		return nil, row.err
+	}
+
+	if err := uc.validator.Struct(ctx, row.input); err != nil {
+		return nil, err
+	}
+
+	if err := uc.policy.Validate(row.input.Password, password.UserInfo{
+		Name:  row.input.Name,
+		Email: row.input.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	passwordHash := dryRunPasswordHash
+	if !dryRun {
+		var err error
+		passwordHash, err = uc.hasher.Hash(row.input.Password)
+		if err != nil || passwordHash == "" {
+			return nil, domain.NewErrUserFailedHashPassword()
+		}
+	}
+
+	hash, _ := wisp.NewNonEmptyString(passwordHash)
+
+	return domain.NewUser(&domain.NewUserInput{
+		Name:  row.input.Name,
+		Email: row.input.Email,
+		Role:  row.input.Role,
+		Phone: row.input.Phone,
+	}, hash, wisp.AuditUser("system"))
+}
+
+// flush checks (dry run) or inserts a batch and records the outcome of each row
+func (uc *ImportUsersUseCase) flush(ctx context.Context, report *port.ImportUsersReport, batch []pendingUser, dryRun bool) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	users := make([]*domain.User, len(batch))
+	for i, pending := range batch {
+		users[i] = pending.user
+	}
+
+	var (
+		conflicts []error
+		err       error
+	)
+	if dryRun {
+		conflicts, err = uc.repo.FindConflicts(ctx, users)
+	} else {
+		conflicts, err = uc.repo.CreateUsers(ctx, users)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, pending := range batch {
+		result := &report.Rows[pending.row]
+		switch {
+		case conflicts[i] != nil:
+			result.Status = port.ImportRowConflict
+			result.Error = rowError(conflicts[i])
+		case dryRun:
+			result.Status = port.ImportRowValid
+		default:
+			result.Status = port.ImportRowCreated
+			result.UserID = pending.user.ID.String()
+		}
+	}
+
+	return nil
+}
+
+// Start buffers the upload, since the request body is closed once the
+// handler returns, and runs the import in background
+func (uc *ImportUsersUseCase) Start(ctx context.Context, input *port.ImportUsersInput) (*port.ImportJob, error) {
+	if input.Format != port.ImportFormatCSV && input.Format != port.ImportFormatJSONL {
+		return nil, domain.NewErrUserImportInvalidFormat(string(input.Format))
+	}
+
+	data, err := io.ReadAll(input.Data)
+	if err != nil {
+		return nil, domain.NewErrUserImportMalformedFile(err.Error())
+	}
+
+	job := &port.ImportJob{
+		ID:        uuid.NewString(),
+		Status:    port.ImportJobPending,
+		DryRun:    input.DryRun,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := uc.jobs.SaveImportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Keep request values such as the tenant, but not its cancellation
+	jobCtx := context.WithoutCancel(ctx)
+	pending := *job
+
+	go uc.run(jobCtx, &pending, &port.ImportUsersInput{
+		Format: input.Format,
+		DryRun: input.DryRun,
+		Data:   bytes.NewReader(data),
+	})
+
+	return job, nil
+}
+
+func (uc *ImportUsersUseCase) run(ctx context.Context, job *port.ImportJob, input *port.ImportUsersInput) {
+	ctx, cancel := context.WithTimeout(ctx, importJobTimeout)
+	defer cancel()
+
+	job.Status = port.ImportJobRunning
+	if err := uc.jobs.SaveImportJob(ctx, job); err != nil {
+		uc.logger.ErrorContext(ctx, "Failed to update import job", "job_id", job.ID, "error", err.Error())
+	}
+
+	report, err := uc.execute(ctx, input)
+
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	if err != nil {
+		job.Status = port.ImportJobFailed
+		job.Error = rowError(err)
+		job.Report = report
+		uc.logger.ErrorContext(ctx, "User import job failed", "job_id", job.ID, "error", err.Error())
+	} else {
+		job.Status = port.ImportJobCompleted
+		job.Report = report
+		uc.logger.InfoContext(ctx, "User import job completed",
+			"job_id", job.ID,
+			"total", report.Total,
+			"created", report.Created,
+			"conflicts", report.Conflicts,
+			"invalid", report.Invalid,
+		)
+	}
+
+	// The job deadline may have expired; the final state must still be stored
+	saveCtx, saveCancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer saveCancel()
+
+	if err := uc.jobs.SaveImportJob(saveCtx, job); err != nil {
+		uc.logger.ErrorContext(ctx, "Failed to store import job result", "job_id", job.ID, "error", err.Error())
+	}
+}
+
+// execute runs Execute for a background job. There is no request left to
+// recover a panic, so it becomes an error that fails the job instead of
+// crashing the process.
+func (uc *ImportUsersUseCase) execute(ctx context.Context, input *port.ImportUsersInput) (report *port.ImportUsersReport, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			uc.logger.ErrorContext(ctx, "User import job panicked",
+				"panic", fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+			)
+			report, err = nil, domain.NewErrUserImportFailed(fmt.Sprint(recovered))
+		}
+	}()
+
+	return uc.Execute(ctx, input)
+}
+
+func (uc *ImportUsersUseCase) Job(ctx context.Context, id string) (*port.ImportJob, error) {
+	return uc.jobs.FindImportJob(ctx, id)
+}
+
+// rowError returns the client facing message of err, followed by the
+// messages of its details, such as password policy violations
+func rowError(err error) string {
+	faultErr, ok := fault.AsFault(err)
+	if !ok {
+		return err.Error()
+	}
+
+	if len(faultErr.Details) == 0 {
+		return faultErr.Message
+	}
+
+	details := make([]string, len(faultErr.Details))
+	for i, detail := range faultErr.Details {
+		details[i] = detail.Message
+	}
+	return faultErr.Message + ": " + strings.Join(details, "; ")
+}
@@ -0,0 +1,369 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/validation"
+)
+
+type mockBulkRepository struct {
+	conflicts map[string]error
+	err       error
+	failAfter int // successful writes before err is returned
+	panics    bool
+	writes    int
+	created   []*domain.User
+	checked   []*domain.User
+}
+
+func (m *mockBulkRepository) result(users []*domain.User) []error {
+	errs := make([]error, len(users))
+	for i, user := range users {
+		errs[i] = m.conflicts[user.Email.String()]
+	}
+	return errs
+}
+
+func (m *mockBulkRepository) FindConflicts(_ context.Context, users []*domain.User) ([]error, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.checked = append(m.checked, users...)
+	return m.result(users), nil
+}
+
+func (m *mockBulkRepository) CreateUsers(_ context.Context, users []*domain.User) ([]error, error) {
+	if m.panics {
+		panic("unexpected repository state")
+	}
+	if m.err != nil && m.writes >= m.failAfter {
+		return nil, m.err
+	}
+	m.writes++
+	errs := m.result(users)
+	for i, user := range users {
+		if errs[i] == nil {
+			m.created = append(m.created, user)
+		}
+	}
+	return errs, nil
+}
+
+type mockJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]port.ImportJob
+}
+
+func newMockJobStore() *mockJobStore {
+	return &mockJobStore{jobs: make(map[string]port.ImportJob)}
+}
+
+func (m *mockJobStore) SaveImportJob(_ context.Context, job *port.ImportJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = *job
+	return nil
+}
+
+func (m *mockJobStore) FindImportJob(_ context.Context, id string) (*port.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, domain.NewErrUserImportJobNotFound(id)
+	}
+	return &job, nil
+}
+
+func newImportUseCase(t *testing.T, repo *mockBulkRepository) *ImportUsersUseCase {
+	t.Helper()
+	log := logger.New(&logger.Config{
+		Level:  logger.LevelError,
+		Format: logger.FormatText,
+	})
+	hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
+	return NewImportUsersUseCase(repo, hasher, validation.New(log, nil), testPolicy, newMockJobStore())
+}
+
+const importCSV = `name,email,password,phone,role
+John Doe,john@example.com,Test@123!,+5511999999999,common
+Jane Doe,jane@example.com,Test@123!,+5511988888888,admin
+`
+
+func TestImportUsersUseCase_Execute(t *testing.T) {
+	t.Run("creates users from CSV", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(importCSV),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Total)
+		assert.Equal(t, 2, report.Created)
+		assert.Len(t, repo.created, 2)
+		assert.Equal(t, 2, report.Rows[0].Line)
+		assert.Equal(t, port.ImportRowCreated, report.Rows[0].Status)
+		assert.NotEmpty(t, report.Rows[0].UserID)
+	})
+
+	t.Run("creates users from JSONL", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		data := `{"name":"John Doe","email":"john@example.com","password":"Test@123!","phone":"+5511999999999","role":"common"}
+
+{"name":"Jane Doe","email":"jane@example.com","password":"Test@123!","phone":"+5511988888888","role":"common"}
+`
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatJSONL,
+			Data:   strings.NewReader(data),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 3, report.Rows[1].Line)
+	})
+
+	t.Run("reports invalid rows and in-file duplicates", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		data := importCSV +
+			"X,not-an-email,Test@123!,+5511977777777,common\n" +
+			"John Again,john@example.com,Test@123!,+5511966666666,common\n"
+
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(data),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 4, report.Total)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 1, report.Invalid)
+		assert.Equal(t, 1, report.Conflicts)
+		assert.Equal(t, port.ImportRowInvalid, report.Rows[2].Status)
+		assert.NotEmpty(t, report.Rows[2].Error)
+		assert.Equal(t, port.ImportRowConflict, report.Rows[3].Status)
+	})
+
+	t.Run("reports passwords that break the policy", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		data := importCSV + "Weak User,weak@example.com,weakpassword,+5511977777777,common\n"
+
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(data),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Invalid)
+		assert.Equal(t, port.ImportRowInvalid, report.Rows[2].Status)
+		assert.Contains(t, report.Rows[2].Error, "password must contain a digit")
+	})
+
+	t.Run("reports database conflicts", func(t *testing.T) {
+		repo := &mockBulkRepository{conflicts: map[string]error{
+			"jane@example.com": domain.NewErrUserEmailAlreadyExists(),
+		}}
+		uc := newImportUseCase(t, repo)
+
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(importCSV),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 1, report.Conflicts)
+		assert.Equal(t, port.ImportRowConflict, report.Rows[1].Status)
+		assert.Equal(t, domain.ErrUserEmailAlreadyExists.Error(), report.Rows[1].Error)
+	})
+
+	t.Run("dry run validates without creating", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			DryRun: true,
+			Data:   strings.NewReader(importCSV),
+		})
+
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 2, report.Valid)
+		assert.Zero(t, report.Created)
+		assert.Empty(t, repo.created)
+		assert.Len(t, repo.checked, 2)
+		assert.Empty(t, report.Rows[0].UserID)
+	})
+
+	t.Run("rejects unknown format", func(t *testing.T) {
+		uc := newImportUseCase(t, &mockBulkRepository{})
+
+		_, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: "xml",
+			Data:   strings.NewReader(""),
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserImportInvalidFormat))
+	})
+
+	t.Run("rejects CSV without required columns", func(t *testing.T) {
+		uc := newImportUseCase(t, &mockBulkRepository{})
+
+		_, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader("name,email\nJohn,john@example.com\n"),
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserImportMalformedFile))
+	})
+
+	t.Run("returns repository failures", func(t *testing.T) {
+		uc := newImportUseCase(t, &mockBulkRepository{err: domain.NewErrUserFailedCreateUser()})
+
+		_, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(importCSV),
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserFailedCreateUser))
+	})
+
+	t.Run("reports malformed CSV rows", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		data := importCSV + "Bad \"Quote,bad@example.com,Test@123!,+5511977777777,common\n"
+
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(data),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Total)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 1, report.Invalid)
+		assert.Equal(t, 4, report.Rows[2].Line)
+		assert.Equal(t, port.ImportRowInvalid, report.Rows[2].Status)
+	})
+
+	t.Run("rejects files over the row limit before writing", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		_, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(generateImportCSV(importMaxRows + 1)),
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserImportTooManyRows))
+		assert.Empty(t, repo.created)
+	})
+
+	t.Run("returns the partial report when a batch fails", func(t *testing.T) {
+		repo := &mockBulkRepository{err: domain.NewErrUserFailedCreateUser(), failAfter: 1}
+		uc := newImportUseCase(t, repo)
+
+		report, err := uc.Execute(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(generateImportCSV(importBatchSize + 10)),
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserFailedCreateUser))
+		require.NotNil(t, report)
+		assert.Equal(t, importBatchSize, report.Created)
+		assert.Equal(t, 10, report.Skipped)
+		assert.Equal(t, domain.ErrUserFailedCreateUser.Error(), report.Error)
+		assert.Equal(t, port.ImportRowCreated, report.Rows[0].Status)
+		assert.Equal(t, port.ImportRowSkipped, report.Rows[importBatchSize].Status)
+	})
+}
+
+func generateImportCSV(rows int) string {
+	var b strings.Builder
+	b.WriteString("name,email,password,phone,role\n")
+	for i := range rows {
+		fmt.Fprintf(&b, "User Name,user%d@example.com,Test@123!,+55119%08d,common\n", i, i)
+	}
+	return b.String()
+}
+
+func TestImportUsersUseCase_Start(t *testing.T) {
+	t.Run("runs the import in background", func(t *testing.T) {
+		repo := &mockBulkRepository{}
+		uc := newImportUseCase(t, repo)
+
+		job, err := uc.Start(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(importCSV),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, port.ImportJobPending, job.Status)
+
+		require.Eventually(t, func() bool {
+			current, err := uc.Job(context.Background(), job.ID)
+			return err == nil && current.Status == port.ImportJobCompleted
+		}, time.Second, 10*time.Millisecond)
+
+		current, err := uc.Job(context.Background(), job.ID)
+		require.NoError(t, err)
+		require.NotNil(t, current.Report)
+		assert.Equal(t, 2, current.Report.Created)
+		assert.NotNil(t, current.FinishedAt)
+	})
+
+	t.Run("fails the job when the import panics", func(t *testing.T) {
+		uc := newImportUseCase(t, &mockBulkRepository{panics: true})
+
+		job, err := uc.Start(context.Background(), &port.ImportUsersInput{
+			Format: port.ImportFormatCSV,
+			Data:   strings.NewReader(importCSV),
+		})
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			current, err := uc.Job(context.Background(), job.ID)
+			return err == nil && current.Status == port.ImportJobFailed
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("rejects unknown format before starting", func(t *testing.T) {
+		uc := newImportUseCase(t, &mockBulkRepository{})
+
+		_, err := uc.Start(context.Background(), &port.ImportUsersInput{
+			Format: "xml",
+			Data:   strings.NewReader(""),
+		})
+
+		assert.True(t, errors.Is(err, domain.ErrUserImportInvalidFormat))
+	})
+
+	t.Run("returns not found for unknown job", func(t *testing.T) {
+		uc := newImportUseCase(t, &mockBulkRepository{})
+
+		_, err := uc.Job(context.Background(), "missing")
+
+		assert.True(t, errors.Is(err, domain.ErrUserImportJobNotFound))
+	})
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+type PurgeDeletedUsersUseCase struct {
+	repo      port.PurgeUserRepositoryPort
+	retention time.Duration
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewPurgeDeletedUsersUseCase(repo port.PurgeUserRepositoryPort, retention time.Duration, batchSize int) *PurgeDeletedUsersUseCase {
+	return &PurgeDeletedUsersUseCase{
+		repo:      repo,
+		retention: retention,
+		batchSize: batchSize,
+		logger:    slog.Default(),
+	}
+}
+
+// SetLogger sets the logger used by Run
+func (uc *PurgeDeletedUsersUseCase) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		uc.logger = logger
+	}
+}
+
+// Execute deletes in batches so a large backlog does not hold one long transaction
+func (uc *PurgeDeletedUsersUseCase) Execute(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "user.PurgeDeletedUsers")
+	defer func() { tracing.End(span, err) }()
+
+	deletedBefore := time.Now().Add(-uc.retention)
+
+	var total int64
+	for {
+		purged, err := uc.repo.PurgeDeletedUsers(ctx, deletedBefore, uc.batchSize)
+		if err != nil {
+			return total, err
+		}
+
+		total += purged
+		if purged < int64(uc.batchSize) {
+			return total, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// Run executes the purge every interval while isLeader reports true, so a
+// single replica purges at a time. It returns when ctx is canceled.
+func (uc *PurgeDeletedUsersUseCase) Run(ctx context.Context, interval time.Duration, isLeader func() bool) {
+	runWhileLeader(ctx, interval, isLeader, func(ctx context.Context) {
+		purged, err := uc.Execute(ctx)
+		if err != nil {
+			uc.logger.ErrorContext(ctx, "Failed to purge deleted users", "purged", purged, "error", err.Error())
+			return
+		}
+
+		if purged > 0 {
+			uc.logger.InfoContext(ctx, "Purged deleted users", "purged", purged, "retention", uc.retention.String())
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+// ReencryptUsersUseCase completes key rotation: it rewrites users encrypted
+// with retired keys (or stored before encryption) using the active key
+type ReencryptUsersUseCase struct {
+	repo      port.ReencryptUserRepositoryPort
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewReencryptUsersUseCase(repo port.ReencryptUserRepositoryPort, batchSize int) *ReencryptUsersUseCase {
+	return &ReencryptUsersUseCase{
+		repo:      repo,
+		batchSize: batchSize,
+		logger:    slog.Default(),
+	}
+}
+
+// SetLogger sets the logger used by Run
+func (uc *ReencryptUsersUseCase) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		uc.logger = logger
+	}
+}
+
+// Execute re-encrypts in batches until no stale user is left. Conflicting
+// users are reported once and not selected again by later batches.
+func (uc *ReencryptUsersUseCase) Execute(ctx context.Context) (_ *port.ReencryptUsersResult, err error) {
+	ctx, span := tracing.Start(ctx, "user.ReencryptUsers")
+	defer func() { tracing.End(span, err) }()
+
+	total := &port.ReencryptUsersResult{}
+	for {
+		result, err := uc.repo.ReencryptUsers(ctx, uc.batchSize, total.Conflicts)
+		if err != nil {
+			return total, err
+		}
+
+		total.Rewritten += result.Rewritten
+		total.Conflicts = append(total.Conflicts, result.Conflicts...)
+		if result.Rewritten+int64(len(result.Conflicts)) < int64(uc.batchSize) {
+			return total, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// Run executes the re-encryption every interval on the leader replica.
+// It returns when ctx is canceled.
+func (uc *ReencryptUsersUseCase) Run(ctx context.Context, interval time.Duration, isLeader func() bool) {
+	runWhileLeader(ctx, interval, isLeader, uc.RunOnce)
+}
+
+// RunOnce executes the re-encryption and logs its outcome. It also runs at
+// startup, so users stored before encryption get their blind indexes
+// before requests are served.
+func (uc *ReencryptUsersUseCase) RunOnce(ctx context.Context) {
+	result, err := uc.Execute(ctx)
+
+	if len(result.Conflicts) > 0 {
+		ids := make([]string, len(result.Conflicts))
+		for i, id := range result.Conflicts {
+			ids[i] = id.String()
+		}
+		uc.logger.WarnContext(ctx, "Users not re-encrypted: email or phone already taken by another user",
+			"user_ids", ids,
+		)
+	}
+
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "Failed to re-encrypt users", "updated", result.Rewritten, "error", err.Error())
+		return
+	}
+
+	if result.Rewritten > 0 {
+		uc.logger.InfoContext(ctx, "Re-encrypted users", "updated", result.Rewritten)
+	}
+}
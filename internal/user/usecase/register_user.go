@@ -7,21 +7,49 @@ import (
 
 	"github.com/marcelofabianov/course/internal/user/domain"
 	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/password"
+	"github.com/marcelofabianov/course/pkg/tracing"
 )
 
 type RegisterUserUseCase struct {
 	repo   port.CreateUserRepositoryPort
 	hasher port.PasswordHasherPort
+	policy port.PasswordPolicyPort
 }
 
-func NewRegisterUserUseCase(repo port.CreateUserRepositoryPort, hasher port.PasswordHasherPort) *RegisterUserUseCase {
+func NewRegisterUserUseCase(
+	repo port.CreateUserRepositoryPort,
+	hasher port.PasswordHasherPort,
+	policy port.PasswordPolicyPort,
+) *RegisterUserUseCase {
 	return &RegisterUserUseCase{
 		repo:   repo,
 		hasher: hasher,
+		policy: policy,
 	}
 }
 
-func (uc *RegisterUserUseCase) Execute(ctx context.Context, input *port.RegisterUserInput) (*port.RegisterUserOutput, error) {
+// Execute registers a user who signs up on their own, so only the roles
+// users may choose for themselves are accepted
+func (uc *RegisterUserUseCase) Execute(ctx context.Context, input *port.RegisterUserInput) (_ *port.RegisterUserOutput, err error) {
+	ctx, span := tracing.Start(ctx, "user.RegisterUser")
+	defer func() { tracing.End(span, err) }()
+
+	role, err := domain.NewUserRole(input.Role)
+	if err != nil {
+		return nil, domain.NewErrUserInvalidRole(input.Role)
+	}
+	if !role.CanSelfAssign() {
+		return nil, domain.NewErrUserRoleNotAllowed(input.Role)
+	}
+
+	if err := uc.policy.Validate(input.Password, password.UserInfo{
+		Name:  input.Name,
+		Email: input.Email,
+	}); err != nil {
+		return nil, err
+	}
+
 	passwordHash, err := uc.hasher.Hash(input.Password)
 	if err != nil || passwordHash == "" {
 		return nil, domain.NewErrUserFailedHashPassword()
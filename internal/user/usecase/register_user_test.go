@@ -10,6 +10,7 @@ import (
 
 	"github.com/marcelofabianov/course/internal/user/domain"
 	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/password"
 )
 
 type mockRepository struct {
@@ -29,6 +30,8 @@ func (m *mockHasher) Hash(_ string) (string, error) {
 	return m.hash, m.err
 }
 
+var testPolicy = password.NewPolicy(password.DefaultPolicyConfig(), nil)
+
 func validInput() *port.RegisterUserInput {
 	return &port.RegisterUserInput{
 		Name:     "John Doe",
@@ -43,7 +46,7 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 	t.Run("creates user successfully", func(t *testing.T) {
 		repo := &mockRepository{}
 		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		output, err := uc.Execute(context.Background(), validInput())
 
@@ -64,7 +67,7 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 	t.Run("returns error when hash fails", func(t *testing.T) {
 		repo := &mockRepository{}
 		hasher := &mockHasher{err: errors.New("hash failed")}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		output, err := uc.Execute(context.Background(), validInput())
 
@@ -75,7 +78,7 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 	t.Run("returns error when domain validation fails", func(t *testing.T) {
 		repo := &mockRepository{}
 		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		input := validInput()
 		input.Email = "invalid-email"
@@ -89,7 +92,7 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 	t.Run("returns error when repository fails with duplicate email", func(t *testing.T) {
 		repo := &mockRepository{err: domain.NewErrUserEmailAlreadyExists()}
 		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		output, err := uc.Execute(context.Background(), validInput())
 
@@ -100,7 +103,7 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 	t.Run("returns error when domain rejects invalid name", func(t *testing.T) {
 		repo := &mockRepository{}
 		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		input := validInput()
 		input.Name = ""
@@ -114,7 +117,7 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 	t.Run("returns error when domain rejects invalid phone", func(t *testing.T) {
 		repo := &mockRepository{}
 		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		input := validInput()
 		input.Phone = "invalid"
@@ -128,7 +131,7 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 	t.Run("returns error when domain rejects invalid role", func(t *testing.T) {
 		repo := &mockRepository{}
 		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		input := validInput()
 		input.Role = "superadmin"
@@ -139,14 +142,41 @@ func TestRegisterUserUseCase_Execute(t *testing.T) {
 		assert.True(t, errors.Is(err, domain.ErrUserInvalidRole))
 	})
 
+	t.Run("rejects the admin role", func(t *testing.T) {
+		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
+		uc := NewRegisterUserUseCase(&mockRepository{}, hasher, testPolicy)
+
+		input := validInput()
+		input.Role = "admin"
+
+		output, err := uc.Execute(context.Background(), input)
+
+		assert.Nil(t, output)
+		assert.True(t, errors.Is(err, domain.ErrUserRoleNotAllowed))
+	})
+
 	t.Run("returns error when hasher returns empty hash", func(t *testing.T) {
 		repo := &mockRepository{}
 		hasher := &mockHasher{hash: ""}
-		uc := NewRegisterUserUseCase(repo, hasher)
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
 
 		output, err := uc.Execute(context.Background(), validInput())
 
 		assert.Nil(t, output)
 		assert.True(t, errors.Is(err, domain.ErrUserFailedHashPassword))
 	})
+
+	t.Run("returns error when password breaks the policy", func(t *testing.T) {
+		repo := &mockRepository{}
+		hasher := &mockHasher{hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5"}
+		uc := NewRegisterUserUseCase(repo, hasher, testPolicy)
+
+		input := validInput()
+		input.Password = "John@1234"
+
+		output, err := uc.Execute(context.Background(), input)
+
+		assert.Nil(t, output)
+		assert.True(t, errors.Is(err, password.ErrPolicyViolation))
+	})
 }
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"context"
+	"time"
+)
+
+// runWhileLeader calls fn every interval while isLeader reports true, so
+// maintenance jobs run on a single replica. It returns when ctx is canceled.
+func runWhileLeader(ctx context.Context, interval time.Duration, isLeader func() bool, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if isLeader() {
+			fn(ctx)
+		}
+	}
+}
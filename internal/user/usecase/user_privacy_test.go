@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marcelofabianov/wisp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/internal/user/domain"
+	"github.com/marcelofabianov/course/internal/user/port"
+	"github.com/marcelofabianov/course/pkg/tenant"
+)
+
+type mockUserStore struct {
+	user      *domain.User
+	findErr   error
+	updateErr error
+	updated   *domain.User
+}
+
+func (m *mockUserStore) FindUserByID(_ context.Context, id wisp.UUID) (*domain.User, error) {
+	if m.findErr != nil {
+		return nil, m.findErr
+	}
+	if m.user == nil || m.user.ID != id {
+		return nil, domain.NewErrUserNotFound(id.String())
+	}
+	return m.user, nil
+}
+
+func (m *mockUserStore) FindUserByEmail(_ context.Context, email wisp.Email) (*domain.User, error) {
+	if m.findErr != nil {
+		return nil, m.findErr
+	}
+	if m.user == nil || m.user.Email != email {
+		return nil, domain.NewErrUserNotFound("")
+	}
+	return m.user, nil
+}
+
+func (m *mockUserStore) UpdateUser(_ context.Context, user *domain.User) error {
+	m.updated = user
+	return m.updateErr
+}
+
+func newStoredUser(t *testing.T) *domain.User {
+	t.Helper()
+	hash, _ := wisp.NewNonEmptyString("$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5")
+	user, err := domain.NewUser(&domain.NewUserInput{
+		Name:  "John Doe",
+		Email: "john@example.com",
+		Phone: "+5511999999999",
+		Role:  "common",
+	}, hash, wisp.AuditUser("system"))
+	require.NoError(t, err)
+	return user
+}
+
+func TestExportUserUseCase_Execute(t *testing.T) {
+	t.Run("exports every stored field except the password hash", func(t *testing.T) {
+		user := newStoredUser(t)
+		uc := NewExportUserUseCase(&mockUserStore{user: user})
+		ctx := tenant.WithID(context.Background(), "6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a")
+
+		export, err := uc.Execute(ctx, user.ID.String())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, export.SchemaVersion)
+		assert.Equal(t, "6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a", export.TenantID)
+		assert.Equal(t, user.ID.String(), export.User.ID)
+		assert.Equal(t, "john@example.com", export.User.Email)
+		assert.Equal(t, "5511999999999", export.User.Phone)
+		assert.True(t, export.User.HasPassword)
+		assert.Nil(t, export.User.ErasedAt)
+		assert.Equal(t, user.Audit, export.User.Audit)
+	})
+
+	t.Run("exports erased users without personal data", func(t *testing.T) {
+		user := newStoredUser(t)
+		require.NoError(t, user.Erase(wisp.AuditUser("system")))
+		uc := NewExportUserUseCase(&mockUserStore{user: user})
+
+		export, err := uc.Execute(context.Background(), user.ID.String())
+
+		require.NoError(t, err)
+		assert.Empty(t, export.User.Email)
+		assert.Empty(t, export.User.Phone)
+		assert.False(t, export.User.HasPassword)
+		assert.NotNil(t, export.User.ErasedAt)
+	})
+
+	t.Run("returns not found for invalid id", func(t *testing.T) {
+		uc := NewExportUserUseCase(&mockUserStore{})
+
+		_, err := uc.Execute(context.Background(), "not-a-uuid")
+
+		assert.True(t, errors.Is(err, domain.ErrUserNotFound))
+	})
+}
+
+func TestEraseUserUseCase_Execute(t *testing.T) {
+	t.Run("anonymizes personal data and keeps the audit trail", func(t *testing.T) {
+		user := newStoredUser(t)
+		createdAt := user.Audit.CreatedAt
+		store := &mockUserStore{user: user}
+		uc := NewEraseUserUseCase(store)
+
+		err := uc.Execute(context.Background(), user.ID.String())
+
+		require.NoError(t, err)
+		require.NotNil(t, store.updated)
+		assert.Equal(t, user.ID, store.updated.ID)
+		assert.Equal(t, domain.ErasedUserName, store.updated.Name.String())
+		assert.True(t, store.updated.Email.IsEmpty())
+		assert.True(t, store.updated.Phone.IsZero())
+		assert.NotContains(t, store.updated.PasswordHash.String(), "argon2id")
+		assert.False(t, store.updated.IsActive)
+		assert.True(t, store.updated.IsErased())
+		assert.True(t, store.updated.Audit.DeletedAt.Valid)
+		assert.Equal(t, createdAt, store.updated.Audit.CreatedAt)
+		assert.Equal(t, 2, store.updated.Audit.Version.Int())
+	})
+
+	t.Run("returns conflict when already erased", func(t *testing.T) {
+		user := newStoredUser(t)
+		require.NoError(t, user.Erase(wisp.AuditUser("system")))
+		uc := NewEraseUserUseCase(&mockUserStore{user: user})
+
+		err := uc.Execute(context.Background(), user.ID.String())
+
+		assert.True(t, errors.Is(err, domain.ErrUserAlreadyErased))
+	})
+
+	t.Run("returns not found for unknown user", func(t *testing.T) {
+		uc := NewEraseUserUseCase(&mockUserStore{})
+
+		err := uc.Execute(context.Background(), wisp.MustNewUUID().String())
+
+		assert.True(t, errors.Is(err, domain.ErrUserNotFound))
+	})
+
+	t.Run("returns repository update error", func(t *testing.T) {
+		user := newStoredUser(t)
+		uc := NewEraseUserUseCase(&mockUserStore{user: user, updateErr: domain.NewErrUserFailedUpdateUser()})
+
+		err := uc.Execute(context.Background(), user.ID.String())
+
+		assert.True(t, errors.Is(err, domain.ErrUserFailedUpdateUser))
+	})
+}
+
+type mockPurgeRepository struct {
+	batches       []int64
+	err           error
+	calls         int
+	deletedBefore time.Time
+}
+
+func (m *mockPurgeRepository) PurgeDeletedUsers(_ context.Context, deletedBefore time.Time, _ int) (int64, error) {
+	m.deletedBefore = deletedBefore
+	if m.err != nil {
+		return 0, m.err
+	}
+	purged := m.batches[m.calls]
+	m.calls++
+	return purged, nil
+}
+
+func TestPurgeDeletedUsersUseCase_Execute(t *testing.T) {
+	t.Run("purges in batches until a short batch", func(t *testing.T) {
+		repo := &mockPurgeRepository{batches: []int64{2, 2, 1}}
+		uc := NewPurgeDeletedUsersUseCase(repo, 24*time.Hour, 2)
+
+		purged, err := uc.Execute(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), purged)
+		assert.Equal(t, 3, repo.calls)
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), repo.deletedBefore, time.Minute)
+	})
+
+	t.Run("returns repository errors", func(t *testing.T) {
+		uc := NewPurgeDeletedUsersUseCase(&mockPurgeRepository{err: domain.NewErrUserFailedPurgeUsers()}, time.Hour, 10)
+
+		_, err := uc.Execute(context.Background())
+
+		assert.True(t, errors.Is(err, domain.ErrUserFailedPurgeUsers))
+	})
+}
+
+func TestPurgeDeletedUsersUseCase_Run(t *testing.T) {
+	t.Run("only purges while leader", func(t *testing.T) {
+		repo := &mockPurgeRepository{batches: make([]int64, 1000)}
+		uc := NewPurgeDeletedUsersUseCase(repo, time.Hour, 10)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		uc.Run(ctx, 5*time.Millisecond, func() bool { return false })
+
+		assert.Zero(t, repo.calls)
+	})
+
+	t.Run("purges on each tick while leader", func(t *testing.T) {
+		repo := &mockPurgeRepository{batches: make([]int64, 1000)}
+		uc := NewPurgeDeletedUsersUseCase(repo, time.Hour, 10)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		uc.Run(ctx, 5*time.Millisecond, func() bool { return true })
+
+		assert.Positive(t, repo.calls)
+	})
+}
+
+type mockReencryptRepository struct {
+	batches []*port.ReencryptUsersResult
+	calls   int
+	skipped [][]wisp.UUID
+}
+
+func (m *mockReencryptRepository) ReencryptUsers(_ context.Context, _ int, skip []wisp.UUID) (*port.ReencryptUsersResult, error) {
+	result := m.batches[m.calls]
+	m.calls++
+	m.skipped = append(m.skipped, skip)
+	return result, nil
+}
+
+func TestReencryptUsersUseCase_Execute(t *testing.T) {
+	t.Run("re-encrypts until a batch is not full", func(t *testing.T) {
+		repo := &mockReencryptRepository{batches: []*port.ReencryptUsersResult{
+			{Rewritten: 3}, {Rewritten: 3}, {},
+		}}
+		uc := NewReencryptUsersUseCase(repo, 3)
+
+		result, err := uc.Execute(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), result.Rewritten)
+		assert.Equal(t, 3, repo.calls)
+	})
+
+	t.Run("skips conflicting users in later batches", func(t *testing.T) {
+		conflict := wisp.UUID(uuid.New())
+		repo := &mockReencryptRepository{batches: []*port.ReencryptUsersResult{
+			{Rewritten: 2, Conflicts: []wisp.UUID{conflict}}, {Rewritten: 1},
+		}}
+		uc := NewReencryptUsersUseCase(repo, 3)
+
+		result, err := uc.Execute(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), result.Rewritten)
+		assert.Equal(t, []wisp.UUID{conflict}, result.Conflicts)
+		assert.Equal(t, []wisp.UUID{conflict}, repo.skipped[1])
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/marcelofabianov/course/config"
 	"github.com/marcelofabianov/course/pkg/retry"
+	"github.com/marcelofabianov/course/pkg/tenant"
 	"github.com/marcelofabianov/fault"
 	"github.com/redis/go-redis/v9"
 )
@@ -141,6 +142,7 @@ func (c *Cache) connect(ctx context.Context) error {
 	}
 
 	client := redis.NewClient(opts)
+	client.AddHook(newTracingHook(opts.Addr))
 
 	pingCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.QueryTimeout)
 	defer cancel()
@@ -236,6 +238,15 @@ func (c *Cache) Client() *redis.Client {
 	return c.client
 }
 
+// tenantKeys scopes every key to the tenant in ctx, see tenant.Key
+func tenantKeys(ctx context.Context, keys []string) []string {
+	scoped := make([]string, len(keys))
+	for i, key := range keys {
+		scoped[i] = tenant.Key(ctx, key)
+	}
+	return scoped
+}
+
 func (c *Cache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	if c.client == nil {
 		return ErrNotConnected
@@ -244,7 +255,7 @@ func (c *Cache) Set(ctx context.Context, key string, value interface{}, expirati
 	execCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.ExecTimeout)
 	defer cancel()
 
-	if err := c.client.Set(execCtx, key, value, expiration).Err(); err != nil {
+	if err := c.client.Set(execCtx, tenant.Key(ctx, key), value, expiration).Err(); err != nil {
 		c.logger.ErrorContext(ctx, "Redis SET failed",
 			"key", key,
 			"expiration", expiration.String(),
@@ -260,6 +271,33 @@ func (c *Cache) Set(ctx context.Context, key string, value interface{}, expirati
 	return nil
 }
 
+// SetNX sets key only if it does not exist, reporting whether it was set.
+// It is the building block of short-lived locks.
+func (c *Cache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if c.client == nil {
+		return false, ErrNotConnected
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.ExecTimeout)
+	defer cancel()
+
+	ok, err := c.client.SetNX(execCtx, tenant.Key(ctx, key), value, expiration).Result()
+	if err != nil {
+		c.logger.ErrorContext(ctx, "Redis SETNX failed",
+			"key", key,
+			"expiration", expiration.String(),
+			"error", err.Error(),
+		)
+		return false, fault.Wrap(ErrOperationFailed, "setnx operation failed",
+			fault.WithWrappedErr(err),
+			fault.WithContext("key", key),
+			fault.WithContext("expiration", expiration.String()),
+		)
+	}
+
+	return ok, nil
+}
+
 func (c *Cache) Get(ctx context.Context, key string) (string, error) {
 	if c.client == nil {
 		return "", ErrNotConnected
@@ -268,7 +306,7 @@ func (c *Cache) Get(ctx context.Context, key string) (string, error) {
 	queryCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.QueryTimeout)
 	defer cancel()
 
-	val, err := c.client.Get(queryCtx, key).Result()
+	val, err := c.client.Get(queryCtx, tenant.Key(ctx, key)).Result()
 	if err == redis.Nil {
 		return "", fault.Wrap(ErrKeyNotFound, "key does not exist",
 			fault.WithContext("key", key),
@@ -296,7 +334,7 @@ func (c *Cache) Delete(ctx context.Context, keys ...string) error {
 	execCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.ExecTimeout)
 	defer cancel()
 
-	if err := c.client.Del(execCtx, keys...).Err(); err != nil {
+	if err := c.client.Del(execCtx, tenantKeys(ctx, keys)...).Err(); err != nil {
 		c.logger.ErrorContext(ctx, "Redis DEL failed",
 			"keys", keys,
 			"error", err.Error(),
@@ -318,7 +356,7 @@ func (c *Cache) Exists(ctx context.Context, keys ...string) (int64, error) {
 	queryCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.QueryTimeout)
 	defer cancel()
 
-	count, err := c.client.Exists(queryCtx, keys...).Result()
+	count, err := c.client.Exists(queryCtx, tenantKeys(ctx, keys)...).Result()
 	if err != nil {
 		c.logger.ErrorContext(ctx, "Redis EXISTS failed",
 			"keys", keys,
@@ -341,7 +379,7 @@ func (c *Cache) Expire(ctx context.Context, key string, expiration time.Duration
 	execCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.ExecTimeout)
 	defer cancel()
 
-	if err := c.client.Expire(execCtx, key, expiration).Err(); err != nil {
+	if err := c.client.Expire(execCtx, tenant.Key(ctx, key), expiration).Err(); err != nil {
 		c.logger.ErrorContext(ctx, "Redis EXPIRE failed",
 			"key", key,
 			"expiration", expiration.String(),
@@ -365,7 +403,7 @@ func (c *Cache) TTL(ctx context.Context, key string) (time.Duration, error) {
 	queryCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.QueryTimeout)
 	defer cancel()
 
-	ttl, err := c.client.TTL(queryCtx, key).Result()
+	ttl, err := c.client.TTL(queryCtx, tenant.Key(ctx, key)).Result()
 	if err != nil {
 		c.logger.ErrorContext(ctx, "Redis TTL failed",
 			"key", key,
@@ -388,7 +426,7 @@ func (c *Cache) Increment(ctx context.Context, key string) (int64, error) {
 	execCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.ExecTimeout)
 	defer cancel()
 
-	val, err := c.client.Incr(execCtx, key).Result()
+	val, err := c.client.Incr(execCtx, tenant.Key(ctx, key)).Result()
 	if err != nil {
 		c.logger.ErrorContext(ctx, "Redis INCR failed",
 			"key", key,
@@ -411,7 +449,7 @@ func (c *Cache) Decrement(ctx context.Context, key string) (int64, error) {
 	execCtx, cancel := context.WithTimeout(ctx, c.config.Redis.Connect.ExecTimeout)
 	defer cancel()
 
-	val, err := c.client.Decr(execCtx, key).Result()
+	val, err := c.client.Decr(execCtx, tenant.Key(ctx, key)).Result()
 	if err != nil {
 		c.logger.ErrorContext(ctx, "Redis DECR failed",
 			"key", key,
@@ -2,12 +2,21 @@ package cache_test
 
 import (
 	"context"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
 	"github.com/marcelofabianov/course/config"
 	"github.com/marcelofabianov/course/pkg/cache"
 	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/tenant"
 )
 
 func TestNew(t *testing.T) {
@@ -165,3 +174,153 @@ func TestCache_Client(t *testing.T) {
 		}
 	})
 }
+
+func TestCache_TenantKeys(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Skip("Config not available")
+	}
+
+	mr := miniredis.RunT(t)
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	cfg.Redis.Credentials.Host = host
+	cfg.Redis.Credentials.Port, _ = strconv.Atoi(port)
+
+	c, _ := cache.New(cfg)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer c.Close()
+
+	tenantA := tenant.WithID(context.Background(), "6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a")
+	tenantB := tenant.WithID(context.Background(), "0b8d1c4c-3b7a-4a2f-9d62-6f1c1d0e6a56")
+
+	t.Run("prefixes keys with the tenant", func(t *testing.T) {
+		if err := c.Set(tenantA, "profile", "a", time.Minute); err != nil {
+			t.Fatalf("set failed: %v", err)
+		}
+
+		if !mr.Exists("tenant:6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a:profile") {
+			t.Error("expected tenant scoped key")
+		}
+	})
+
+	t.Run("isolates tenants", func(t *testing.T) {
+		if _, err := c.Get(tenantB, "profile"); err == nil {
+			t.Error("expected tenant B not to see tenant A key")
+		}
+
+		val, err := c.Get(tenantA, "profile")
+		if err != nil || val != "a" {
+			t.Errorf("expected 'a', got %q (%v)", val, err)
+		}
+	})
+
+	t.Run("keeps keys without tenant unchanged", func(t *testing.T) {
+		if err := c.Set(context.Background(), "global", "g", time.Minute); err != nil {
+			t.Fatalf("set failed: %v", err)
+		}
+
+		if !mr.Exists("global") {
+			t.Error("expected unscoped key")
+		}
+	})
+
+	t.Run("deletes tenant scoped keys", func(t *testing.T) {
+		if err := c.Delete(tenantA, "profile"); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+
+		count, err := c.Exists(tenantA, "profile")
+		if err != nil || count != 0 {
+			t.Errorf("expected key removed, got %d (%v)", count, err)
+		}
+	})
+}
+
+func TestCache_SetNX(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Skip("Config not available")
+	}
+
+	mr := miniredis.RunT(t)
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	cfg.Redis.Credentials.Host = host
+	cfg.Redis.Credentials.Port, _ = strconv.Atoi(port)
+
+	c, _ := cache.New(cfg)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+
+	ok, err := c.SetNX(ctx, "lock", "1", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected the first SetNX to set the key, got %v (%v)", ok, err)
+	}
+
+	ok, err = c.SetNX(ctx, "lock", "2", time.Minute)
+	if err != nil || ok {
+		t.Errorf("expected SetNX to keep an existing key, got %v (%v)", ok, err)
+	}
+
+	if val, _ := c.Get(ctx, "lock"); val != "1" {
+		t.Errorf("expected '1', got %q", val)
+	}
+
+	mr.FastForward(time.Minute)
+	if ok, _ := c.SetNX(ctx, "lock", "3", time.Minute); !ok {
+		t.Error("expected SetNX to set an expired key")
+	}
+}
+
+func TestCache_Tracing(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Skip("Config not available")
+	}
+
+	previous := otel.GetTracerProvider()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mr := miniredis.RunT(t)
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	cfg.Redis.Credentials.Host = host
+	cfg.Redis.Credentials.Port, _ = strconv.Atoi(port)
+
+	c, _ := cache.New(cfg)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer c.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, _ = c.Get(ctx, "missing")
+	parent.End()
+
+	var get sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "redis get" {
+			get = span
+		}
+	}
+	if get == nil {
+		t.Fatal("expected a span for the GET command")
+	}
+	if get.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the command span to be parented to the span in ctx")
+	}
+	if get.Status().Code == codes.Error {
+		t.Error("expected a missing key not to be recorded as an error")
+	}
+	for _, attr := range get.Attributes() {
+		if attr.Value.Emit() == "missing" {
+			t.Error("expected keys not to be recorded")
+		}
+	}
+}
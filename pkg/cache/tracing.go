@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+// tracingHook starts a client span per Redis command or pipeline. Keys and
+// values are left out, as they may carry tenant IDs or personal data.
+type tracingHook struct {
+	attrs []attribute.KeyValue
+}
+
+func newTracingHook(addr string) tracingHook {
+	host, portText, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portText)
+
+	return tracingHook{attrs: []attribute.KeyValue{
+		semconv.DBSystemNameRedis,
+		semconv.ServerAddress(host),
+		semconv.ServerPort(port),
+	}}
+}
+
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := tracing.Start(ctx, "redis "+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(h.attrs...),
+			trace.WithAttributes(semconv.DBOperationName(cmd.Name())),
+		)
+
+		err := next(ctx, cmd)
+		tracing.End(span, spanError(err))
+		return err
+	}
+}
+
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := tracing.Start(ctx, "redis pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(h.attrs...),
+			trace.WithAttributes(semconv.DBOperationBatchSize(len(cmds))),
+		)
+
+		err := next(ctx, cmds)
+		tracing.End(span, spanError(err))
+		return err
+	}
+}
+
+// spanError ignores redis.Nil, which only means a key does not exist
+func spanError(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
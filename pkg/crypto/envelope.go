@@ -0,0 +1,272 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/marcelofabianov/fault"
+)
+
+const (
+	// keySize is the AES-256 key length for master and data keys
+	keySize = 32
+
+	// wrappedKeySize is a data key sealed by a master key: nonce + key + tag
+	wrappedKeySize = 12 + keySize + 16
+)
+
+var (
+	ErrInvalidKey        = fault.New("invalid encryption key", fault.WithCode(fault.Invalid))
+	ErrUnknownKeyVersion = fault.New("unknown encryption key version", fault.WithCode(fault.Internal))
+	ErrEncryptFailed     = fault.New("failed to encrypt value", fault.WithCode(fault.Internal))
+	ErrDecryptFailed     = fault.New("failed to decrypt value", fault.WithCode(fault.Internal))
+)
+
+type EnvelopeConfig struct {
+	// Keys maps a key version to a base64 encoded 32-byte master key.
+	// Retired versions must stay configured until every value is re-encrypted.
+	Keys map[int]string
+
+	// ActiveVersion is the key version used for new values
+	ActiveVersion int
+
+	// BlindIndexKey is a base64 encoded key for blind indexes. It is not
+	// versioned: changing it invalidates every stored index.
+	BlindIndexKey string
+}
+
+// Envelope encrypts values with AES-256-GCM using envelope encryption: each
+// value is sealed with a fresh data key, and the data key is sealed with the
+// active master key. Ciphertexts are strings in the form "v<version>:<base64>"
+// so the key version of a stored value is visible without decrypting it.
+type Envelope struct {
+	keys          map[int]cipher.AEAD
+	active        int
+	blindIndexKey []byte
+}
+
+func NewEnvelope(cfg EnvelopeConfig) (*Envelope, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, fault.Wrap(ErrInvalidKey, "at least one key is required")
+	}
+
+	keys := make(map[int]cipher.AEAD, len(cfg.Keys))
+	for version, encoded := range cfg.Keys {
+		if version <= 0 {
+			return nil, fault.Wrap(ErrInvalidKey, "key version must be positive",
+				fault.WithContext("version", version),
+			)
+		}
+
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fault.Wrap(ErrInvalidKey, err.Error(),
+				fault.WithContext("version", version),
+			)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fault.Wrap(ErrInvalidKey, "failed to create cipher",
+				fault.WithContext("version", version),
+			)
+		}
+		keys[version] = aead
+	}
+
+	if _, ok := keys[cfg.ActiveVersion]; !ok {
+		return nil, fault.Wrap(ErrInvalidKey, "active key version is not configured",
+			fault.WithContext("version", cfg.ActiveVersion),
+		)
+	}
+
+	blindIndexKey, err := decodeKey(cfg.BlindIndexKey)
+	if err != nil {
+		return nil, fault.Wrap(ErrInvalidKey, "blind index key: "+err.Error())
+	}
+
+	return &Envelope{
+		keys:          keys,
+		active:        cfg.ActiveVersion,
+		blindIndexKey: blindIndexKey,
+	}, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("key must be base64 encoded")
+	}
+	if len(key) != keySize {
+		return nil, errors.New("key must be 32 bytes")
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ActiveVersion returns the key version used for new values
+func (e *Envelope) ActiveVersion() int {
+	return e.active
+}
+
+// ActivePrefix returns the prefix of values encrypted with the active key
+func (e *Envelope) ActivePrefix() string {
+	return versionPrefix(e.active)
+}
+
+func versionPrefix(version int) string {
+	return "v" + strconv.Itoa(version) + ":"
+}
+
+func (e *Envelope) Encrypt(plaintext []byte) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fault.Wrap(ErrEncryptFailed, "failed to generate data key")
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", fault.Wrap(ErrEncryptFailed, "failed to create data cipher")
+	}
+
+	prefix := e.ActivePrefix()
+	// The prefix is authenticated so the key version cannot be swapped
+	aad := []byte(prefix)
+
+	wrapped, err := seal(e.keys[e.active], dataKey, aad)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(dataAEAD, plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + base64.RawStdEncoding.EncodeToString(append(wrapped, sealed...)), nil
+}
+
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fault.Wrap(ErrEncryptFailed, "failed to generate nonce")
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func (e *Envelope) Decrypt(ciphertext string) ([]byte, error) {
+	version, payload, ok := parseCiphertext(ciphertext)
+	if !ok {
+		return nil, fault.Wrap(ErrDecryptFailed, "malformed ciphertext")
+	}
+
+	masterAEAD, ok := e.keys[version]
+	if !ok {
+		return nil, fault.Wrap(ErrUnknownKeyVersion, ErrUnknownKeyVersion.Error(),
+			fault.WithCode(fault.Internal),
+			fault.WithContext("version", version),
+		)
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(data) < wrappedKeySize {
+		return nil, fault.Wrap(ErrDecryptFailed, "malformed ciphertext")
+	}
+
+	aad := []byte(versionPrefix(version))
+
+	dataKey, err := open(masterAEAD, data[:wrappedKeySize], aad)
+	if err != nil {
+		return nil, err
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, fault.Wrap(ErrDecryptFailed, "failed to create data cipher")
+	}
+
+	return open(dataAEAD, data[wrappedKeySize:], aad)
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fault.Wrap(ErrDecryptFailed, "malformed ciphertext")
+	}
+
+	nonce, body := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, body, aad)
+	if err != nil {
+		return nil, fault.Wrap(ErrDecryptFailed, "authentication failed")
+	}
+	return plaintext, nil
+}
+
+func (e *Envelope) EncryptString(plaintext string) (string, error) {
+	return e.Encrypt([]byte(plaintext))
+}
+
+func (e *Envelope) DecryptString(ciphertext string) (string, error) {
+	plaintext, err := e.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencryption reports whether value is plaintext or was encrypted
+// with a key other than the active one
+func (e *Envelope) NeedsReencryption(value string) bool {
+	version, _, ok := parseCiphertext(value)
+	return !ok || version != e.active
+}
+
+// BlindIndex returns a deterministic HMAC-SHA256 of value, hex encoded, for
+// equality lookups and unique constraints on encrypted columns. purpose
+// separates indexes of different fields so equal values do not match
+// across columns. Callers must normalize value first.
+func (e *Envelope) BlindIndex(purpose, value string) string {
+	mac := hmac.New(sha256.New, e.blindIndexKey)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsCiphertext reports whether value has the envelope format
+func IsCiphertext(value string) bool {
+	_, _, ok := parseCiphertext(value)
+	return ok
+}
+
+func parseCiphertext(value string) (int, string, bool) {
+	if !strings.HasPrefix(value, "v") {
+		return 0, "", false
+	}
+
+	versionText, payload, found := strings.Cut(value[1:], ":")
+	if !found || payload == "" {
+		return 0, "", false
+	}
+
+	version, err := strconv.Atoi(versionText)
+	if err != nil || version <= 0 {
+		return 0, "", false
+	}
+
+	return version, payload, true
+}
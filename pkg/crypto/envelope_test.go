@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKeyV1      = "OR5eiHFkdjWXLue8DCZ9HMBqYKC9OYj29p2H+Buf2H8="
+	testKeyV2      = "HZReP7yAgQV8KC9yc82I0EmWOINZhVZI8fJJ/O/hSpw="
+	testBlindIndex = "JyazSXxTKPxrF3vUxk8tuqJHiXVzOwTZyPIoYOIFzuk="
+)
+
+func newTestEnvelope(t *testing.T, active int, keys map[int]string) *Envelope {
+	t.Helper()
+	envelope, err := NewEnvelope(EnvelopeConfig{Keys: keys, ActiveVersion: active, BlindIndexKey: testBlindIndex})
+	require.NoError(t, err)
+	return envelope
+}
+
+func TestNewEnvelope(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  EnvelopeConfig
+	}{
+		{"no keys", EnvelopeConfig{ActiveVersion: 1, BlindIndexKey: testBlindIndex}},
+		{"active version missing", EnvelopeConfig{Keys: map[int]string{1: testKeyV1}, ActiveVersion: 2, BlindIndexKey: testBlindIndex}},
+		{"key not base64", EnvelopeConfig{Keys: map[int]string{1: "not base64!"}, ActiveVersion: 1, BlindIndexKey: testBlindIndex}},
+		{"key too short", EnvelopeConfig{Keys: map[int]string{1: "c2hvcnQ="}, ActiveVersion: 1, BlindIndexKey: testBlindIndex}},
+		{"non positive version", EnvelopeConfig{Keys: map[int]string{0: testKeyV1}, ActiveVersion: 0, BlindIndexKey: testBlindIndex}},
+		{"missing blind index key", EnvelopeConfig{Keys: map[int]string{1: testKeyV1}, ActiveVersion: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEnvelope(tt.cfg)
+			assert.True(t, errors.Is(err, ErrInvalidKey))
+		})
+	}
+}
+
+func TestEnvelope_EncryptDecrypt(t *testing.T) {
+	envelope := newTestEnvelope(t, 1, map[int]string{1: testKeyV1})
+
+	t.Run("round trips values", func(t *testing.T) {
+		ciphertext, err := envelope.EncryptString("john@example.com")
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(ciphertext, "v1:"))
+		assert.NotContains(t, ciphertext, "john")
+
+		plaintext, err := envelope.DecryptString(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "john@example.com", plaintext)
+	})
+
+	t.Run("produces different ciphertexts for the same value", func(t *testing.T) {
+		first, err := envelope.EncryptString("john@example.com")
+		require.NoError(t, err)
+		second, err := envelope.EncryptString("john@example.com")
+		require.NoError(t, err)
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("rejects tampered ciphertexts", func(t *testing.T) {
+		ciphertext, err := envelope.EncryptString("john@example.com")
+		require.NoError(t, err)
+
+		tampered := []byte(ciphertext)
+		last := len(tampered) - 2
+		if tampered[last] == 'A' {
+			tampered[last] = 'B'
+		} else {
+			tampered[last] = 'A'
+		}
+
+		_, err = envelope.DecryptString(string(tampered))
+		assert.True(t, errors.Is(err, ErrDecryptFailed))
+	})
+
+	t.Run("rejects swapped key versions", func(t *testing.T) {
+		rotated := newTestEnvelope(t, 2, map[int]string{1: testKeyV1, 2: testKeyV1})
+		ciphertext, err := rotated.EncryptString("john@example.com")
+		require.NoError(t, err)
+
+		_, err = rotated.DecryptString("v1:" + strings.TrimPrefix(ciphertext, "v2:"))
+		assert.True(t, errors.Is(err, ErrDecryptFailed))
+	})
+
+	t.Run("rejects malformed ciphertexts", func(t *testing.T) {
+		_, err := envelope.DecryptString("john@example.com")
+		assert.True(t, errors.Is(err, ErrDecryptFailed))
+
+		_, err = envelope.DecryptString("v1:c2hvcnQ")
+		assert.True(t, errors.Is(err, ErrDecryptFailed))
+	})
+}
+
+func TestEnvelope_Rotation(t *testing.T) {
+	old := newTestEnvelope(t, 1, map[int]string{1: testKeyV1})
+	rotated := newTestEnvelope(t, 2, map[int]string{1: testKeyV1, 2: testKeyV2})
+
+	ciphertext, err := old.EncryptString("5511999999999")
+	require.NoError(t, err)
+
+	t.Run("decrypts values of retired keys", func(t *testing.T) {
+		plaintext, err := rotated.DecryptString(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "5511999999999", plaintext)
+	})
+
+	t.Run("reports values that need re-encryption", func(t *testing.T) {
+		current, err := rotated.EncryptString("5511999999999")
+		require.NoError(t, err)
+
+		assert.True(t, rotated.NeedsReencryption(ciphertext))
+		assert.True(t, rotated.NeedsReencryption("5511999999999"))
+		assert.False(t, rotated.NeedsReencryption(current))
+		assert.Equal(t, "v2:", rotated.ActivePrefix())
+	})
+
+	t.Run("fails for unknown key versions", func(t *testing.T) {
+		current, err := rotated.EncryptString("5511999999999")
+		require.NoError(t, err)
+
+		_, err = old.DecryptString(current)
+		assert.True(t, errors.Is(err, ErrUnknownKeyVersion))
+	})
+}
+
+func TestEnvelope_BlindIndex(t *testing.T) {
+	envelope := newTestEnvelope(t, 1, map[int]string{1: testKeyV1})
+	rotated := newTestEnvelope(t, 2, map[int]string{1: testKeyV1, 2: testKeyV2})
+
+	index := envelope.BlindIndex("users.email", "john@example.com")
+
+	assert.Len(t, index, 64)
+	assert.Equal(t, index, envelope.BlindIndex("users.email", "john@example.com"))
+	assert.Equal(t, index, rotated.BlindIndex("users.email", "john@example.com"), "key rotation keeps indexes")
+	assert.NotEqual(t, index, envelope.BlindIndex("users.phone", "john@example.com"))
+	assert.NotEqual(t, index, envelope.BlindIndex("users.email", "jane@example.com"))
+}
+
+func TestIsCiphertext(t *testing.T) {
+	assert.True(t, IsCiphertext("v1:abc"))
+	assert.True(t, IsCiphertext("v12:abc"))
+	assert.False(t, IsCiphertext("john@example.com"))
+	assert.False(t, IsCiphertext("v:abc"))
+	assert.False(t, IsCiphertext("v1:"))
+	assert.False(t, IsCiphertext("vx:abc"))
+}
@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/marcelofabianov/fault"
+	"github.com/marcelofabianov/wisp"
+)
+
+var ErrFieldEnvelopeNotSet = fault.New("field encryption is not configured", fault.WithCode(fault.Internal))
+
+// fieldEnvelope is used by Encrypted, since driver.Valuer and sql.Scanner
+// give no way to pass dependencies
+var fieldEnvelope atomic.Pointer[Envelope]
+
+// SetFieldEnvelope sets the envelope used by every Encrypted column
+func SetFieldEnvelope(e *Envelope) {
+	fieldEnvelope.Store(e)
+}
+
+func loadFieldEnvelope() (*Envelope, error) {
+	e := fieldEnvelope.Load()
+	if e == nil {
+		return nil, ErrFieldEnvelopeNotSet
+	}
+	return e, nil
+}
+
+type scanner[V any] interface {
+	*V
+	sql.Scanner
+}
+
+// Encrypted stores a value type, such as wisp.Email, encrypted in its
+// column. V must be stored as a string (or NULL) and *V must scan from one.
+// Plaintext values written before encryption are read as is, so rows can be
+// migrated gradually by re-encryption.
+type Encrypted[V driver.Valuer, PV scanner[V]] struct {
+	Val V
+}
+
+type (
+	EncryptedEmail = Encrypted[wisp.Email, *wisp.Email]
+	EncryptedPhone = Encrypted[wisp.Phone, *wisp.Phone]
+)
+
+// Value implements the driver.Valuer interface.
+func (e Encrypted[V, PV]) Value() (driver.Value, error) {
+	plain, err := e.Val.Value()
+	if err != nil || plain == nil {
+		return nil, err
+	}
+
+	text, ok := plain.(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot encrypt %T value", plain)
+	}
+
+	envelope, err := loadFieldEnvelope()
+	if err != nil {
+		return nil, err
+	}
+
+	return envelope.EncryptString(text)
+}
+
+// Scan implements the sql.Scanner interface.
+func (e *Encrypted[V, PV]) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return PV(&e.Val).Scan(nil)
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Encrypted", value)
+	}
+
+	if IsCiphertext(text) {
+		envelope, err := loadFieldEnvelope()
+		if err != nil {
+			return err
+		}
+
+		text, err = envelope.DecryptString(text)
+		if err != nil {
+			return err
+		}
+	}
+
+	return PV(&e.Val).Scan(text)
+}
@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marcelofabianov/wisp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypted(t *testing.T) {
+	envelope := newTestEnvelope(t, 1, map[int]string{1: testKeyV1})
+	SetFieldEnvelope(envelope)
+	t.Cleanup(func() { SetFieldEnvelope(nil) })
+
+	t.Run("stores the value encrypted", func(t *testing.T) {
+		field := EncryptedEmail{Val: wisp.MustNewEmail("john@example.com")}
+
+		value, err := field.Value()
+		require.NoError(t, err)
+
+		ciphertext, ok := value.(string)
+		require.True(t, ok)
+		assert.True(t, IsCiphertext(ciphertext))
+
+		var scanned EncryptedEmail
+		require.NoError(t, scanned.Scan(ciphertext))
+		assert.Equal(t, field.Val, scanned.Val)
+	})
+
+	t.Run("stores empty values as NULL", func(t *testing.T) {
+		value, err := EncryptedPhone{}.Value()
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		var scanned EncryptedPhone
+		require.NoError(t, scanned.Scan(nil))
+		assert.True(t, scanned.Val.IsZero())
+	})
+
+	t.Run("reads plaintext written before encryption", func(t *testing.T) {
+		var scanned EncryptedPhone
+		require.NoError(t, scanned.Scan([]byte("5511999999999")))
+		assert.Equal(t, "5511999999999", scanned.Val.String())
+	})
+
+	t.Run("validates the decrypted value", func(t *testing.T) {
+		ciphertext, err := envelope.EncryptString("not an email")
+		require.NoError(t, err)
+
+		var scanned EncryptedEmail
+		assert.Error(t, scanned.Scan(ciphertext))
+	})
+
+	t.Run("requires a configured envelope", func(t *testing.T) {
+		SetFieldEnvelope(nil)
+		defer SetFieldEnvelope(envelope)
+
+		_, err := EncryptedEmail{Val: wisp.MustNewEmail("john@example.com")}.Value()
+		assert.True(t, errors.Is(err, ErrFieldEnvelopeNotSet))
+	})
+}
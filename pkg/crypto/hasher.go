@@ -4,11 +4,13 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/marcelofabianov/fault"
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -20,6 +22,10 @@ var (
 type PasswordHasher interface {
 	Hash(password string) (string, error)
 	Verify(password, hash string) (bool, error)
+	// NeedsRehash reports whether hash was not produced with the current
+	// algorithm and parameters, so it should be replaced after a successful
+	// Verify
+	NeedsRehash(hash string) bool
 }
 
 type Argon2Params struct {
@@ -41,7 +47,8 @@ func DefaultArgon2Params() *Argon2Params {
 }
 
 type Argon2Hasher struct {
-	params *Argon2Params
+	params       *Argon2Params
+	legacyBcrypt bool
 }
 
 func NewArgon2Hasher() *Argon2Hasher {
@@ -52,6 +59,13 @@ func NewArgon2HasherWithParams(params *Argon2Params) *Argon2Hasher {
 	return &Argon2Hasher{params: params}
 }
 
+// SetLegacyBcrypt enables verifying bcrypt hashes, for users imported from
+// systems that used bcrypt. Such hashes always need rehash, so they are
+// replaced by Argon2 hashes as users log in; disable it once migrated.
+func (h *Argon2Hasher) SetLegacyBcrypt(enabled bool) {
+	h.legacyBcrypt = enabled
+}
+
 func (h *Argon2Hasher) Hash(password string) (string, error) {
 	if password == "" {
 		return "", ErrEmptyPassword
@@ -89,6 +103,10 @@ func (h *Argon2Hasher) Hash(password string) (string, error) {
 }
 
 func (h *Argon2Hasher) Verify(password, hash string) (bool, error) {
+	if h.legacyBcrypt && isBcryptHash(hash) {
+		return verifyBcrypt(password, hash)
+	}
+
 	params, salt, key, err := decodeHash(hash)
 	if err != nil {
 		return false, err
@@ -106,6 +124,37 @@ func (h *Argon2Hasher) Verify(password, hash string) (bool, error) {
 	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
 }
 
+func (h *Argon2Hasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		params.SaltLength != h.params.SaltLength ||
+		params.KeyLength != h.params.KeyLength
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+func verifyBcrypt(password, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, fault.Wrap(ErrInvalidHash, "invalid bcrypt hash")
+	}
+}
+
 func decodeHash(hash string) (*Argon2Params, []byte, []byte, error) {
 	parts := strings.Split(hash, "$")
 	if len(parts) != 6 {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestArgon2Hasher_Hash(t *testing.T) {
@@ -129,3 +130,67 @@ func TestArgon2Hasher_Verify(t *testing.T) {
 		assert.False(t, match)
 	})
 }
+
+func TestArgon2Hasher_LegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Test@123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	t.Run("verifies bcrypt hashes when enabled", func(t *testing.T) {
+		hasher := NewArgon2Hasher()
+		hasher.SetLegacyBcrypt(true)
+
+		match, err := hasher.Verify("Test@123!", string(legacy))
+		require.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = hasher.Verify("WrongPassword", string(legacy))
+		require.NoError(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("rejects bcrypt hashes when disabled", func(t *testing.T) {
+		hasher := NewArgon2Hasher()
+
+		match, err := hasher.Verify("Test@123!", string(legacy))
+
+		assert.True(t, errors.Is(err, ErrInvalidHash))
+		assert.False(t, match)
+	})
+}
+
+func TestArgon2Hasher_NeedsRehash(t *testing.T) {
+	params := &Argon2Params{
+		Memory:      32 * 1024,
+		Iterations:  1,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+
+	t.Run("returns false for current params", func(t *testing.T) {
+		hasher := NewArgon2HasherWithParams(params)
+
+		hash, err := hasher.Hash("Test@123!")
+		require.NoError(t, err)
+
+		assert.False(t, hasher.NeedsRehash(hash))
+	})
+
+	t.Run("returns true when params were raised", func(t *testing.T) {
+		hash, err := NewArgon2HasherWithParams(params).Hash("Test@123!")
+		require.NoError(t, err)
+
+		stronger := *params
+		stronger.Iterations = 2
+		hasher := NewArgon2HasherWithParams(&stronger)
+
+		assert.True(t, hasher.NeedsRehash(hash))
+	})
+
+	t.Run("returns true for bcrypt and malformed hashes", func(t *testing.T) {
+		hasher := NewArgon2Hasher()
+
+		assert.True(t, hasher.NeedsRehash("$2a$10$abcdefghijklmnopqrstuu5Vw5bQ0a1o1ZKz2sWqY3kQXG5p7.8yS"))
+		assert.True(t, hasher.NeedsRehash("not-a-valid-hash"))
+	})
+}
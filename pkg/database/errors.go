@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/marcelofabianov/fault"
+)
+
+// PostgreSQL SQLSTATE codes handled by the error translator.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	SQLStateNotNullViolation     = "23502"
+	SQLStateForeignKeyViolation  = "23503"
+	SQLStateUniqueViolation      = "23505"
+	SQLStateCheckViolation       = "23514"
+	SQLStateExclusionViolation   = "23P01"
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+	SQLStateQueryCanceled        = "57014"
+	SQLStateLockNotAvailable     = "55P03"
+
+	sqlStateClassIntegrity  = "23"
+	sqlStateClassConnection = "08"
+)
+
+var (
+	// ErrUniqueViolation is returned when a unique constraint is violated
+	ErrUniqueViolation = fault.New(
+		"unique constraint violation",
+		fault.WithCode(fault.Conflict),
+	)
+
+	// ErrForeignKeyViolation is returned when a foreign key constraint is violated
+	ErrForeignKeyViolation = fault.New(
+		"foreign key constraint violation",
+		fault.WithCode(fault.Invalid),
+	)
+
+	// ErrNotNullViolation is returned when a NOT NULL constraint is violated
+	ErrNotNullViolation = fault.New(
+		"not null constraint violation",
+		fault.WithCode(fault.Invalid),
+	)
+
+	// ErrCheckViolation is returned when a CHECK or exclusion constraint is violated
+	ErrCheckViolation = fault.New(
+		"check constraint violation",
+		fault.WithCode(fault.DomainViolation),
+	)
+
+	// ErrIntegrityViolation is returned for any other integrity constraint violation (class 23)
+	ErrIntegrityViolation = fault.New(
+		"integrity constraint violation",
+		fault.WithCode(fault.Invalid),
+	)
+
+	// ErrSerializationFailure is returned when a transaction could not be serialized.
+	// The operation is safe to retry.
+	ErrSerializationFailure = fault.New(
+		"transaction serialization failure",
+		fault.WithCode(fault.Conflict),
+	)
+
+	// ErrDeadlockDetected is returned when the transaction was aborted to break a deadlock.
+	// The operation is safe to retry.
+	ErrDeadlockDetected = fault.New(
+		"deadlock detected",
+		fault.WithCode(fault.Conflict),
+	)
+
+	// ErrTimeout is returned when a statement is canceled by timeout or lock timeout
+	ErrTimeout = fault.New(
+		"database operation timed out",
+		fault.WithCode(fault.InfraError),
+	)
+
+	// ErrConnectionLost is returned for connection exceptions (class 08)
+	ErrConnectionLost = fault.New(
+		"database connection exception",
+		fault.WithCode(fault.InfraError),
+	)
+)
+
+// ConstraintErrors maps a constraint name to a factory of the domain error
+// that should be returned when that constraint is violated.
+type ConstraintErrors map[string]func() error
+
+// ErrorTranslator converts driver errors into fault errors.
+// Constraint names registered by repositories take precedence over the
+// generic SQLSTATE mapping. It is safe for concurrent use.
+type ErrorTranslator struct {
+	mu          sync.RWMutex
+	constraints ConstraintErrors
+	logger      *slog.Logger
+}
+
+// NewErrorTranslator creates a translator with the given constraint table
+func NewErrorTranslator(constraints ConstraintErrors) *ErrorTranslator {
+	t := &ErrorTranslator{constraints: make(ConstraintErrors, len(constraints))}
+	for name, fn := range constraints {
+		t.Register(name, fn)
+	}
+	return t
+}
+
+// SetLogger sets the logger driver details are reported to; by default
+// they go to slog.Default()
+func (t *ErrorTranslator) SetLogger(logger *slog.Logger) {
+	t.logger = logger
+}
+
+// Register maps a constraint name to a domain error factory
+func (t *ErrorTranslator) Register(constraint string, fn func() error) {
+	if constraint == "" || fn == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.constraints[strings.ToLower(constraint)] = fn
+}
+
+// Translate converts err into a fault error.
+//
+// Violations of registered constraints return the registered domain error.
+// Other PostgreSQL errors are mapped by SQLSTATE. Errors that are not
+// recognized are returned unchanged, so callers can decide on a fallback.
+//
+// The fault context is serialised to clients, so driver details (SQLSTATE,
+// message, table, constraint, column) never go there: they are logged, and
+// the driver error is kept as the cause for errors.As.
+func (t *ErrorTranslator) Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fault.Wrap(fmt.Errorf("%w: %w", ErrTimeout, err), "deadline exceeded",
+				fault.WithCode(ErrTimeout.Code),
+			)
+		}
+		return err
+	}
+
+	if fn := t.lookup(pgErr.ConstraintName); fn != nil {
+		return fn()
+	}
+
+	sentinel := sentinelFor(pgErr.Code)
+	if sentinel == nil {
+		return err
+	}
+
+	t.log().Warn("Database error translated",
+		"sqlstate", pgErr.Code,
+		"detail", pgErr.Message,
+		"table", pgErr.TableName,
+		"constraint", pgErr.ConstraintName,
+		"column", pgErr.ColumnName,
+	)
+
+	return fault.Wrap(fmt.Errorf("%w: %w", sentinel, pgErr), sentinel.Message,
+		fault.WithCode(sentinel.Code),
+	)
+}
+
+func (t *ErrorTranslator) lookup(constraint string) func() error {
+	if constraint == "" {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.constraints[strings.ToLower(constraint)]
+}
+
+func (t *ErrorTranslator) log() *slog.Logger {
+	if t.logger != nil {
+		return t.logger
+	}
+	return slog.Default()
+}
+
+func sentinelFor(code string) *fault.Error {
+	switch code {
+	case SQLStateUniqueViolation:
+		return ErrUniqueViolation
+	case SQLStateForeignKeyViolation:
+		return ErrForeignKeyViolation
+	case SQLStateNotNullViolation:
+		return ErrNotNullViolation
+	case SQLStateCheckViolation, SQLStateExclusionViolation:
+		return ErrCheckViolation
+	case SQLStateSerializationFailure:
+		return ErrSerializationFailure
+	case SQLStateDeadlockDetected:
+		return ErrDeadlockDetected
+	case SQLStateQueryCanceled, SQLStateLockNotAvailable:
+		return ErrTimeout
+	}
+
+	switch {
+	case strings.HasPrefix(code, sqlStateClassIntegrity):
+		return ErrIntegrityViolation
+	case strings.HasPrefix(code, sqlStateClassConnection):
+		return ErrConnectionLost
+	}
+
+	return nil
+}
+
+var defaultTranslator = NewErrorTranslator(nil)
+
+// TranslateError converts err using only the generic SQLSTATE mapping
+func TranslateError(err error) error {
+	return defaultTranslator.Translate(err)
+}
+
+// IsRetryable reports whether err is a transient failure that can be retried,
+// such as a serialization failure or a deadlock
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrSerializationFailure) || errors.Is(err, ErrDeadlockDetected)
+}
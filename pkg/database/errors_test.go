@@ -0,0 +1,150 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/marcelofabianov/fault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errEmailTaken = fault.New("email taken", fault.WithCode(fault.Conflict))
+
+func TestErrorTranslator_Translate(t *testing.T) {
+	t.Run("returns nil for nil error", func(t *testing.T) {
+		assert.Nil(t, TranslateError(nil))
+	})
+
+	t.Run("returns unknown errors unchanged", func(t *testing.T) {
+		original := errors.New("boom")
+
+		assert.Same(t, original, TranslateError(original))
+	})
+
+	t.Run("maps SQLSTATE to fault codes", func(t *testing.T) {
+		tests := []struct {
+			code     string
+			sentinel error
+			fault    fault.Code
+		}{
+			{SQLStateUniqueViolation, ErrUniqueViolation, fault.Conflict},
+			{SQLStateForeignKeyViolation, ErrForeignKeyViolation, fault.Invalid},
+			{SQLStateNotNullViolation, ErrNotNullViolation, fault.Invalid},
+			{SQLStateCheckViolation, ErrCheckViolation, fault.DomainViolation},
+			{SQLStateExclusionViolation, ErrCheckViolation, fault.DomainViolation},
+			{"23000", ErrIntegrityViolation, fault.Invalid},
+			{SQLStateSerializationFailure, ErrSerializationFailure, fault.Conflict},
+			{SQLStateDeadlockDetected, ErrDeadlockDetected, fault.Conflict},
+			{SQLStateQueryCanceled, ErrTimeout, fault.InfraError},
+			{SQLStateLockNotAvailable, ErrTimeout, fault.InfraError},
+			{"08006", ErrConnectionLost, fault.InfraError},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.code, func(t *testing.T) {
+				err := TranslateError(&pgconn.PgError{Code: tt.code, TableName: "users"})
+
+				assert.True(t, errors.Is(err, tt.sentinel))
+				assert.True(t, fault.IsCode(err, tt.fault))
+
+				fErr, ok := fault.AsFault(err)
+				require.True(t, ok)
+				assert.Equal(t, tt.fault, fErr.Code)
+
+				var pgErr *pgconn.PgError
+				require.True(t, errors.As(err, &pgErr), "expected the driver error as the cause")
+				assert.Equal(t, "users", pgErr.TableName)
+			})
+		}
+	})
+
+	t.Run("logs driver details instead of returning them to clients", func(t *testing.T) {
+		var logs bytes.Buffer
+		translator := NewErrorTranslator(nil)
+		translator.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+		err := translator.Translate(&pgconn.PgError{
+			Code:           SQLStateCheckViolation,
+			Message:        `new row for relation "users" violates check constraint "users_role_check"`,
+			TableName:      "users",
+			ConstraintName: "users_role_check",
+			ColumnName:     "role",
+		})
+
+		response := fault.ToResponse(err)
+		assert.Empty(t, response.Context)
+		assert.Equal(t, ErrCheckViolation.Message, response.Message)
+		assert.Contains(t, logs.String(), "constraint=users_role_check")
+		assert.Contains(t, logs.String(), "table=users")
+	})
+
+	t.Run("leaves unmapped SQLSTATE unchanged", func(t *testing.T) {
+		original := &pgconn.PgError{Code: "42P01"}
+
+		assert.Equal(t, error(original), TranslateError(original))
+	})
+
+	t.Run("maps wrapped driver errors", func(t *testing.T) {
+		wrapped := fmt.Errorf("insert: %w", &pgconn.PgError{Code: SQLStateUniqueViolation})
+
+		assert.True(t, errors.Is(TranslateError(wrapped), ErrUniqueViolation))
+	})
+
+	t.Run("maps context deadline to timeout", func(t *testing.T) {
+		err := TranslateError(context.DeadlineExceeded)
+
+		assert.True(t, errors.Is(err, ErrTimeout))
+		assert.True(t, fault.IsInfraError(err))
+	})
+
+	t.Run("prefers registered constraint errors", func(t *testing.T) {
+		translator := NewErrorTranslator(ConstraintErrors{
+			"users_email_key": func() error { return errEmailTaken },
+		})
+
+		err := translator.Translate(&pgconn.PgError{
+			Code:           SQLStateUniqueViolation,
+			ConstraintName: "users_email_key",
+		})
+
+		assert.Same(t, errEmailTaken, err)
+	})
+
+	t.Run("falls back to SQLSTATE for unregistered constraints", func(t *testing.T) {
+		translator := NewErrorTranslator(ConstraintErrors{
+			"users_email_key": func() error { return errEmailTaken },
+		})
+
+		err := translator.Translate(&pgconn.PgError{
+			Code:           SQLStateUniqueViolation,
+			ConstraintName: "users_phone_key",
+		})
+
+		assert.True(t, errors.Is(err, ErrUniqueViolation))
+	})
+
+	t.Run("register adds constraint after construction", func(t *testing.T) {
+		translator := NewErrorTranslator(nil)
+		translator.Register("USERS_EMAIL_KEY", func() error { return errEmailTaken })
+
+		err := translator.Translate(&pgconn.PgError{
+			Code:           SQLStateUniqueViolation,
+			ConstraintName: "users_email_key",
+		})
+
+		assert.Same(t, errEmailTaken, err)
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(TranslateError(&pgconn.PgError{Code: SQLStateSerializationFailure})))
+	assert.True(t, IsRetryable(TranslateError(&pgconn.PgError{Code: SQLStateDeadlockDetected})))
+	assert.False(t, IsRetryable(TranslateError(&pgconn.PgError{Code: SQLStateUniqueViolation})))
+	assert.False(t, IsRetryable(errors.New("boom")))
+}
@@ -2,6 +2,7 @@ package database_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -246,3 +247,120 @@ func Example_rawSQLDB() {
 
 	fmt.Println("Raw sql.DB access available")
 }
+
+// Example_listener demonstrates reacting to row changes with LISTEN/NOTIFY
+func Example_listener() {
+	cfg, _ := config.Load()
+	db, _ := database.New(cfg)
+
+	ctx := context.Background()
+	if err := db.Connect(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	listener := database.NewListener(db)
+
+	// A trigger calling pg_notify('users_changed', NEW.id::text) lets
+	// every replica drop its cached copy of the row
+	_ = listener.Listen("users_changed", func(ctx context.Context, n database.Notification) error {
+		fmt.Printf("Invalidate cache key user:%s\n", n.Payload)
+		return nil
+	})
+
+	// Runs in background on a dedicated connection, reconnecting with backoff
+	if err := listener.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer listener.Stop(ctx)
+
+	_ = db.Notify(ctx, "users_changed", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")
+}
+
+// Example_withLock demonstrates running a job on a single replica
+func Example_withLock() {
+	cfg, _ := config.Load()
+	db, _ := database.New(cfg)
+
+	ctx := context.Background()
+	if err := db.Connect(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	err := db.WithLock(ctx, "cleanup-expired-sessions", func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < now()")
+		return err
+	})
+	if errors.Is(err, database.ErrLockNotAcquired) {
+		fmt.Println("Another replica is running the cleanup")
+	}
+}
+
+// Example_leaderElection demonstrates electing a single leader among replicas
+func Example_leaderElection() {
+	cfg, _ := config.Load()
+	db, _ := database.New(cfg)
+
+	ctx := context.Background()
+	if err := db.Connect(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	elector := database.NewLeaderElector(db, database.LeaderElectorConfig{
+		Name: "outbox-relay",
+		OnChange: func(ctx context.Context, leader bool) {
+			fmt.Printf("Leadership changed: %v\n", leader)
+		},
+	})
+
+	if err := elector.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer elector.Stop(ctx)
+
+	// Background jobs check leadership before each run
+	if elector.IsLeader() {
+		fmt.Println("Relaying outbox messages")
+	}
+}
+
+// Example_mapper demonstrates mapping structs to rows through db tags
+func Example_mapper() {
+	cfg, _ := config.Load()
+	db, _ := database.New(cfg)
+
+	ctx := context.Background()
+	if err := db.Connect(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	type Product struct {
+		ID    string `db:"id"`
+		Name  string `db:"name"`
+		Price int64  `db:"price"`
+	}
+
+	if err := database.Insert(ctx, db.DB(), "products", &Product{ID: "p-1", Name: "Book", Price: 4990}); err != nil {
+		log.Fatal(err)
+	}
+
+	query, args, err := database.Named(
+		"SELECT id, name, price FROM products WHERE price <= :max ORDER BY name",
+		map[string]any{"max": 10000},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	products, err := database.Select[Product](ctx, db.DB(), query, args...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, p := range products {
+		fmt.Printf("%s: %d\n", p.Name, p.Price)
+	}
+}
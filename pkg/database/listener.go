@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/retry"
+)
+
+var (
+	// ErrListenerRunning is returned when the listener is started twice or
+	// a channel is registered after it has started
+	ErrListenerRunning = fault.New(
+		"listener already running",
+		fault.WithCode(fault.Conflict),
+	)
+
+	// ErrListenerNotRunning is returned when stopping a listener that was not started
+	ErrListenerNotRunning = fault.New(
+		"listener not running",
+		fault.WithCode(fault.NotFound),
+	)
+
+	// ErrInvalidChannel is returned when a channel name or handler is invalid
+	ErrInvalidChannel = fault.New(
+		"invalid notification channel",
+		fault.WithCode(fault.Invalid),
+	)
+
+	// ErrListenFailed is returned when the LISTEN command fails
+	ErrListenFailed = fault.New(
+		"failed to listen on channel",
+		fault.WithCode(fault.InfraError),
+	)
+)
+
+const listenerCloseTimeout = 5 * time.Second
+
+// Notification is a message received through LISTEN/NOTIFY
+type Notification struct {
+	Channel string
+	Payload string
+	PID     uint32
+}
+
+// NotificationHandler processes a notification received on a channel.
+// Returned errors are logged and do not stop the listener.
+type NotificationHandler func(ctx context.Context, n Notification) error
+
+// Listener holds a dedicated connection subscribed to PostgreSQL channels and
+// delivers notifications to registered handlers.
+// The connection is re-established with exponential backoff when it drops.
+type Listener struct {
+	logger   *slog.Logger
+	backoff  retry.Strategy
+	connect  func(ctx context.Context) (*pgx.Conn, error)
+	mu       sync.RWMutex
+	handlers map[string][]NotificationHandler
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewListener creates a listener bound to the given database.
+// Reconnection uses the database backoff settings from config.
+func NewListener(db *DB) *Listener {
+	return &Listener{
+		logger:   db.logger,
+		backoff:  db.config.GetDatabaseRetryConfig().Strategy,
+		connect:  db.DedicatedConn,
+		handlers: make(map[string][]NotificationHandler),
+	}
+}
+
+// SetLogger sets a custom logger for the listener
+func (l *Listener) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		l.logger = logger
+	}
+}
+
+// Listen registers a handler for a channel. Handlers must be registered
+// before Start is called.
+func (l *Listener) Listen(channel string, handler NotificationHandler) error {
+	if channel == "" || handler == nil {
+		return fault.Wrap(ErrInvalidChannel, "channel and handler are required",
+			fault.WithContext("channel", channel),
+		)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.done != nil {
+		return fault.Wrap(ErrListenerRunning, "cannot register channel after start",
+			fault.WithContext("channel", channel),
+		)
+	}
+
+	l.handlers[channel] = append(l.handlers[channel], handler)
+	return nil
+}
+
+// Start launches the background goroutine that keeps the subscription alive.
+// The listener runs until ctx is cancelled or Stop is called.
+func (l *Listener) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.done != nil {
+		return ErrListenerRunning
+	}
+
+	if len(l.handlers) == 0 {
+		l.logger.Info("Listener not started: no channels registered")
+		return nil
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	l.cancel = cancel
+	l.done = make(chan struct{})
+
+	go l.run(runCtx, l.done)
+
+	l.logger.Info("Listener started", "channels", l.channelsLocked())
+	return nil
+}
+
+// Stop cancels the subscription and waits for the listener to exit
+func (l *Listener) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	cancel, done := l.cancel, l.done
+	l.mu.Unlock()
+
+	if done == nil {
+		return ErrListenerNotRunning
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fault.Wrap(ctx.Err(), "listener did not stop in time")
+	}
+
+	l.mu.Lock()
+	l.cancel = nil
+	l.done = nil
+	l.mu.Unlock()
+
+	l.logger.Info("Listener stopped")
+	return nil
+}
+
+// IsRunning returns true if the listener has been started
+func (l *Listener) IsRunning() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.done != nil
+}
+
+// run keeps a session open, reconnecting with backoff until ctx is cancelled
+func (l *Listener) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	attempt := 0
+	for {
+		err := l.session(ctx, func() { attempt = 0 })
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := l.backoff.NextDelay(attempt)
+		attempt++
+
+		l.logger.Warn("Listener connection lost, reconnecting",
+			"attempt", attempt,
+			"delay", delay.String(),
+			"error", err.Error(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// session opens a dedicated connection, subscribes to every channel and
+// dispatches notifications until the connection fails or ctx is cancelled
+func (l *Listener) session(ctx context.Context, onConnected func()) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), listenerCloseTimeout)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	channels := l.channels()
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fault.Wrap(ErrListenFailed, "LISTEN failed",
+				fault.WithWrappedErr(err),
+				fault.WithContext("channel", channel),
+			)
+		}
+	}
+
+	onConnected()
+	l.logger.Debug("Listener subscribed", "channels", channels)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		l.dispatch(ctx, Notification{
+			Channel: n.Channel,
+			Payload: n.Payload,
+			PID:     n.PID,
+		})
+	}
+}
+
+// dispatch delivers a notification to every handler of its channel
+func (l *Listener) dispatch(ctx context.Context, n Notification) {
+	l.mu.RLock()
+	handlers := l.handlers[n.Channel]
+	l.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := l.invoke(ctx, handler, n); err != nil {
+			l.logger.Error("Notification handler failed",
+				"channel", n.Channel,
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// invoke runs a handler, converting panics into errors
+func (l *Listener) invoke(ctx context.Context, handler NotificationHandler, n Notification) (err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = fault.New(fmt.Sprintf("notification handler panic: %v", rvr),
+				fault.WithCode(fault.Internal),
+			)
+		}
+	}()
+
+	return handler(ctx, n)
+}
+
+func (l *Listener) channels() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.channelsLocked()
+}
+
+func (l *Listener) channelsLocked() []string {
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
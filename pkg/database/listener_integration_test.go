@@ -0,0 +1,76 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/retry"
+)
+
+func TestListener_Integration(t *testing.T) {
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	db, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	require.NoError(t, db.Connect(ctx))
+	defer db.Close()
+
+	listener := NewListener(db)
+	listener.backoff = retry.NewConstantBackoff(50 * time.Millisecond)
+
+	received := make(chan Notification, 10)
+	require.NoError(t, listener.Listen("listener_test", func(_ context.Context, n Notification) error {
+		received <- n
+		return nil
+	}))
+	require.NoError(t, listener.Start(ctx))
+	defer listener.Stop(context.Background())
+
+	waitFor := func(payload string) Notification {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			if err := db.Notify(ctx, "listener_test", payload); err != nil {
+				t.Fatalf("notify failed: %v", err)
+			}
+			select {
+			case n := <-received:
+				if n.Payload == payload {
+					return n
+				}
+			case <-time.After(100 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("notification %q not received", payload)
+			}
+		}
+	}
+
+	t.Run("receives notifications", func(t *testing.T) {
+		n := waitFor("hello")
+
+		assert.Equal(t, "listener_test", n.Channel)
+		assert.NotZero(t, n.PID)
+	})
+
+	t.Run("reconnects after connection is terminated", func(t *testing.T) {
+		_, err := db.ExecContext(ctx,
+			"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query LIKE 'LISTEN%' AND pid <> pg_backend_pid()")
+		require.NoError(t, err)
+
+		n := waitFor("after-reconnect")
+
+		assert.Equal(t, "after-reconnect", n.Payload)
+	})
+}
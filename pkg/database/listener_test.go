@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/retry"
+)
+
+func newTestListener(t *testing.T) *Listener {
+	t.Helper()
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	db, err := New(cfg)
+	require.NoError(t, err)
+
+	l := NewListener(db)
+	l.backoff = retry.NewConstantBackoff(time.Millisecond)
+	return l
+}
+
+func noopHandler(context.Context, Notification) error { return nil }
+
+func TestListener_Listen(t *testing.T) {
+	t.Run("rejects empty channel", func(t *testing.T) {
+		l := newTestListener(t)
+
+		err := l.Listen("", noopHandler)
+
+		assert.True(t, errors.Is(err, ErrInvalidChannel))
+	})
+
+	t.Run("rejects nil handler", func(t *testing.T) {
+		l := newTestListener(t)
+
+		err := l.Listen("users", nil)
+
+		assert.True(t, errors.Is(err, ErrInvalidChannel))
+	})
+
+	t.Run("rejects registration after start", func(t *testing.T) {
+		l := newTestListener(t)
+		l.connect = func(context.Context) (*pgx.Conn, error) { return nil, errors.New("offline") }
+		require.NoError(t, l.Listen("users", noopHandler))
+		require.NoError(t, l.Start(context.Background()))
+		t.Cleanup(func() { _ = l.Stop(context.Background()) })
+
+		err := l.Listen("orders", noopHandler)
+
+		assert.True(t, errors.Is(err, ErrListenerRunning))
+	})
+}
+
+func TestListener_StartStop(t *testing.T) {
+	t.Run("does not start without channels", func(t *testing.T) {
+		l := newTestListener(t)
+
+		require.NoError(t, l.Start(context.Background()))
+
+		assert.False(t, l.IsRunning())
+		assert.True(t, errors.Is(l.Stop(context.Background()), ErrListenerNotRunning))
+	})
+
+	t.Run("returns error when started twice", func(t *testing.T) {
+		l := newTestListener(t)
+		l.connect = func(context.Context) (*pgx.Conn, error) { return nil, errors.New("offline") }
+		require.NoError(t, l.Listen("users", noopHandler))
+		require.NoError(t, l.Start(context.Background()))
+		t.Cleanup(func() { _ = l.Stop(context.Background()) })
+
+		assert.True(t, errors.Is(l.Start(context.Background()), ErrListenerRunning))
+	})
+
+	t.Run("keeps reconnecting until stopped", func(t *testing.T) {
+		l := newTestListener(t)
+		var attempts atomic.Int32
+		l.connect = func(context.Context) (*pgx.Conn, error) {
+			attempts.Add(1)
+			return nil, errors.New("offline")
+		}
+		require.NoError(t, l.Listen("users", noopHandler))
+		require.NoError(t, l.Start(context.Background()))
+
+		assert.Eventually(t, func() bool { return attempts.Load() >= 3 }, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, l.Stop(ctx))
+		assert.False(t, l.IsRunning())
+	})
+
+	t.Run("stops when parent context is cancelled", func(t *testing.T) {
+		l := newTestListener(t)
+		l.connect = func(context.Context) (*pgx.Conn, error) { return nil, errors.New("offline") }
+		require.NoError(t, l.Listen("users", noopHandler))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, l.Start(ctx))
+		cancel()
+
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+		defer stopCancel()
+		assert.NoError(t, l.Stop(stopCtx))
+	})
+}
+
+func TestListener_Dispatch(t *testing.T) {
+	t.Run("delivers notification to channel handlers", func(t *testing.T) {
+		l := newTestListener(t)
+		var received []Notification
+		handler := func(_ context.Context, n Notification) error {
+			received = append(received, n)
+			return nil
+		}
+		require.NoError(t, l.Listen("users", handler))
+		require.NoError(t, l.Listen("users", handler))
+		require.NoError(t, l.Listen("orders", handler))
+
+		l.dispatch(context.Background(), Notification{Channel: "users", Payload: "42"})
+
+		require.Len(t, received, 2)
+		assert.Equal(t, "42", received[0].Payload)
+	})
+
+	t.Run("recovers from handler panic", func(t *testing.T) {
+		l := newTestListener(t)
+		called := false
+		require.NoError(t, l.Listen("users", func(context.Context, Notification) error {
+			panic("boom")
+		}))
+		require.NoError(t, l.Listen("users", func(context.Context, Notification) error {
+			called = true
+			return nil
+		}))
+
+		assert.NotPanics(t, func() {
+			l.dispatch(context.Background(), Notification{Channel: "users"})
+		})
+		assert.True(t, called)
+	})
+}
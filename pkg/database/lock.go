@@ -0,0 +1,411 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/retry"
+)
+
+var (
+	// ErrLockNotAcquired is returned when an advisory lock is held by another session
+	ErrLockNotAcquired = fault.New(
+		"advisory lock not acquired",
+		fault.WithCode(fault.Conflict),
+	)
+
+	// ErrLockFailed is returned when an advisory lock operation fails
+	ErrLockFailed = fault.New(
+		"advisory lock operation failed",
+		fault.WithCode(fault.InfraError),
+	)
+
+	// ErrElectorRunning is returned when the leader elector is started twice
+	ErrElectorRunning = fault.New(
+		"leader elector already running",
+		fault.WithCode(fault.Conflict),
+	)
+
+	// ErrElectorNotRunning is returned when stopping an elector that was not started
+	ErrElectorNotRunning = fault.New(
+		"leader elector not running",
+		fault.WithCode(fault.NotFound),
+	)
+)
+
+const (
+	defaultElectionInterval = 5 * time.Second
+	lockReleaseTimeout      = 5 * time.Second
+)
+
+// LockKey converts a lock name into the 64-bit key used by pg_advisory_lock
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64()) // #nosec G115 - wrapping is intended, any 64-bit value is a valid key
+}
+
+// Lock is a session-level advisory lock held on a pooled connection.
+// The connection is kept out of the pool until Unlock is called.
+type Lock struct {
+	mu   sync.Mutex
+	conn *sql.Conn
+	name string
+	key  int64
+}
+
+// TryLock acquires a session-level advisory lock without waiting.
+// It returns ErrLockNotAcquired if another session holds the lock.
+func (db *DB) TryLock(ctx context.Context, name string) (*Lock, error) {
+	if db.conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return nil, fault.Wrap(ErrLockFailed, "failed to acquire connection",
+			fault.WithWrappedErr(err),
+			fault.WithContext("lock", name),
+		)
+	}
+
+	key := LockKey(name)
+
+	queryCtx, cancel := context.WithTimeout(ctx, db.config.Database.Connect.QueryTimeout)
+	defer cancel()
+
+	var acquired bool
+	if err := conn.QueryRowContext(queryCtx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		// The lock may have been taken before the error, as on a timeout
+		discardConn(conn)
+		return nil, fault.Wrap(ErrLockFailed, "pg_try_advisory_lock failed",
+			fault.WithWrappedErr(err),
+			fault.WithContext("lock", name),
+		)
+	}
+
+	if !acquired {
+		_ = conn.Close()
+		return nil, fault.Wrap(ErrLockNotAcquired, "lock held by another session",
+			fault.WithCode(fault.Conflict),
+			fault.WithContext("lock", name),
+		)
+	}
+
+	return &Lock{conn: conn, name: name, key: key}, nil
+}
+
+// WithLock runs fn while holding the named advisory lock.
+// It returns ErrLockNotAcquired without running fn if the lock is taken.
+func (db *DB) WithLock(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	lock, err := db.TryLock(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Unlock(context.Background()); err != nil {
+			db.logger.Error("Failed to release advisory lock", "lock", name, "error", err.Error())
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// Name returns the lock name
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Unlock releases the lock and returns the connection to the pool. When
+// the release fails the connection is closed instead, so the lock cannot
+// outlive Unlock on a pooled session. Calling Unlock more than once is a
+// no-op.
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	unlockCtx, cancel := context.WithTimeout(ctx, lockReleaseTimeout)
+	defer cancel()
+
+	_, err := l.conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", l.key)
+	if err != nil {
+		// Closing the session is what releases the lock now
+		discardConn(l.conn)
+		l.conn = nil
+		return fault.Wrap(ErrLockFailed, "pg_advisory_unlock failed",
+			fault.WithWrappedErr(err),
+			fault.WithContext("lock", l.name),
+		)
+	}
+
+	closeErr := l.conn.Close()
+	l.conn = nil
+	if closeErr != nil {
+		return fault.Wrap(ErrLockFailed, "failed to release connection",
+			fault.WithWrappedErr(closeErr),
+			fault.WithContext("lock", l.name),
+		)
+	}
+
+	return nil
+}
+
+// discardConn closes the session of conn instead of returning it to the
+// pool, for sessions that may still hold a lock: database/sql drops the
+// connections whose Raw callback fails with driver.ErrBadConn.
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+}
+
+// LeaderElectorConfig holds leader election settings
+type LeaderElectorConfig struct {
+	// Name identifies the election; every replica must use the same name
+	Name string
+
+	// Interval between lock attempts as follower and liveness checks as leader
+	Interval time.Duration
+
+	// OnChange is called whenever leadership is gained or lost
+	OnChange func(ctx context.Context, leader bool)
+}
+
+// lockSession is a long-lived session able to hold advisory locks
+type lockSession interface {
+	TryAcquire(ctx context.Context, key int64) (bool, error)
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+}
+
+// LeaderElector keeps a session-level advisory lock on a dedicated
+// connection so that exactly one replica acts as leader. When the leader's
+// connection dies PostgreSQL releases the lock and another replica takes over.
+type LeaderElector struct {
+	logger   *slog.Logger
+	backoff  retry.Strategy
+	connect  func(ctx context.Context) (lockSession, error)
+	name     string
+	key      int64
+	interval time.Duration
+	onChange func(ctx context.Context, leader bool)
+	mu       sync.RWMutex
+	leader   bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewLeaderElector creates an elector bound to the given database.
+// Reconnection uses the database backoff settings from config.
+func NewLeaderElector(db *DB, cfg LeaderElectorConfig) *LeaderElector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultElectionInterval
+	}
+
+	return &LeaderElector{
+		logger:  db.logger,
+		backoff: db.config.GetDatabaseRetryConfig().Strategy,
+		connect: func(ctx context.Context) (lockSession, error) {
+			conn, err := db.DedicatedConn(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &pgxLockSession{conn: conn}, nil
+		},
+		name:     cfg.Name,
+		key:      LockKey(cfg.Name),
+		interval: cfg.Interval,
+		onChange: cfg.OnChange,
+	}
+}
+
+// SetLogger sets a custom logger for the elector
+func (e *LeaderElector) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		e.logger = logger
+	}
+}
+
+// IsLeader reports whether this instance currently holds leadership
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// IsRunning returns true if the elector has been started
+func (e *LeaderElector) IsRunning() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.done != nil
+}
+
+// Start launches the election loop in background.
+// The loop runs until ctx is cancelled or Stop is called.
+func (e *LeaderElector) Start(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.done != nil {
+		return ErrElectorRunning
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+	e.done = make(chan struct{})
+
+	go e.run(runCtx, e.done)
+
+	e.logger.Info("Leader election started", "election", e.name, "interval", e.interval.String())
+	return nil
+}
+
+// Stop leaves the election, releasing leadership if held
+func (e *LeaderElector) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.mu.Unlock()
+
+	if done == nil {
+		return ErrElectorNotRunning
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fault.Wrap(ctx.Err(), "leader elector did not stop in time")
+	}
+
+	e.mu.Lock()
+	e.cancel = nil
+	e.done = nil
+	e.mu.Unlock()
+
+	e.logger.Info("Leader election stopped", "election", e.name)
+	return nil
+}
+
+// run campaigns for leadership, reconnecting with backoff until ctx is cancelled
+func (e *LeaderElector) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	attempt := 0
+	for {
+		err := e.campaign(ctx, func() { attempt = 0 })
+		e.setLeader(ctx, false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := e.backoff.NextDelay(attempt)
+		attempt++
+
+		e.logger.Warn("Leader election session lost, reconnecting",
+			"election", e.name,
+			"attempt", attempt,
+			"delay", delay.String(),
+			"error", err.Error(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// campaign holds one session: followers try to take the lock, the leader
+// verifies its session is alive. Returns when the session fails.
+func (e *LeaderElector) campaign(ctx context.Context, onConnected func()) error {
+	session, err := e.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), lockReleaseTimeout)
+		defer cancel()
+		_ = session.Close(closeCtx)
+	}()
+
+	onConnected()
+
+	for {
+		if e.IsLeader() {
+			if err := session.Ping(ctx); err != nil {
+				return err
+			}
+		} else {
+			acquired, err := session.TryAcquire(ctx, e.key)
+			if err != nil {
+				return err
+			}
+			if acquired {
+				e.setLeader(ctx, true)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.interval):
+		}
+	}
+}
+
+// setLeader records the leadership state and reports changes
+func (e *LeaderElector) setLeader(ctx context.Context, leader bool) {
+	e.mu.Lock()
+	changed := e.leader != leader
+	e.leader = leader
+	e.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if leader {
+		e.logger.Info("Leadership acquired", "election", e.name)
+	} else {
+		e.logger.Warn("Leadership lost", "election", e.name)
+	}
+
+	if e.onChange != nil {
+		e.onChange(ctx, leader)
+	}
+}
+
+// pgxLockSession implements lockSession on a dedicated pgx connection
+type pgxLockSession struct {
+	conn *pgx.Conn
+}
+
+func (s *pgxLockSession) TryAcquire(ctx context.Context, key int64) (bool, error) {
+	var acquired bool
+	if err := s.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		return false, fault.Wrap(ErrLockFailed, "pg_try_advisory_lock failed",
+			fault.WithWrappedErr(err),
+		)
+	}
+	return acquired, nil
+}
+
+func (s *pgxLockSession) Ping(ctx context.Context) error {
+	return s.conn.Ping(ctx)
+}
+
+func (s *pgxLockSession) Close(ctx context.Context) error {
+	return s.conn.Close(ctx)
+}
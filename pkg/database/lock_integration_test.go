@@ -0,0 +1,119 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/retry"
+)
+
+func connectIntegrationDB(t *testing.T) *DB {
+	t.Helper()
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	db, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	require.NoError(t, db.Connect(ctx))
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestDB_TryLock_Integration(t *testing.T) {
+	db := connectIntegrationDB(t)
+	ctx := context.Background()
+
+	t.Run("second session cannot acquire held lock", func(t *testing.T) {
+		lock, err := db.TryLock(ctx, "integration-lock")
+		require.NoError(t, err)
+
+		_, err = db.TryLock(ctx, "integration-lock")
+		assert.True(t, errors.Is(err, ErrLockNotAcquired))
+
+		require.NoError(t, lock.Unlock(ctx))
+
+		again, err := db.TryLock(ctx, "integration-lock")
+		require.NoError(t, err)
+		require.NoError(t, again.Unlock(ctx))
+	})
+
+	t.Run("failed unlock closes the session holding the lock", func(t *testing.T) {
+		lock, err := db.TryLock(ctx, "integration-failed-unlock")
+		require.NoError(t, err)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.True(t, errors.Is(lock.Unlock(canceled), ErrLockFailed))
+
+		// PostgreSQL releases the lock once it sees the session end
+		assert.Eventually(t, func() bool {
+			again, err := db.TryLock(ctx, "integration-failed-unlock")
+			if err != nil {
+				return false
+			}
+			require.NoError(t, again.Unlock(ctx))
+			return true
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("WithLock skips work while lock is held", func(t *testing.T) {
+		lock, err := db.TryLock(ctx, "integration-with-lock")
+		require.NoError(t, err)
+		defer lock.Unlock(ctx)
+
+		called := false
+		err = db.WithLock(ctx, "integration-with-lock", func(context.Context) error {
+			called = true
+			return nil
+		})
+
+		assert.True(t, errors.Is(err, ErrLockNotAcquired))
+		assert.False(t, called)
+	})
+}
+
+func TestLeaderElector_Failover_Integration(t *testing.T) {
+	db := connectIntegrationDB(t)
+	ctx := context.Background()
+
+	newElector := func() *LeaderElector {
+		e := NewLeaderElector(db, LeaderElectorConfig{
+			Name:     "integration-election",
+			Interval: 50 * time.Millisecond,
+		})
+		e.backoff = retry.NewConstantBackoff(50 * time.Millisecond)
+		return e
+	}
+
+	a := newElector()
+	require.NoError(t, a.Start(ctx))
+	defer a.Stop(ctx)
+	require.Eventually(t, a.IsLeader, 5*time.Second, 10*time.Millisecond)
+
+	b := newElector()
+	require.NoError(t, b.Start(ctx))
+	defer b.Stop(ctx)
+	assert.Never(t, b.IsLeader, 300*time.Millisecond, 20*time.Millisecond)
+
+	// Kill the leader's backend; PostgreSQL releases its session locks
+	_, err := db.ExecContext(ctx,
+		"SELECT pg_terminate_backend(pid) FROM pg_locks WHERE locktype = 'advisory' AND pid <> pg_backend_pid()")
+	require.NoError(t, err)
+
+	assert.Eventually(t, b.IsLeader, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return !a.IsLeader() }, 5*time.Second, 10*time.Millisecond)
+}
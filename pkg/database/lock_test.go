@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/retry"
+)
+
+// fakeLockServer emulates PostgreSQL advisory locks shared between sessions
+type fakeLockServer struct {
+	mu      sync.Mutex
+	holders map[int64]*fakeLockSession
+}
+
+func newFakeLockServer() *fakeLockServer {
+	return &fakeLockServer{holders: make(map[int64]*fakeLockSession)}
+}
+
+func (s *fakeLockServer) connect(context.Context) (lockSession, error) {
+	return &fakeLockSession{server: s}, nil
+}
+
+// kill terminates the session holding key, as pg_terminate_backend would
+func (s *fakeLockServer) kill(key int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if holder, ok := s.holders[key]; ok {
+		holder.dead = true
+		delete(s.holders, key)
+	}
+}
+
+type fakeLockSession struct {
+	server *fakeLockServer
+	dead   bool
+}
+
+func (f *fakeLockSession) TryAcquire(_ context.Context, key int64) (bool, error) {
+	f.server.mu.Lock()
+	defer f.server.mu.Unlock()
+	if f.dead {
+		return false, errors.New("connection terminated")
+	}
+	if holder, ok := f.server.holders[key]; ok && holder != f {
+		return false, nil
+	}
+	f.server.holders[key] = f
+	return true, nil
+}
+
+func (f *fakeLockSession) Ping(context.Context) error {
+	f.server.mu.Lock()
+	defer f.server.mu.Unlock()
+	if f.dead {
+		return errors.New("connection terminated")
+	}
+	return nil
+}
+
+func (f *fakeLockSession) Close(context.Context) error {
+	f.server.mu.Lock()
+	defer f.server.mu.Unlock()
+	for key, holder := range f.server.holders {
+		if holder == f {
+			delete(f.server.holders, key)
+		}
+	}
+	return nil
+}
+
+func newTestElector(t *testing.T, server *fakeLockServer, onChange func(context.Context, bool)) *LeaderElector {
+	t.Helper()
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	db, err := New(cfg)
+	require.NoError(t, err)
+
+	e := NewLeaderElector(db, LeaderElectorConfig{
+		Name:     "jobs",
+		Interval: 5 * time.Millisecond,
+		OnChange: onChange,
+	})
+	e.backoff = retry.NewConstantBackoff(time.Millisecond)
+	e.connect = server.connect
+	return e
+}
+
+func TestLockKey(t *testing.T) {
+	assert.Equal(t, LockKey("cleanup"), LockKey("cleanup"))
+	assert.NotEqual(t, LockKey("cleanup"), LockKey("outbox"))
+}
+
+func TestDB_TryLock(t *testing.T) {
+	t.Run("returns error when not connected", func(t *testing.T) {
+		cfg, _ := config.Load()
+		db, _ := New(cfg)
+
+		_, err := db.TryLock(context.Background(), "cleanup")
+
+		assert.True(t, errors.Is(err, ErrNotConnected))
+	})
+}
+
+func TestDB_WithLock(t *testing.T) {
+	t.Run("does not run fn when not connected", func(t *testing.T) {
+		cfg, _ := config.Load()
+		db, _ := New(cfg)
+		called := false
+
+		err := db.WithLock(context.Background(), "cleanup", func(context.Context) error {
+			called = true
+			return nil
+		})
+
+		assert.True(t, errors.Is(err, ErrNotConnected))
+		assert.False(t, called)
+	})
+}
+
+func TestLeaderElector(t *testing.T) {
+	t.Run("single instance becomes leader", func(t *testing.T) {
+		server := newFakeLockServer()
+		changes := make(chan bool, 10)
+		e := newTestElector(t, server, func(_ context.Context, leader bool) { changes <- leader })
+
+		require.NoError(t, e.Start(context.Background()))
+		t.Cleanup(func() { _ = e.Stop(context.Background()) })
+
+		assert.Eventually(t, e.IsLeader, time.Second, time.Millisecond)
+		assert.True(t, <-changes)
+	})
+
+	t.Run("only one of two instances leads", func(t *testing.T) {
+		server := newFakeLockServer()
+		a := newTestElector(t, server, nil)
+		b := newTestElector(t, server, nil)
+
+		require.NoError(t, a.Start(context.Background()))
+		t.Cleanup(func() { _ = a.Stop(context.Background()) })
+		assert.Eventually(t, a.IsLeader, time.Second, time.Millisecond)
+
+		require.NoError(t, b.Start(context.Background()))
+		t.Cleanup(func() { _ = b.Stop(context.Background()) })
+
+		assert.Never(t, b.IsLeader, 50*time.Millisecond, 5*time.Millisecond)
+		assert.True(t, a.IsLeader())
+	})
+
+	t.Run("fails over when the leader connection dies", func(t *testing.T) {
+		server := newFakeLockServer()
+		aChanges := make(chan bool, 10)
+		a := newTestElector(t, server, func(_ context.Context, leader bool) { aChanges <- leader })
+		b := newTestElector(t, server, nil)
+
+		require.NoError(t, a.Start(context.Background()))
+		t.Cleanup(func() { _ = a.Stop(context.Background()) })
+		assert.Eventually(t, a.IsLeader, time.Second, time.Millisecond)
+		require.True(t, <-aChanges)
+
+		require.NoError(t, b.Start(context.Background()))
+		t.Cleanup(func() { _ = b.Stop(context.Background()) })
+
+		server.kill(a.key)
+
+		assert.Eventually(t, b.IsLeader, time.Second, time.Millisecond)
+		assert.False(t, <-aChanges)
+		assert.False(t, a.IsLeader())
+	})
+
+	t.Run("releases leadership on stop", func(t *testing.T) {
+		server := newFakeLockServer()
+		a := newTestElector(t, server, nil)
+		b := newTestElector(t, server, nil)
+
+		require.NoError(t, a.Start(context.Background()))
+		assert.Eventually(t, a.IsLeader, time.Second, time.Millisecond)
+		require.NoError(t, b.Start(context.Background()))
+		t.Cleanup(func() { _ = b.Stop(context.Background()) })
+
+		require.NoError(t, a.Stop(context.Background()))
+
+		assert.False(t, a.IsLeader())
+		assert.Eventually(t, b.IsLeader, time.Second, time.Millisecond)
+	})
+
+	t.Run("returns error when started twice", func(t *testing.T) {
+		e := newTestElector(t, newFakeLockServer(), nil)
+		require.NoError(t, e.Start(context.Background()))
+		t.Cleanup(func() { _ = e.Stop(context.Background()) })
+
+		assert.True(t, errors.Is(e.Start(context.Background()), ErrElectorRunning))
+	})
+
+	t.Run("returns error when stopped before start", func(t *testing.T) {
+		e := newTestElector(t, newFakeLockServer(), nil)
+
+		assert.True(t, errors.Is(e.Stop(context.Background()), ErrElectorNotRunning))
+	})
+}
+
+// failingUnlockConn is a driver connection whose statements all fail
+type failingUnlockConn struct {
+	closed *bool
+}
+
+func (c *failingUnlockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (c *failingUnlockConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (c *failingUnlockConn) Close() error {
+	*c.closed = true
+	return nil
+}
+
+func (c *failingUnlockConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return nil, errors.New("connection reset")
+}
+
+type failingUnlockConnector struct {
+	closed *bool
+}
+
+func (c failingUnlockConnector) Connect(context.Context) (driver.Conn, error) {
+	return &failingUnlockConn{closed: c.closed}, nil
+}
+
+func (c failingUnlockConnector) Driver() driver.Driver { return nil }
+
+func TestLock_UnlockFailureDiscardsConnection(t *testing.T) {
+	var closed bool
+	pool := sql.OpenDB(failingUnlockConnector{closed: &closed})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	lock := &Lock{conn: conn, name: "jobs", key: LockKey("jobs")}
+
+	err = lock.Unlock(context.Background())
+
+	assert.True(t, errors.Is(err, ErrLockFailed))
+	assert.True(t, closed, "expected the session holding the lock to be closed")
+	assert.Zero(t, pool.Stats().Idle, "expected the connection not to return to the pool")
+	assert.NoError(t, lock.Unlock(context.Background()))
+}
@@ -0,0 +1,461 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/marcelofabianov/fault"
+)
+
+var (
+	// ErrNoRows is returned by Get when the query returns no rows
+	ErrNoRows = fault.New(
+		"no rows in result set",
+		fault.WithCode(fault.NotFound),
+	)
+
+	// ErrMappingFailed is returned when a type cannot be mapped to columns
+	ErrMappingFailed = fault.New(
+		"failed to map struct to columns",
+		fault.WithCode(fault.Internal),
+	)
+
+	// ErrScanFailed is returned when a row cannot be scanned into a struct
+	ErrScanFailed = fault.New(
+		"failed to scan row",
+		fault.WithCode(fault.Internal),
+	)
+
+	// ErrNamedParameter is returned when a named parameter has no matching value
+	ErrNamedParameter = fault.New(
+		"invalid named parameter",
+		fault.WithCode(fault.Internal),
+	)
+)
+
+const dbTag = "db"
+
+// Querier is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+//
+// Driver errors returned by the helpers below are passed through unchanged,
+// so repositories can convert them with their ErrorTranslator.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// structMap holds the column layout of a struct type
+type structMap struct {
+	columns []string
+	fields  map[string][]int
+}
+
+var structMaps sync.Map // reflect.Type -> *structMap
+
+// mapOf returns the cached column layout of t.
+// Fields are mapped by their db tag; untagged embedded structs (such as
+// wisp.Audit) are flattened; fields tagged db:"-" are ignored.
+func mapOf(t reflect.Type) (*structMap, error) {
+	if cached, ok := structMaps.Load(t); ok {
+		return cached.(*structMap), nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fault.Wrap(ErrMappingFailed, "type is not a struct",
+			fault.WithContext("type", t.String()),
+		)
+	}
+
+	m := &structMap{fields: make(map[string][]int)}
+	if err := m.collect(t, nil); err != nil {
+		return nil, err
+	}
+
+	actual, _ := structMaps.LoadOrStore(t, m)
+	return actual.(*structMap), nil
+}
+
+func (m *structMap) collect(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := append(append([]int{}, index...), i)
+
+		tag := field.Tag.Get(dbTag)
+		if tag == "-" {
+			continue
+		}
+
+		if tag == "" {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := m.collect(field.Type, path); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		column := strings.SplitN(tag, ",", 2)[0]
+		if _, exists := m.fields[column]; exists {
+			return fault.Wrap(ErrMappingFailed, "duplicate column",
+				fault.WithContext("type", t.String()),
+				fault.WithContext("column", column),
+			)
+		}
+
+		m.columns = append(m.columns, column)
+		m.fields[column] = path
+	}
+
+	return nil
+}
+
+// Columns returns the db columns of T in declaration order
+func Columns[T any]() ([]string, error) {
+	m, err := mapOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{}, m.columns...), nil
+}
+
+// BuildInsert builds an INSERT statement for every db column of entity
+func BuildInsert[T any](table string, entity *T) (string, []any, error) {
+	m, err := mapOf(reflect.TypeOf(entity).Elem())
+	if err != nil {
+		return "", nil, err
+	}
+
+	v := reflect.ValueOf(entity).Elem()
+	placeholders := make([]string, len(m.columns))
+	args := make([]any, len(m.columns))
+	for i, column := range m.columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = v.FieldByIndex(m.fields[column]).Interface()
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table,
+		strings.Join(m.columns, ", "),
+		strings.Join(placeholders, ", "),
+	)
+
+	return query, args, nil
+}
+
+// BuildInsertMany builds a multi-row INSERT statement for entities.
+// Callers may append clauses such as ON CONFLICT or RETURNING.
+func BuildInsertMany[T any](table string, entities []*T) (string, []any, error) {
+	if len(entities) == 0 {
+		return "", nil, fault.Wrap(ErrMappingFailed, "insert requires at least one row",
+			fault.WithContext("table", table),
+		)
+	}
+
+	m, err := mapOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return "", nil, err
+	}
+
+	rows := make([]string, len(entities))
+	args := make([]any, 0, len(entities)*len(m.columns))
+	for i, entity := range entities {
+		v := reflect.ValueOf(entity).Elem()
+		placeholders := make([]string, len(m.columns))
+		for j, column := range m.columns {
+			args = append(args, v.FieldByIndex(m.fields[column]).Interface())
+			placeholders[j] = fmt.Sprintf("$%d", len(args))
+		}
+		rows[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		table,
+		strings.Join(m.columns, ", "),
+		strings.Join(rows, ", "),
+	)
+
+	return query, args, nil
+}
+
+// BuildUpdate builds an UPDATE statement setting every db column of entity
+// except the key columns, which form the WHERE clause
+func BuildUpdate[T any](table string, entity *T, keyColumns ...string) (string, []any, error) {
+	if len(keyColumns) == 0 {
+		return "", nil, fault.Wrap(ErrMappingFailed, "update requires at least one key column",
+			fault.WithContext("table", table),
+		)
+	}
+
+	m, err := mapOf(reflect.TypeOf(entity).Elem())
+	if err != nil {
+		return "", nil, err
+	}
+
+	isKey := make(map[string]bool, len(keyColumns))
+	for _, key := range keyColumns {
+		if _, ok := m.fields[key]; !ok {
+			return "", nil, fault.Wrap(ErrMappingFailed, "unknown key column",
+				fault.WithContext("table", table),
+				fault.WithContext("column", key),
+			)
+		}
+		isKey[key] = true
+	}
+
+	v := reflect.ValueOf(entity).Elem()
+	sets := make([]string, 0, len(m.columns))
+	args := make([]any, 0, len(m.columns))
+	for _, column := range m.columns {
+		if isKey[column] {
+			continue
+		}
+		args = append(args, v.FieldByIndex(m.fields[column]).Interface())
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	conditions := make([]string, len(keyColumns))
+	for i, key := range keyColumns {
+		args = append(args, v.FieldByIndex(m.fields[key]).Interface())
+		conditions[i] = fmt.Sprintf("%s = $%d", key, len(args))
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		table,
+		strings.Join(sets, ", "),
+		strings.Join(conditions, " AND "),
+	)
+
+	return query, args, nil
+}
+
+// Insert inserts entity into table using its db columns
+func Insert[T any](ctx context.Context, q Querier, table string, entity *T) error {
+	query, args, err := BuildInsert(table, entity)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Update updates the row of table identified by keyColumns and returns
+// the number of affected rows
+func Update[T any](ctx context.Context, q Querier, table string, entity *T, keyColumns ...string) (int64, error) {
+	query, args, err := BuildUpdate(table, entity, keyColumns...)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Get runs query and scans the first row into a new T.
+// It returns ErrNoRows when the query returns no rows.
+func Get[T any](ctx context.Context, q Querier, query string, args ...any) (*T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNoRows
+	}
+
+	entity := new(T)
+	if err := scanRow(rows, entity); err != nil {
+		return nil, err
+	}
+
+	return entity, rows.Err()
+}
+
+// Select runs query and scans every row into a slice of T
+func Select[T any](ctx context.Context, q Querier, query string, args ...any) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []T
+	for rows.Next() {
+		var entity T
+		if err := scanRow(rows, &entity); err != nil {
+			return nil, err
+		}
+		result = append(result, entity)
+	}
+
+	return result, rows.Err()
+}
+
+// scanRow scans the current row into dest, matching columns to db tags.
+// Fields implementing sql.Scanner (such as the wisp value types) decode
+// themselves.
+func scanRow(rows *sql.Rows, dest any) error {
+	v := reflect.ValueOf(dest).Elem()
+
+	m, err := mapOf(v.Type())
+	if err != nil {
+		return err
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fault.Wrap(fmt.Errorf("%w: %w", ErrScanFailed, err), "failed to read columns",
+			fault.WithCode(fault.Internal),
+		)
+	}
+
+	targets := make([]any, len(columns))
+	for i, column := range columns {
+		path, ok := m.fields[column]
+		if !ok {
+			return fault.Wrap(ErrScanFailed, "column has no matching field",
+				fault.WithContext("type", v.Type().String()),
+				fault.WithContext("column", column),
+			)
+		}
+		targets[i] = v.FieldByIndex(path).Addr().Interface()
+	}
+
+	if err := rows.Scan(targets...); err != nil {
+		// fault.Wrap keeps a single cause, so ErrScanFailed and err are
+		// joined to keep both reachable with errors.Is and errors.As
+		return fault.Wrap(fmt.Errorf("%w: %w", ErrScanFailed, err), "scan failed",
+			fault.WithCode(fault.Internal),
+			fault.WithContext("type", v.Type().String()),
+		)
+	}
+
+	return nil
+}
+
+// Named converts a query using :name parameters into positional $n
+// parameters. arg is either a map[string]any or a struct (or pointer to
+// struct) whose db tags provide the values. A name used more than once is
+// bound to the same position. PostgreSQL casts (::type) and quoted literals
+// are left untouched.
+func Named(query string, arg any) (string, []any, error) {
+	lookup, err := namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		out       strings.Builder
+		args      []any
+		positions = make(map[string]int)
+		inQuote   bool
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'':
+			inQuote = !inQuote
+			out.WriteByte(c)
+		case inQuote || c != ':':
+			out.WriteByte(c)
+		case i+1 < len(query) && query[i+1] == ':':
+			out.WriteString("::")
+			i++
+		default:
+			j := i + 1
+			for j < len(query) && isNameChar(query[j]) {
+				j++
+			}
+			if j == i+1 {
+				out.WriteByte(c)
+				continue
+			}
+
+			name := query[i+1 : j]
+			pos, seen := positions[name]
+			if !seen {
+				value, ok := lookup(name)
+				if !ok {
+					return "", nil, fault.Wrap(ErrNamedParameter, "missing value for parameter",
+						fault.WithContext("parameter", name),
+					)
+				}
+				args = append(args, value)
+				pos = len(args)
+				positions[name] = pos
+			}
+
+			fmt.Fprintf(&out, "$%d", pos)
+			i = j - 1
+		}
+	}
+
+	return out.String(), args, nil
+}
+
+func namedLookup(arg any) (func(name string) (any, bool), error) {
+	if params, ok := arg.(map[string]any); ok {
+		return func(name string) (any, bool) {
+			value, ok := params[name]
+			return value, ok
+		}, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fault.Wrap(ErrNamedParameter, "named argument is nil")
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, fault.Wrap(ErrNamedParameter, "named argument must be a map or struct",
+			fault.WithContext("type", fmt.Sprintf("%T", arg)),
+		)
+	}
+
+	m, err := mapOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	return func(name string) (any, bool) {
+		path, ok := m.fields[name]
+		if !ok {
+			return nil, false
+		}
+		return v.FieldByIndex(path).Interface(), true
+	}, nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+// IsNoRows reports whether err means the query returned no rows
+func IsNoRows(err error) bool {
+	return errors.Is(err, ErrNoRows) || errors.Is(err, sql.ErrNoRows)
+}
@@ -0,0 +1,93 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/marcelofabianov/wisp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapperItem struct {
+	ID   wisp.UUID           `db:"id"`
+	Name wisp.NonEmptyString `db:"name"`
+	wisp.Audit
+}
+
+func TestMapper_Integration(t *testing.T) {
+	db := connectIntegrationDB(t)
+	ctx := context.Background()
+
+	tx, err := db.DB().BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TEMP TABLE mapper_items (
+			id UUID PRIMARY KEY,
+			name TEXT NOT NULL,
+			audit_created_at TIMESTAMPTZ NOT NULL,
+			audit_created_by TEXT NOT NULL,
+			audit_updated_at TIMESTAMPTZ NOT NULL,
+			audit_updated_by TEXT NOT NULL,
+			audit_archived_at TIMESTAMPTZ,
+			audit_deleted_at TIMESTAMPTZ,
+			audit_version INTEGER NOT NULL
+		) ON COMMIT DROP
+	`)
+	require.NoError(t, err)
+
+	newItem := func(name string) *mapperItem {
+		id, err := wisp.NewUUID()
+		require.NoError(t, err)
+		itemName, err := wisp.NewNonEmptyString(name)
+		require.NoError(t, err)
+		return &mapperItem{ID: id, Name: itemName, Audit: wisp.NewAudit(wisp.SystemAuditUser)}
+	}
+
+	first, second := newItem("first"), newItem("second")
+	require.NoError(t, Insert(ctx, tx, "mapper_items", first))
+	require.NoError(t, Insert(ctx, tx, "mapper_items", second))
+
+	t.Run("Get scans wisp types", func(t *testing.T) {
+		query, args, err := Named("SELECT * FROM mapper_items WHERE id = :id", first)
+		require.NoError(t, err)
+
+		got, err := Get[mapperItem](ctx, tx, query, args...)
+		require.NoError(t, err)
+
+		assert.Equal(t, first.ID, got.ID)
+		assert.Equal(t, first.Name, got.Name)
+		assert.Equal(t, first.Audit.CreatedBy, got.Audit.CreatedBy)
+	})
+
+	t.Run("Get returns ErrNoRows", func(t *testing.T) {
+		_, err := Get[mapperItem](ctx, tx, "SELECT * FROM mapper_items WHERE name = 'missing'")
+		assert.True(t, IsNoRows(err))
+	})
+
+	t.Run("Select returns every row", func(t *testing.T) {
+		items, err := Select[mapperItem](ctx, tx, "SELECT * FROM mapper_items ORDER BY name")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, first.ID, items[0].ID)
+		assert.Equal(t, second.ID, items[1].ID)
+	})
+
+	t.Run("Update writes changed columns", func(t *testing.T) {
+		renamed, err := wisp.NewNonEmptyString("renamed")
+		require.NoError(t, err)
+		first.Name = renamed
+
+		affected, err := Update(ctx, tx, "mapper_items", first, "id")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), affected)
+
+		got, err := Get[mapperItem](ctx, tx, "SELECT * FROM mapper_items WHERE id = $1", first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, renamed, got.Name)
+	})
+}
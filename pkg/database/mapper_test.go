@@ -0,0 +1,244 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/marcelofabianov/wisp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapperAccount struct {
+	ID       wisp.UUID           `db:"id"`
+	Name     wisp.NonEmptyString `db:"name"`
+	Email    wisp.Email          `db:"email"`
+	Internal string              `db:"-"`
+	Untagged string
+	secret   string `db:"secret"`
+	wisp.Audit
+}
+
+func TestColumns(t *testing.T) {
+	t.Run("maps tagged fields and flattens embedded structs", func(t *testing.T) {
+		columns, err := Columns[mapperAccount]()
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"id", "name", "email",
+			"audit_created_at", "audit_created_by",
+			"audit_updated_at", "audit_updated_by",
+			"audit_archived_at", "audit_deleted_at", "audit_version",
+		}, columns)
+	})
+
+	t.Run("rejects non struct types", func(t *testing.T) {
+		_, err := Columns[string]()
+		assert.True(t, errors.Is(err, ErrMappingFailed))
+	})
+
+	t.Run("rejects duplicate columns", func(t *testing.T) {
+		type duplicated struct {
+			A string `db:"name"`
+			B string `db:"name"`
+		}
+
+		_, err := Columns[duplicated]()
+		assert.True(t, errors.Is(err, ErrMappingFailed))
+	})
+}
+
+func TestBuildInsert(t *testing.T) {
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	query, args, err := BuildInsert("accounts", &row{ID: 7, Name: "Ana"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "INSERT INTO accounts (id, name) VALUES ($1, $2)", query)
+	assert.Equal(t, []any{7, "Ana"}, args)
+}
+
+func TestBuildInsertMany(t *testing.T) {
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	t.Run("builds one tuple per entity", func(t *testing.T) {
+		query, args, err := BuildInsertMany("accounts", []*row{{ID: 1, Name: "Ana"}, {ID: 2, Name: "Bia"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, "INSERT INTO accounts (id, name) VALUES ($1, $2), ($3, $4)", query)
+		assert.Equal(t, []any{1, "Ana", 2, "Bia"}, args)
+	})
+
+	t.Run("requires at least one entity", func(t *testing.T) {
+		_, _, err := BuildInsertMany[row]("accounts", nil)
+		assert.True(t, errors.Is(err, ErrMappingFailed))
+	})
+}
+
+func TestBuildUpdate(t *testing.T) {
+	type row struct {
+		TenantID int    `db:"tenant_id"`
+		ID       int    `db:"id"`
+		Name     string `db:"name"`
+		Active   bool   `db:"active"`
+	}
+
+	t.Run("sets non key columns and filters by keys", func(t *testing.T) {
+		query, args, err := BuildUpdate("accounts", &row{TenantID: 1, ID: 7, Name: "Ana", Active: true}, "tenant_id", "id")
+		require.NoError(t, err)
+
+		assert.Equal(t, "UPDATE accounts SET name = $1, active = $2 WHERE tenant_id = $3 AND id = $4", query)
+		assert.Equal(t, []any{"Ana", true, 1, 7}, args)
+	})
+
+	t.Run("requires a key column", func(t *testing.T) {
+		_, _, err := BuildUpdate("accounts", &row{})
+		assert.True(t, errors.Is(err, ErrMappingFailed))
+	})
+
+	t.Run("rejects unknown key columns", func(t *testing.T) {
+		_, _, err := BuildUpdate("accounts", &row{}, "uuid")
+		assert.True(t, errors.Is(err, ErrMappingFailed))
+	})
+}
+
+func TestNamed(t *testing.T) {
+	t.Run("binds map values", func(t *testing.T) {
+		query, args, err := Named(
+			"SELECT * FROM users WHERE email = :email AND role = :role",
+			map[string]any{"email": "ana@example.com", "role": "admin"},
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, "SELECT * FROM users WHERE email = $1 AND role = $2", query)
+		assert.Equal(t, []any{"ana@example.com", "admin"}, args)
+	})
+
+	t.Run("binds struct fields by db tag", func(t *testing.T) {
+		type filter struct {
+			Name  string `db:"name"`
+			Limit int    `db:"limit"`
+		}
+
+		query, args, err := Named("SELECT id FROM users WHERE name = :name LIMIT :limit", &filter{Name: "Ana", Limit: 10})
+		require.NoError(t, err)
+
+		assert.Equal(t, "SELECT id FROM users WHERE name = $1 LIMIT $2", query)
+		assert.Equal(t, []any{"Ana", 10}, args)
+	})
+
+	t.Run("reuses position for repeated names", func(t *testing.T) {
+		query, args, err := Named(
+			"SELECT id FROM users WHERE name = :term OR email = :term",
+			map[string]any{"term": "ana"},
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, "SELECT id FROM users WHERE name = $1 OR email = $1", query)
+		assert.Equal(t, []any{"ana"}, args)
+	})
+
+	t.Run("keeps casts and quoted literals", func(t *testing.T) {
+		query, args, err := Named(
+			"SELECT :id::uuid, 'a:b', created_at::date FROM users",
+			map[string]any{"id": "123"},
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, "SELECT $1::uuid, 'a:b', created_at::date FROM users", query)
+		assert.Equal(t, []any{"123"}, args)
+	})
+
+	t.Run("fails on missing parameter", func(t *testing.T) {
+		_, _, err := Named("SELECT * FROM users WHERE id = :id", map[string]any{})
+		assert.True(t, errors.Is(err, ErrNamedParameter))
+	})
+
+	t.Run("fails on unsupported argument", func(t *testing.T) {
+		_, _, err := Named("SELECT :id", 42)
+		assert.True(t, errors.Is(err, ErrNamedParameter))
+	})
+}
+
+func TestIsNoRows(t *testing.T) {
+	assert.True(t, IsNoRows(ErrNoRows))
+	assert.False(t, IsNoRows(errors.New("boom")))
+}
+
+// singleRowConn is a driver connection whose queries all return one row
+// with a text value per column
+type singleRowConn struct {
+	columns []string
+	values  []driver.Value
+}
+
+func (c *singleRowConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (c *singleRowConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (c *singleRowConn) Close() error {
+	return nil
+}
+
+func (c *singleRowConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &singleRow{conn: c}, nil
+}
+
+type singleRow struct {
+	conn *singleRowConn
+	read bool
+}
+
+func (r *singleRow) Columns() []string {
+	return r.conn.columns
+}
+
+func (r *singleRow) Close() error {
+	return nil
+}
+
+func (r *singleRow) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	copy(dest, r.conn.values)
+	return nil
+}
+
+type singleRowConnector struct {
+	conn *singleRowConn
+}
+
+func (c singleRowConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.conn, nil
+}
+
+func (c singleRowConnector) Driver() driver.Driver { return nil }
+
+func TestGet_ScanError(t *testing.T) {
+	db := sql.OpenDB(singleRowConnector{conn: &singleRowConn{
+		columns: []string{"audit_version"},
+		values:  []driver.Value{"not a version"},
+	}})
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err := Get[mapperAccount](context.Background(), db, "SELECT audit_version FROM accounts")
+
+	require.ErrorIs(t, err, ErrScanFailed)
+	assert.Contains(t, err.Error(), `name "audit_version"`, "expected the scan error to be wrapped")
+}
@@ -5,7 +5,13 @@
 // - Connection pooling com configurações otimizadas
 // - Health checks (manual e background)
 // - Tratamento de erros estruturado com fault
+// - Tradução de erros do PostgreSQL (SQLSTATE) para códigos fault
 // - Timeouts configuráveis para todas as operações
+// - LISTEN/NOTIFY com conexão dedicada e reconexão automática
+// - Advisory locks e eleição de líder entre réplicas
+// - Mapeamento de structs por tags db (Insert, Update, Get[T], Select[T] e parâmetros nomeados)
+// - Isolamento por tenant: app.tenant_id definido em cada transação para as políticas RLS
+// - Spans OpenTelemetry em Exec, Query e transações (ver pkg/tracing)
 package database
 
 import (
@@ -16,8 +22,11 @@ import (
 
 	"github.com/marcelofabianov/course/config"
 	"github.com/marcelofabianov/course/pkg/retry"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/tracing"
 	"github.com/marcelofabianov/fault"
 
+	"github.com/jackc/pgx/v5"
 	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL driver
 )
 
@@ -81,8 +90,18 @@ var (
 		"failed to begin transaction",
 		fault.WithCode(fault.Internal),
 	)
+
+	// ErrDedicatedConnFailed is returned when a dedicated connection cannot be opened
+	ErrDedicatedConnFailed = fault.New(
+		"failed to open dedicated connection",
+		fault.WithCode(fault.InfraError),
+	)
 )
 
+// setTenantQuery scopes app.tenant_id to the current transaction, like SET LOCAL.
+// Row-level security policies read it through current_setting('app.tenant_id').
+const setTenantQuery = "SELECT set_config('app.tenant_id', $1, true)"
+
 // DB wraps sql.DB with additional functionality
 type DB struct {
 	conn   *sql.DB
@@ -302,10 +321,12 @@ func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}
 		return nil, ErrNotConnected
 	}
 
+	ctx, span := db.startSpan(ctx, queryOperation(query), query)
 	execCtx, cancel := context.WithTimeout(ctx, db.config.Database.Connect.ExecTimeout)
 	defer cancel()
 
 	result, err := db.conn.ExecContext(execCtx, query, args...)
+	tracing.End(span, err)
 	if err != nil {
 		db.logger.Error("Query execution failed",
 			"query", query,
@@ -322,16 +343,20 @@ func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}
 	return result, nil
 }
 
-// QueryContext executes a query that returns rows with timeout
+// QueryContext executes a query that returns rows with timeout. Its span
+// ends once the query returns its first result, so it does not cover the
+// time spent reading the rows: *sql.Rows gives no hook to end it on Close.
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	if db.conn == nil {
 		return nil, ErrNotConnected
 	}
 
+	ctx, span := db.startSpan(ctx, queryOperation(query), query)
 	queryCtx, cancel := context.WithTimeout(ctx, db.config.Database.Connect.QueryTimeout)
 	defer cancel()
 
 	rows, err := db.conn.QueryContext(queryCtx, query, args...)
+	tracing.End(span, err)
 	if err != nil {
 		db.logger.Error("Query failed",
 			"query", query,
@@ -348,25 +373,32 @@ func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{
 	return rows, nil
 }
 
-// QueryRowContext executes a query that returns at most one row with timeout
+// QueryRowContext executes a query that returns at most one row with
+// timeout. Like QueryContext, its span does not cover the Scan of the row.
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	if db.conn == nil {
 		return nil
 	}
 
+	ctx, span := db.startSpan(ctx, queryOperation(query), query)
 	queryCtx, cancel := context.WithTimeout(ctx, db.config.Database.Connect.QueryTimeout)
 	defer cancel()
 
-	return db.conn.QueryRowContext(queryCtx, query, args...)
+	row := db.conn.QueryRowContext(queryCtx, query, args...)
+	tracing.End(span, row.Err())
+	return row
 }
 
 // BeginTx starts a transaction with the given options
-func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (tx *sql.Tx, err error) {
 	if db.conn == nil {
 		return nil, ErrNotConnected
 	}
 
-	tx, err := db.conn.BeginTx(ctx, opts)
+	spanCtx, span := db.startSpan(ctx, "BEGIN", "")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err = db.conn.BeginTx(spanCtx, opts)
 	if err != nil {
 		db.logger.Error("Failed to begin transaction", "error", err.Error())
 		return nil, fault.Wrap(ErrTransactionFailed, "begin transaction failed",
@@ -374,9 +406,93 @@ func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 		)
 	}
 
+	if id, ok := db.transactionTenant(ctx); ok {
+		if _, err := tx.ExecContext(ctx, setTenantQuery, id.String()); err != nil {
+			_ = tx.Rollback()
+			db.logger.Error("Failed to set transaction tenant", "tenant_id", id.String(), "error", err.Error())
+			return nil, fault.Wrap(ErrTransactionFailed, "set tenant failed",
+				fault.WithWrappedErr(err),
+				fault.WithContext("tenant_id", id.String()),
+			)
+		}
+	}
+
 	return tx, nil
 }
 
+// transactionTenant returns the tenant of ctx. With multi-tenancy disabled
+// no request carries a tenant, so the default tenant is used: the RLS
+// policies on tenant tables would otherwise hide every row and reject
+// every insert.
+func (db *DB) transactionTenant(ctx context.Context) (tenant.ID, bool) {
+	if id, ok := tenant.FromContext(ctx); ok {
+		return id, true
+	}
+
+	if db.config.Tenant.Enabled {
+		return "", false
+	}
+
+	id, err := tenant.Parse(db.config.Tenant.DefaultID)
+	if err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// WithTx runs fn inside a transaction, committing when fn succeeds and
+// rolling back otherwise. Errors returned by fn are passed through unchanged.
+func (db *DB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	ctx, span := tracing.Start(ctx, "transaction")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fault.Wrap(ErrTransactionFailed, "commit failed",
+			fault.WithWrappedErr(err),
+		)
+	}
+
+	return nil
+}
+
+// DedicatedConn opens a new connection outside of the pool.
+// It is meant for long-lived sessions such as LISTEN/NOTIFY or session-level
+// advisory locks. The caller owns the connection and must close it.
+func (db *DB) DedicatedConn(ctx context.Context) (*pgx.Conn, error) {
+	if db.conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, db.config.Database.Connect.QueryTimeout)
+	defer cancel()
+
+	conn, err := pgx.Connect(connectCtx, db.config.GetDatabaseDSN())
+	if err != nil {
+		return nil, fault.Wrap(ErrDedicatedConnFailed, "pgx.Connect failed",
+			fault.WithWrappedErr(err),
+			fault.WithContext("host", db.config.Database.Credentials.Host),
+		)
+	}
+
+	return conn, nil
+}
+
+// Notify sends a notification with the given payload on a channel
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	_, err := db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
+
 // StartHealthCheckRoutine starts a background goroutine that performs periodic health checks
 func (db *DB) StartHealthCheckRoutine(ctx context.Context) {
 	if db.conn == nil {
@@ -5,10 +5,13 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -124,3 +127,46 @@ func TestDB_Connect_Integration(t *testing.T) {
 		assert.GreaterOrEqual(t, stats.OpenConnections, 0)
 	})
 }
+
+func TestDB_TenantTransaction_Integration(t *testing.T) {
+	db := connectIntegrationDB(t)
+	tenantID := tenant.ID("6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a")
+
+	t.Run("sets app.tenant_id for the transaction only", func(t *testing.T) {
+		ctx := tenant.WithID(context.Background(), tenantID)
+
+		err := db.WithTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+			var current string
+			require.NoError(t, tx.QueryRowContext(ctx, "SELECT current_setting('app.tenant_id', true)").Scan(&current))
+			assert.Equal(t, tenantID.String(), current)
+			return nil
+		})
+		require.NoError(t, err)
+
+		var outside sql.NullString
+		require.NoError(t, db.DB().QueryRowContext(context.Background(),
+			"SELECT NULLIF(current_setting('app.tenant_id', true), '')").Scan(&outside))
+		assert.False(t, outside.Valid)
+	})
+
+	t.Run("uses the default tenant when tenancy is disabled", func(t *testing.T) {
+		require.False(t, db.config.Tenant.Enabled)
+
+		err := db.WithTx(context.Background(), nil, func(ctx context.Context, tx *sql.Tx) error {
+			var current string
+			require.NoError(t, tx.QueryRowContext(ctx, "SELECT current_setting('app.tenant_id', true)").Scan(&current))
+			assert.Equal(t, db.config.Tenant.DefaultID, current)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("rolls back when fn fails", func(t *testing.T) {
+		errBoom := errors.New("boom")
+
+		err := db.WithTx(context.Background(), nil, func(context.Context, *sql.Tx) error {
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+	})
+}
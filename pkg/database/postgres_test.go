@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -194,3 +195,39 @@ func TestDB_IsConnected(t *testing.T) {
 		assert.True(t, db.IsConnected())
 	})
 }
+
+func TestDB_transactionTenant(t *testing.T) {
+	newDB := func(enabled bool, defaultID string) *DB {
+		cfg, err := config.Load()
+		require.NoError(t, err)
+		cfg.Tenant.Enabled = enabled
+		cfg.Tenant.DefaultID = defaultID
+
+		db, err := New(cfg)
+		require.NoError(t, err)
+		return db
+	}
+
+	const defaultID = "00000000-0000-0000-0000-000000000000"
+	requestID := tenant.ID("6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a")
+
+	t.Run("uses the tenant of the context", func(t *testing.T) {
+		id, ok := newDB(true, defaultID).transactionTenant(tenant.WithID(context.Background(), requestID))
+
+		assert.True(t, ok)
+		assert.Equal(t, requestID, id)
+	})
+
+	t.Run("uses the default tenant when tenancy is disabled", func(t *testing.T) {
+		id, ok := newDB(false, defaultID).transactionTenant(context.Background())
+
+		assert.True(t, ok)
+		assert.Equal(t, tenant.ID(defaultID), id)
+	})
+
+	t.Run("leaves the tenant unset when tenancy is enabled", func(t *testing.T) {
+		_, ok := newDB(true, defaultID).transactionTenant(context.Background())
+
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+// startSpan starts a client span for a statement, named after its operation
+// (SELECT, INSERT...). The query text is recorded as is: statements use
+// placeholders, so it carries no values.
+func (db *DB) startSpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBNamespace(db.config.Database.Credentials.Name),
+		semconv.DBOperationName(operation),
+		semconv.ServerAddress(db.config.Database.Credentials.Host),
+		semconv.ServerPort(db.config.Database.Credentials.Port),
+	}
+	if query != "" {
+		attrs = append(attrs, semconv.DBQueryText(query))
+	}
+
+	return tracing.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// queryOperation returns the first keyword of query, e.g. "SELECT"
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,22 @@
+package i18n
+
+import "context"
+
+type contextKey struct{}
+
+// WithLocalizer returns a context carrying l
+func WithLocalizer(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the localizer of the request, or nil, which keeps
+// messages in English
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(contextKey{}).(*Localizer)
+	return l
+}
+
+// Locale returns the locale of the request
+func Locale(ctx context.Context) string {
+	return FromContext(ctx).Locale()
+}
@@ -0,0 +1,231 @@
+// Package i18n traduz as mensagens da aplicação para os idiomas suportados
+// (pt-BR e en) e carrega o idioma de cada requisição no contexto.
+//
+// As mensagens de erro são traduzidas a partir do erro sentinela, e as
+// demais a partir de chaves com parâmetros no formato "{0}". O inglês é o
+// idioma de origem: sem tradução, a mensagem original é mantida.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/pt_BR"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// Supported locales, as BCP 47 tags
+const (
+	LocaleEN   = "en"
+	LocalePtBR = "pt-BR"
+)
+
+// Catalog holds messages by locale and key
+type Catalog map[string]map[string]string
+
+// ErrorCatalog holds the messages of sentinel errors by locale. English is
+// the error text itself, so it only needs an entry to change it.
+type ErrorCatalog map[error]map[string]string
+
+// Bundle holds the translations of every supported locale
+type Bundle struct {
+	uni           *ut.UniversalTranslator
+	defaultLocale string
+
+	mu     sync.RWMutex
+	errors []errorMessages
+}
+
+type errorMessages struct {
+	err      error
+	messages map[string]string
+}
+
+// NewBundle creates a bundle with the built-in messages, using
+// defaultLocale when a request asks for no supported locale
+func NewBundle(defaultLocale string) (*Bundle, error) {
+	b := &Bundle{
+		uni: ut.New(en.New(), en.New(), pt_BR.New()),
+	}
+
+	locale, ok := b.find(defaultLocale)
+	if !ok {
+		return nil, fmt.Errorf("unsupported default locale %q", defaultLocale)
+	}
+	b.defaultLocale = locale
+
+	if err := b.AddCatalog(codeMessages); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Supported returns the supported locales
+func (b *Bundle) Supported() []string {
+	return []string{LocaleEN, LocalePtBR}
+}
+
+// DefaultLocale returns the locale used when none matches
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// Translator returns the universal-translator of locale, to register and
+// render validator messages
+func (b *Bundle) Translator(locale string) ut.Translator {
+	trans, _ := b.uni.GetTranslator(translatorLocale(locale))
+	return trans
+}
+
+// AddCatalog adds messages, replacing existing keys
+func (b *Bundle) AddCatalog(catalog Catalog) error {
+	for locale, messages := range catalog {
+		if _, ok := b.find(locale); !ok {
+			return fmt.Errorf("unsupported locale %q", locale)
+		}
+
+		trans := b.Translator(locale)
+		for key, text := range messages {
+			if err := trans.Add(key, text, true); err != nil {
+				return fmt.Errorf("invalid message %q for %s: %w", key, locale, err)
+			}
+		}
+	}
+	return nil
+}
+
+// AddErrors adds the messages of sentinel errors
+func (b *Bundle) AddErrors(catalog ErrorCatalog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for err, messages := range catalog {
+		b.errors = append(b.errors, errorMessages{err: err, messages: messages})
+	}
+}
+
+// Match returns the supported locale that best fits locales, in order of
+// preference. A locale matches exactly or by language, so "pt" and
+// "pt-PT" match "pt-BR" and any English variant matches "en". It returns
+// "" when none fits.
+func (b *Bundle) Match(locales ...string) string {
+	for _, locale := range locales {
+		if match, ok := b.find(locale); ok {
+			return match
+		}
+
+		base, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+		for _, supported := range b.Supported() {
+			if supportedBase, _, _ := strings.Cut(supported, "-"); strings.EqualFold(base, supportedBase) {
+				return supported
+			}
+		}
+	}
+	return ""
+}
+
+// Localizer returns the localizer of locale, or of the default locale when
+// locale is not supported
+func (b *Bundle) Localizer(locale string) *Localizer {
+	match, ok := b.find(locale)
+	if !ok {
+		match = b.defaultLocale
+	}
+	return &Localizer{bundle: b, locale: match, trans: b.Translator(match)}
+}
+
+func (b *Bundle) find(locale string) (string, bool) {
+	for _, supported := range b.Supported() {
+		if strings.EqualFold(strings.ReplaceAll(locale, "_", "-"), supported) {
+			return supported, true
+		}
+	}
+	return "", false
+}
+
+func (b *Bundle) errorMessage(err error, locale string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, entry := range b.errors {
+		if errors.Is(err, entry.err) {
+			message, ok := entry.messages[locale]
+			if !ok && locale == LocaleEN {
+				return entry.err.Error(), true
+			}
+			return message, ok
+		}
+	}
+	return "", false
+}
+
+// translatorLocale converts a BCP 47 tag to the locale name of the
+// locales package, e.g. "pt-BR" to "pt_BR"
+func translatorLocale(locale string) string {
+	return strings.ReplaceAll(locale, "-", "_")
+}
+
+// Localizer translates messages to one locale
+type Localizer struct {
+	bundle *Bundle
+	locale string
+	trans  ut.Translator
+}
+
+// Locale returns the locale of l, or English for a nil localizer
+func (l *Localizer) Locale() string {
+	if l == nil {
+		return LocaleEN
+	}
+	return l.locale
+}
+
+// Translator returns the universal-translator of the locale
+func (l *Localizer) Translator() ut.Translator {
+	if l == nil {
+		return nil
+	}
+	return l.trans
+}
+
+// Message translates key with params, returning fallback when the locale
+// has no such message or it expects more params
+func (l *Localizer) Message(key, fallback string, params ...string) (message string) {
+	if l == nil {
+		return fallback
+	}
+
+	// universal-translator indexes params without checking their count
+	defer func() {
+		if recover() != nil {
+			message = fallback
+		}
+	}()
+
+	message, err := l.trans.T(key, params...)
+	if err != nil || message == "" {
+		return fallback
+	}
+	return message
+}
+
+// Error translates the message of err. Errors without a translation keep
+// message in English and get the generic message of their fault code in
+// other locales, which is better than English in a localized interface.
+func (l *Localizer) Error(err error, code, message string) string {
+	if l == nil {
+		return message
+	}
+
+	if translated, ok := l.bundle.errorMessage(err, l.locale); ok {
+		return translated
+	}
+	if l.locale == LocaleEN {
+		return message
+	}
+	return l.Message("code."+code, message)
+}
@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/marcelofabianov/fault"
+)
+
+func newTestBundle(t *testing.T) *Bundle {
+	t.Helper()
+
+	bundle, err := NewBundle(LocaleEN)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	return bundle
+}
+
+func TestNewBundle_UnsupportedDefault(t *testing.T) {
+	if _, err := NewBundle("fr"); err == nil {
+		t.Error("expected an error for an unsupported default locale")
+	}
+}
+
+func TestBundle_Match(t *testing.T) {
+	bundle := newTestBundle(t)
+
+	tests := []struct {
+		locales  []string
+		expected string
+	}{
+		{[]string{"pt-BR"}, LocalePtBR},
+		{[]string{"pt_br"}, LocalePtBR},
+		{[]string{"pt-PT", "en"}, LocalePtBR},
+		{[]string{"en-US"}, LocaleEN},
+		{[]string{"fr", "pt"}, LocalePtBR},
+		{[]string{"fr"}, ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := bundle.Match(tt.locales...); got != tt.expected {
+			t.Errorf("Match(%v) = %q, expected %q", tt.locales, got, tt.expected)
+		}
+	}
+}
+
+func TestLocalizer_Error(t *testing.T) {
+	errNotFound := errors.New("user not found")
+	bundle := newTestBundle(t)
+	bundle.AddErrors(ErrorCatalog{errNotFound: {LocalePtBR: "usuário não encontrado"}})
+
+	wrapped := fault.Wrap(errNotFound, "user not found", fault.WithCode(fault.NotFound))
+	unknown := fault.New("request timeout", fault.WithCode(fault.Internal))
+
+	ptBR := bundle.Localizer(LocalePtBR)
+	if got := ptBR.Error(wrapped, "not_found", "user not found"); got != "usuário não encontrado" {
+		t.Errorf("expected the sentinel translation, got %q", got)
+	}
+	if got := ptBR.Error(unknown, "internal_error", "request timeout"); got != "ocorreu um erro interno inesperado" {
+		t.Errorf("expected the generic message of the code, got %q", got)
+	}
+
+	en := bundle.Localizer(LocaleEN)
+	if got := en.Error(unknown, "internal_error", "request timeout"); got != "request timeout" {
+		t.Errorf("expected the original message in English, got %q", got)
+	}
+
+	var none *Localizer
+	if got := none.Error(wrapped, "not_found", "user not found"); got != "user not found" {
+		t.Errorf("expected a nil localizer to keep the message, got %q", got)
+	}
+}
+
+func TestLocalizer_Message(t *testing.T) {
+	bundle := newTestBundle(t)
+	if err := bundle.AddCatalog(Catalog{LocalePtBR: {"greeting": "olá, {0}"}}); err != nil {
+		t.Fatalf("failed to add catalog: %v", err)
+	}
+
+	l := bundle.Localizer(LocalePtBR)
+	if got := l.Message("greeting", "hello", "Ana"); got != "olá, Ana" {
+		t.Errorf("expected the translated message, got %q", got)
+	}
+	if got := l.Message("greeting", "hello"); got != "hello" {
+		t.Errorf("expected the fallback when params are missing, got %q", got)
+	}
+	if got := l.Message("missing", "fallback"); got != "fallback" {
+		t.Errorf("expected the fallback for unknown keys, got %q", got)
+	}
+
+	if err := bundle.AddCatalog(Catalog{"fr": {"greeting": "salut"}}); err == nil {
+		t.Error("expected an error for an unsupported locale")
+	}
+}
+
+func TestContext(t *testing.T) {
+	bundle := newTestBundle(t)
+
+	if locale := Locale(context.Background()); locale != LocaleEN {
+		t.Errorf("expected English without a localizer, got %s", locale)
+	}
+
+	ctx := WithLocalizer(context.Background(), bundle.Localizer("pt-br"))
+	if locale := Locale(ctx); locale != LocalePtBR {
+		t.Errorf("expected pt-BR, got %s", locale)
+	}
+}
@@ -0,0 +1,17 @@
+package i18n
+
+// codeMessages are the generic messages of fault codes, used for errors
+// without a translation of their own
+var codeMessages = Catalog{
+	LocalePtBR: {
+		"code.invalid_input":       "dados inválidos",
+		"code.not_found":           "recurso não encontrado",
+		"code.conflict":            "conflito com o estado atual do recurso",
+		"code.unauthorized":        "não autenticado",
+		"code.forbidden":           "acesso negado",
+		"code.domain_violation":    "operação não permitida",
+		"code.infra_error":         "serviço indisponível no momento",
+		"code.service_unavailable": "serviço sobrecarregado, tente novamente em instantes",
+		"code.internal_error":      "ocorreu um erro interno inesperado",
+	},
+}
@@ -2,6 +2,8 @@ package logger
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
@@ -32,11 +34,15 @@ type Config struct {
 	Environment string
 	AddSource   bool
 	TimeFormat  string
+	Redaction   *RedactionConfig // nil logs the attributes as they are
+	Sampling    *SamplingConfig  // nil logs every record
 }
 
 type Logger struct {
 	logger      *slog.Logger
 	config      *Config
+	level       *slog.LevelVar // shared by the loggers derived with With and WithGroup
+	sampling    *samplingState // nil without sampling
 	serviceName string
 	environment string
 }
@@ -59,7 +65,8 @@ func New(cfg *Config) *Logger {
 		cfg.Environment = "development"
 	}
 
-	level := parseLogLevel(cfg.Level)
+	level := new(slog.LevelVar)
+	level.Set(parseLogLevel(cfg.Level))
 
 	handlerOpts := &slog.HandlerOptions{
 		Level:     level,
@@ -84,7 +91,20 @@ func New(cfg *Config) *Logger {
 		handler = slog.NewJSONHandler(cfg.Output, handlerOpts)
 	}
 
-	baseLogger := slog.New(handler)
+	if cfg.Redaction != nil {
+		handler = NewRedactHandler(handler, *cfg.Redaction)
+	}
+
+	var sampling *samplingState
+	if cfg.Sampling != nil {
+		sampling = newSamplingState(*cfg.Sampling, handler.WithAttrs([]slog.Attr{
+			slog.String("service", cfg.ServiceName),
+			slog.String("environment", cfg.Environment),
+		}))
+		handler = &samplingHandler{handler: handler, state: sampling}
+	}
+
+	baseLogger := slog.New(&levelHandler{handler: handler})
 	baseLogger = baseLogger.With(
 		slog.String("service", cfg.ServiceName),
 		slog.String("environment", cfg.Environment),
@@ -93,6 +113,8 @@ func New(cfg *Config) *Logger {
 	return &Logger{
 		logger:      baseLogger,
 		config:      cfg,
+		level:       level,
+		sampling:    sampling,
 		serviceName: cfg.ServiceName,
 		environment: cfg.Environment,
 	}
@@ -161,6 +183,8 @@ func (l *Logger) With(args ...any) *Logger {
 	return &Logger{
 		logger:      l.logger.With(args...),
 		config:      l.config,
+		level:       l.level,
+		sampling:    l.sampling,
 		serviceName: l.serviceName,
 		environment: l.environment,
 	}
@@ -170,6 +194,8 @@ func (l *Logger) WithGroup(name string) *Logger {
 	return &Logger{
 		logger:      l.logger.WithGroup(name),
 		config:      l.config,
+		level:       l.level,
+		sampling:    l.sampling,
 		serviceName: l.serviceName,
 		environment: l.environment,
 	}
@@ -195,6 +221,51 @@ func (l *Logger) Environment() string {
 	return l.environment
 }
 
+// ErrLevelNotAdjustable is returned by SetLevel on loggers built by
+// NewFromSlog, whose handler owns the level
+var ErrLevelNotAdjustable = errors.New("logger level is not adjustable")
+
+// Level returns the current minimum level
+func (l *Logger) Level() LogLevel {
+	for _, level := range []LogLevel{LevelDebug, LevelInfo, LevelWarn} {
+		if l.Enabled(context.Background(), level) {
+			return level
+		}
+	}
+	return LevelError
+}
+
+// SetLevel changes the minimum level at runtime, for this logger and every
+// logger derived from the same New call
+func (l *Logger) SetLevel(level LogLevel) error {
+	if !level.IsValid() {
+		return fmt.Errorf("invalid log level: %q", level)
+	}
+	if l.level == nil {
+		return ErrLevelNotAdjustable
+	}
+	l.level.Set(parseLogLevel(level))
+	return nil
+}
+
+// SamplingStats returns the records dropped by sampling so far
+func (l *Logger) SamplingStats() SamplingStats {
+	if l.sampling == nil {
+		return SamplingStats{}
+	}
+	return SamplingStats{Dropped: l.sampling.dropped.Load()}
+}
+
+// IsValid reports whether level is one of the supported levels
+func (level LogLevel) IsValid() bool {
+	switch level {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+		return true
+	default:
+		return false
+	}
+}
+
 func (l *Logger) Enabled(ctx context.Context, level LogLevel) bool {
 	slogLevel := parseLogLevel(level)
 	return l.logger.Enabled(ctx, slogLevel)
@@ -310,6 +310,37 @@ func TestLogLevelFiltering(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Config{
+		Level:  LevelInfo,
+		Format: FormatJSON,
+		Output: &buf,
+	})
+	requestLogger := logger.With("request_id", "req-123")
+
+	requestLogger.Debug("hidden")
+	assert.Empty(t, buf.String())
+	assert.Equal(t, LevelInfo, logger.Level())
+
+	require.NoError(t, logger.SetLevel(LevelDebug))
+	assert.Equal(t, LevelDebug, logger.Level())
+	assert.Equal(t, LevelDebug, requestLogger.Level())
+
+	requestLogger.Debug("shown")
+	assert.Contains(t, buf.String(), "shown")
+
+	require.NoError(t, requestLogger.SetLevel(LevelError))
+	assert.Equal(t, LevelError, logger.Level())
+
+	assert.Error(t, logger.SetLevel("verbose"))
+	assert.Equal(t, LevelError, logger.Level())
+
+	external := NewFromSlog(slog.New(slog.NewJSONHandler(&buf, nil)), "svc", "test")
+	assert.ErrorIs(t, external.SetLevel(LevelDebug), ErrLevelNotAdjustable)
+	assert.Equal(t, LevelInfo, external.Level())
+}
+
 func TestEnabled(t *testing.T) {
 	cfg := &Config{
 		Level:       LevelInfo,
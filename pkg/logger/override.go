@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type levelOverrideKey struct{}
+
+// WithLevelOverride returns a context whose records are logged from level
+// up, even below the logger level, by every logger built by New or derived
+// with WithLevel. Meant to debug a single request without raising the
+// level of the whole application.
+func WithLevelOverride(ctx context.Context, level LogLevel) context.Context {
+	return context.WithValue(ctx, levelOverrideKey{}, parseLogLevel(level))
+}
+
+// LevelOverride returns the override set by WithLevelOverride, if any
+func LevelOverride(ctx context.Context) (LogLevel, bool) {
+	level, ok := ctx.Value(levelOverrideKey{}).(slog.Level)
+	if !ok {
+		return "", false
+	}
+	return levelName(level), true
+}
+
+// WithLevel returns a logger that also logs from level up, whatever the
+// level of l. Calls without a context, such as Debug, are covered too,
+// which WithLevelOverride cannot do.
+func (l *Logger) WithLevel(level LogLevel) *Logger {
+	override := parseLogLevel(level)
+	return &Logger{
+		logger:      slog.New(&levelHandler{handler: l.logger.Handler(), override: &override}),
+		config:      l.config,
+		level:       l.level,
+		sampling:    l.sampling,
+		serviceName: l.serviceName,
+		environment: l.environment,
+	}
+}
+
+// levelHandler enables the records of handler, which filters by the logger
+// level, and those allowed by an override of the logger or the context. The
+// wrapped handler only filters in Enabled, so Handle writes them as is.
+type levelHandler struct {
+	handler  slog.Handler
+	override *slog.Level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.override != nil && level >= *h.override {
+		return true
+	}
+	if override, ok := ctx.Value(levelOverrideKey{}).(slog.Level); ok && level >= override {
+		return true
+	}
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{handler: h.handler.WithAttrs(attrs), override: h.override}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{handler: h.handler.WithGroup(name), override: h.override}
+}
+
+func levelName(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLevelOverride(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Config{
+		Level:  LevelWarn,
+		Format: FormatJSON,
+		Output: &buf,
+	})
+
+	ctx := context.Background()
+	logger.DebugContext(ctx, "hidden")
+	assert.Empty(t, buf.String())
+
+	_, ok := LevelOverride(ctx)
+	assert.False(t, ok)
+
+	debugCtx := WithLevelOverride(ctx, LevelDebug)
+	level, ok := LevelOverride(debugCtx)
+	assert.True(t, ok)
+	assert.Equal(t, LevelDebug, level)
+
+	logger.With("request_id", "req-123").DebugContext(debugCtx, "shown")
+	logger.Slog().InfoContext(debugCtx, "shown through slog")
+	assert.Contains(t, buf.String(), `"msg":"shown"`)
+	assert.Contains(t, buf.String(), "req-123")
+	assert.Contains(t, buf.String(), "shown through slog")
+
+	buf.Reset()
+	logger.InfoContext(ctx, "hidden again")
+	assert.Empty(t, buf.String())
+	assert.Equal(t, LevelWarn, logger.Level())
+}
+
+func TestWithLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Config{
+		Level:  LevelWarn,
+		Format: FormatJSON,
+		Output: &buf,
+	})
+
+	debugLogger := logger.WithLevel(LevelDebug).With("request_id", "req-123")
+	debugLogger.Debug("shown")
+	assert.Contains(t, buf.String(), `"msg":"shown"`)
+	assert.Contains(t, buf.String(), "req-123")
+	assert.Equal(t, LevelDebug, debugLogger.Level())
+
+	buf.Reset()
+	logger.Info("hidden")
+	assert.Empty(t, buf.String())
+	assert.Equal(t, LevelWarn, logger.Level())
+
+	// The override holds whatever the global level
+	assert.NoError(t, debugLogger.SetLevel(LevelError))
+	assert.Equal(t, LevelError, logger.Level())
+	debugLogger.Debug("still shown")
+	assert.Contains(t, buf.String(), "still shown")
+}
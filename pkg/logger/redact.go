@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// redactedValue replaces the values of sensitive attributes
+const redactedValue = "[REDACTED]"
+
+// DefaultSensitiveKeys are the attribute keys always redacted, shared with
+// the sanitizing of pkg/validation
+var DefaultSensitiveKeys = []string{
+	"password", "senha", "token", "secret", "apikey", "api_key",
+	"credit_card", "card_number", "cvv", "pin", "private_key",
+	"authorization", "cookie",
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cpfPattern   = regexp.MustCompile(`\b\d{3}\.\d{3}\.\d{3}-\d{2}\b|\b\d{11}\b`)
+	// A bare run of digits is only taken for a phone with +55, the area code
+	// in parentheses or a separator, so timestamps and IDs are kept
+	phonePattern = regexp.MustCompile(`\+55\s?\(?\d{2}\)?\s?9?\d{4}-?\d{4}\b|\(\d{2}\)\s?9?\d{4}-?\d{4}\b|\b\d{2}\s9?\d{4}-?\d{4}\b|\b9?\d{4}-\d{4}\b`)
+)
+
+// RedactionConfig configures the handler of NewRedactHandler
+type RedactionConfig struct {
+	Keys []string // redacted in addition to DefaultSensitiveKeys
+}
+
+// redactHandler redacts the attributes whose key names a secret and masks
+// the emails, phones and CPFs in messages and text values, inside groups
+// and LogValuer values too.
+type redactHandler struct {
+	handler slog.Handler
+	keys    []string
+}
+
+// NewRedactHandler wraps handler with the redaction of sensitive data. A
+// key matches a sensitive key when equal to it or ending with it, ignoring
+// case and separators, or when starting with it followed by a separator:
+// "token" matches "access_token", "accessToken" and "token_type".
+func NewRedactHandler(handler slog.Handler, cfg RedactionConfig) slog.Handler {
+	keys := make([]string, 0, len(DefaultSensitiveKeys)+len(cfg.Keys))
+	for _, key := range slices.Concat(DefaultSensitiveKeys, cfg.Keys) {
+		if key = normalizeKey(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return &redactHandler{handler: handler, keys: keys}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, maskPatterns(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redactAttr(a)
+	}
+	return &redactHandler{handler: h.handler.WithAttrs(redacted), keys: h.keys}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{handler: h.handler.WithGroup(name), keys: h.keys}
+}
+
+func (h *redactHandler) redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	if h.isSensitive(a.Key) {
+		a.Value = slog.StringValue(redactedValue)
+		return a
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(maskPatterns(a.Value.String()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			redacted[i] = h.redactAttr(attr)
+		}
+		a.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		// Handlers print errors as their message, which can carry an email
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(maskPatterns(err.Error()))
+		}
+	}
+
+	return a
+}
+
+func (h *redactHandler) isSensitive(key string) bool {
+	key = normalizeKey(key)
+	for _, sensitive := range h.keys {
+		if strings.HasSuffix(key, sensitive) || strings.HasPrefix(key, sensitive+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeKey lowercases key and turns "-" and "." into "_", so
+// "X-Api-Key" matches "api_key"
+func normalizeKey(key string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(strings.TrimSpace(key)))
+}
+
+func maskPatterns(s string) string {
+	// Cheap check before the regular expressions: every pattern has a digit
+	// or an @
+	if !strings.ContainsAny(s, "@0123456789") {
+		return s
+	}
+
+	s = emailPattern.ReplaceAllStringFunc(s, maskEmail)
+	s = cpfPattern.ReplaceAllString(s, "***.***.***-**")
+	return maskPhones(s)
+}
+
+// maskPhones masks the phones not joined to other text by a "-", which
+// would make the digits part of an ID such as a UUID
+func maskPhones(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range phonePattern.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if (start > 0 && s[start-1] == '-') || (end < len(s) && s[end] == '-') {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(maskPhone(s[start:end]))
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// maskEmail keeps the first letter and the domain: j***@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	return email[:1] + "***" + email[at:]
+}
+
+// maskPhone keeps the last four digits: ****-4321
+func maskPhone(phone string) string {
+	digits := make([]byte, 0, len(phone))
+	for i := 0; i < len(phone); i++ {
+		if phone[i] >= '0' && phone[i] <= '9' {
+			digits = append(digits, phone[i])
+		}
+	}
+	return "****-" + string(digits[len(digits)-4:])
+}
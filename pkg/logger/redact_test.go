@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type credentials struct {
+	User     string
+	Password string
+}
+
+func (c credentials) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("user", c.User),
+		slog.String("password", c.Password),
+	)
+}
+
+func newRedactLogger(buf *bytes.Buffer, keys ...string) *Logger {
+	return New(&Config{
+		Level:     LevelDebug,
+		Format:    FormatJSON,
+		Output:    buf,
+		Redaction: &RedactionConfig{Keys: keys},
+	})
+}
+
+func decodeLog(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestRedactHandler_Keys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactLogger(&buf, "birth_date")
+
+	logger.Info("login",
+		"password", "hunter2",
+		"access_token", "eyJhbGciOi",
+		"refreshToken", "abc",
+		"Authorization", "Bearer eyJhbGciOi",
+		"X-Api-Key", "key-123",
+		"birth_date", "1990-01-01",
+		"user_id", "user-1",
+	)
+
+	entry := decodeLog(t, &buf)
+	for _, key := range []string{"password", "access_token", "refreshToken", "Authorization", "X-Api-Key", "birth_date"} {
+		assert.Equal(t, "[REDACTED]", entry[key], key)
+	}
+	assert.Equal(t, "user-1", entry["user_id"])
+}
+
+func TestRedactHandler_Patterns(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactLogger(&buf)
+
+	logger.Info("user john.doe@example.com registered",
+		"email", "john.doe@example.com",
+		"cpf", "123.456.789-09",
+		"document", "12345678909",
+		"phone", "(11) 98765-4321",
+		"mobile", "+55 11 987654321",
+		"request_id", "6f1c1d0e-6a56-4123-9162-0b8d1c4c3b7a",
+		"timestamp", "2026-10-18T15:49:06Z",
+		"error", errors.New("email john.doe@example.com already exists"),
+	)
+
+	entry := decodeLog(t, &buf)
+	assert.Equal(t, "user j***@example.com registered", entry["msg"])
+	assert.Equal(t, "j***@example.com", entry["email"])
+	assert.Equal(t, "***.***.***-**", entry["cpf"])
+	assert.Equal(t, "***.***.***-**", entry["document"])
+	assert.Equal(t, "****-4321", entry["phone"])
+	assert.Equal(t, "****-4321", entry["mobile"])
+	assert.Equal(t, "6f1c1d0e-6a56-4123-9162-0b8d1c4c3b7a", entry["request_id"])
+	assert.Equal(t, "2026-10-18T15:49:06Z", entry["timestamp"])
+	assert.Equal(t, "email j***@example.com already exists", entry["error"])
+}
+
+func TestRedactHandler_GroupsAndLogValuer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactLogger(&buf)
+
+	logger.WithGroup("request").With("cookie", "session=abc").Info("handled",
+		slog.Group("user", slog.String("email", "ana@example.com"), slog.String("senha", "segredo")),
+		"credentials", credentials{User: "ana", Password: "segredo"},
+	)
+
+	entry := decodeLog(t, &buf)
+	request := entry["request"].(map[string]any)
+	assert.Equal(t, "[REDACTED]", request["cookie"])
+
+	user := request["user"].(map[string]any)
+	assert.Equal(t, "a***@example.com", user["email"])
+	assert.Equal(t, "[REDACTED]", user["senha"])
+
+	creds := request["credentials"].(map[string]any)
+	assert.Equal(t, "ana", creds["user"])
+	assert.Equal(t, "[REDACTED]", creds["password"])
+}
+
+func TestRedactHandler_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Config{Format: FormatJSON, Output: &buf})
+
+	logger.Info("login", "password", "hunter2")
+
+	assert.Equal(t, "hunter2", decodeLog(t, &buf)["password"])
+}
@@ -0,0 +1,188 @@
+package logger
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// samplingCounters bounds the memory of the sampler; messages sharing a
+	// counter are sampled together
+	samplingCounters = 4096
+	// maxSeenErrors bounds the error messages remembered as already logged
+	maxSeenErrors = 4096
+)
+
+// SamplingConfig configures the sampling of repeated records: in each tick,
+// the first First records of a level and message are logged, then one in
+// every Thereafter. The first record of each error message, and the records
+// logged with an Unsampled context, are always logged.
+type SamplingConfig struct {
+	First      int
+	Thereafter int           // 0 drops the rest of the tick
+	Tick       time.Duration // defaults to one second
+}
+
+// SamplingStats counts the records dropped by sampling
+type SamplingStats struct {
+	Dropped uint64
+}
+
+type samplingCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// samplingState is shared by the handlers of a logger and those derived
+// from it, so a message is counted once whatever its attributes
+type samplingState struct {
+	first      uint64
+	thereafter uint64
+	tick       time.Duration
+	counters   [samplingCounters]samplingCounter
+
+	mu         sync.Mutex
+	seenErrors map[string]struct{}
+
+	dropped    atomic.Uint64
+	reported   atomic.Uint64
+	lastReport atomic.Int64
+
+	// report logs the dropped records without the attributes of the logger
+	// that happens to trigger it
+	report slog.Handler
+}
+
+func newSamplingState(cfg SamplingConfig, report slog.Handler) *samplingState {
+	tick := cfg.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return &samplingState{
+		first:      uint64(max(cfg.First, 0)),
+		thereafter: uint64(max(cfg.Thereafter, 0)),
+		tick:       tick,
+		seenErrors: make(map[string]struct{}),
+		report:     report,
+	}
+}
+
+// sample reports whether a record of level and message is logged
+func (s *samplingState) sample(now time.Time, level slog.Level, message string) bool {
+	if level >= slog.LevelError && s.firstError(message) {
+		return true
+	}
+
+	n := s.counter(level, message).incCheckReset(now, s.tick)
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true
+	}
+
+	s.dropped.Add(1)
+	return false
+}
+
+func (s *samplingState) counter(level slog.Level, message string) *samplingCounter {
+	hash := fnv.New32a()
+	hash.Write([]byte(level.String()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(message))
+	return &s.counters[hash.Sum32()%samplingCounters]
+}
+
+// firstError reports whether message is logged at error level for the
+// first time
+func (s *samplingState) firstError(message string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, seen := s.seenErrors[message]; seen || len(s.seenErrors) >= maxSeenErrors {
+		return false
+	}
+	s.seenErrors[message] = struct{}{}
+	return true
+}
+
+// takeDropped returns the records dropped since the last report, at most
+// once per tick
+func (s *samplingState) takeDropped(now time.Time) uint64 {
+	last := s.lastReport.Load()
+	if now.UnixNano()-last < s.tick.Nanoseconds() || !s.lastReport.CompareAndSwap(last, now.UnixNano()) {
+		return 0
+	}
+	dropped := s.dropped.Load()
+	return dropped - s.reported.Swap(dropped)
+}
+
+// incCheckReset counts a record in the current tick, starting a new tick
+// when the last one is over
+func (c *samplingCounter) incCheckReset(now time.Time, tick time.Duration) uint64 {
+	nanos := now.UnixNano()
+	resetAt := c.resetAt.Load()
+	if resetAt > nanos {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, nanos+tick.Nanoseconds()) {
+		// Another record started the tick
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+type unsampledKey struct{}
+
+// Unsampled marks ctx so the records logged with it are never dropped by
+// sampling: security events, and access lines whose sampling the access log
+// already decided
+func Unsampled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unsampledKey{}, true)
+}
+
+func isUnsampled(ctx context.Context) bool {
+	unsampled, _ := ctx.Value(unsampledKey{}).(bool)
+	return unsampled
+}
+
+// samplingHandler drops the records over the sampling rates and logs, at
+// most once per tick, how many were dropped
+type samplingHandler struct {
+	handler slog.Handler
+	state   *samplingState
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	now := record.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	if !isUnsampled(ctx) && !h.state.sample(now, record.Level, record.Message) {
+		return nil
+	}
+
+	if dropped := h.state.takeDropped(now); dropped > 0 {
+		report := slog.NewRecord(now, slog.LevelWarn, "log records dropped by sampling", 0)
+		report.AddAttrs(slog.Uint64("dropped", dropped))
+		_ = h.state.report.Handle(ctx, report)
+	}
+
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{handler: h.handler.WithAttrs(attrs), state: h.state}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{handler: h.handler.WithGroup(name), state: h.state}
+}
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSamplingLogger(buf *bytes.Buffer, cfg SamplingConfig) *Logger {
+	return New(&Config{
+		Level:    LevelDebug,
+		Format:   FormatJSON,
+		Output:   buf,
+		Sampling: &cfg,
+	})
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newSamplingLogger(&buf, SamplingConfig{First: 3, Thereafter: 10, Tick: time.Minute})
+
+	for i := range 25 {
+		// Attributes do not split a message
+		logger.With("request_id", i).Info("request completed")
+	}
+	logger.Info("other message")
+
+	// 3 first, then the 13th and the 23rd
+	assert.Equal(t, 5, strings.Count(buf.String(), `"msg":"request completed"`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"msg":"other message"`))
+	assert.Equal(t, uint64(20), logger.SamplingStats().Dropped)
+}
+
+func TestSampling_LevelsAreSeparate(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newSamplingLogger(&buf, SamplingConfig{First: 1, Tick: time.Minute})
+
+	logger.Info("cache miss")
+	logger.Info("cache miss")
+	logger.Warn("cache miss")
+
+	assert.Equal(t, 2, strings.Count(buf.String(), `"msg":"cache miss"`))
+	assert.Equal(t, uint64(1), logger.SamplingStats().Dropped)
+}
+
+func TestSampling_FirstErrorAlwaysKept(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newSamplingLogger(&buf, SamplingConfig{First: 0, Thereafter: 0, Tick: time.Minute})
+
+	logger.Info("dropped")
+	logger.Error("database unavailable")
+	logger.Error("database unavailable")
+	logger.Error("redis unavailable")
+
+	assert.NotContains(t, buf.String(), `"msg":"dropped"`)
+	assert.Equal(t, 1, strings.Count(buf.String(), `"msg":"database unavailable"`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"msg":"redis unavailable"`))
+	assert.Equal(t, uint64(2), logger.SamplingStats().Dropped)
+}
+
+func TestSampling_UnsampledContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newSamplingLogger(&buf, SamplingConfig{First: 1, Tick: time.Minute})
+	ctx := Unsampled(context.Background())
+
+	for range 5 {
+		logger.WarnContext(ctx, "security_event")
+		logger.Info("request completed")
+	}
+
+	assert.Equal(t, 5, strings.Count(buf.String(), `"msg":"security_event"`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"msg":"request completed"`))
+	assert.Equal(t, uint64(4), logger.SamplingStats().Dropped)
+}
+
+func TestSampling_Tick(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newSamplingLogger(&buf, SamplingConfig{First: 1, Tick: 50 * time.Millisecond})
+
+	logger.Info("heartbeat")
+	logger.Info("heartbeat")
+	time.Sleep(60 * time.Millisecond)
+	logger.Info("heartbeat")
+
+	assert.Equal(t, 2, strings.Count(buf.String(), `"msg":"heartbeat"`))
+	assert.Contains(t, buf.String(), `"msg":"log records dropped by sampling","service":"unknown-service","environment":"development","dropped":1`)
+}
+
+func TestSampling_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&Config{Format: FormatJSON, Output: &buf})
+
+	for range 10 {
+		logger.Info("request completed")
+	}
+
+	assert.Equal(t, 10, strings.Count(buf.String(), "request completed"))
+	assert.Equal(t, SamplingStats{}, logger.SamplingStats())
+}
+
+func TestSampling_Concurrency(t *testing.T) {
+	var buf syncBuffer
+	logger := New(&Config{
+		Format:   FormatJSON,
+		Output:   &buf,
+		Sampling: &SamplingConfig{First: 10, Thereafter: 100, Tick: time.Minute},
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			for range 100 {
+				logger.Info("request completed")
+			}
+		})
+	}
+	wg.Wait()
+
+	// 10 first, then the 110th, 210th... up to the 910th
+	assert.Equal(t, 19, strings.Count(buf.String(), "request completed"))
+	assert.Equal(t, uint64(981), logger.SamplingStats().Dropped)
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
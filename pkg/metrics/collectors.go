@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+// breakerStates are the states of gobreaker, exposed one-hot so a
+// dashboard can graph each
+var breakerStates = []string{"closed", "half-open", "open"}
+
+// statsCollector reads a stats snapshot once per scrape and turns it into
+// const metrics
+type statsCollector struct {
+	descs   []*prometheus.Desc
+	collect func(ch chan<- prometheus.Metric)
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch)
+}
+
+func newDesc(namespace, subsystem, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+}
+
+// NewDatabaseCollector exposes the connection pool stats, as returned by
+// database.DB.Stats
+func NewDatabaseCollector(namespace string, stats func() sql.DBStats) prometheus.Collector {
+	var (
+		maxOpen   = newDesc(namespace, "db", "max_open_connections", "Maximum number of open connections.")
+		open      = newDesc(namespace, "db", "open_connections", "Open connections, in use or idle.")
+		inUse     = newDesc(namespace, "db", "in_use_connections", "Connections in use.")
+		idle      = newDesc(namespace, "db", "idle_connections", "Idle connections.")
+		waits     = newDesc(namespace, "db", "wait_count_total", "Times a connection was waited for.")
+		waitTime  = newDesc(namespace, "db", "wait_duration_seconds_total", "Time spent waiting for a connection.")
+		idleClose = newDesc(namespace, "db", "max_idle_closed_total", "Connections closed by the idle limits.")
+		lifeClose = newDesc(namespace, "db", "max_lifetime_closed_total", "Connections closed by the lifetime limit.")
+	)
+
+	return &statsCollector{
+		descs: []*prometheus.Desc{maxOpen, open, inUse, idle, waits, waitTime, idleClose, lifeClose},
+		collect: func(ch chan<- prometheus.Metric) {
+			s := stats()
+			ch <- prometheus.MustNewConstMetric(maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+			ch <- prometheus.MustNewConstMetric(open, prometheus.GaugeValue, float64(s.OpenConnections))
+			ch <- prometheus.MustNewConstMetric(inUse, prometheus.GaugeValue, float64(s.InUse))
+			ch <- prometheus.MustNewConstMetric(idle, prometheus.GaugeValue, float64(s.Idle))
+			ch <- prometheus.MustNewConstMetric(waits, prometheus.CounterValue, float64(s.WaitCount))
+			ch <- prometheus.MustNewConstMetric(waitTime, prometheus.CounterValue, s.WaitDuration.Seconds())
+			ch <- prometheus.MustNewConstMetric(idleClose, prometheus.CounterValue, float64(s.MaxIdleClosed+s.MaxIdleTimeClosed))
+			ch <- prometheus.MustNewConstMetric(lifeClose, prometheus.CounterValue, float64(s.MaxLifetimeClosed))
+		},
+	}
+}
+
+// NewCacheCollector exposes the Redis pool stats, as returned by
+// cache.Cache.Stats
+func NewCacheCollector(namespace string, stats func() *redis.PoolStats) prometheus.Collector {
+	var (
+		hits     = newDesc(namespace, "redis", "pool_hits_total", "Times a free connection was found in the pool.")
+		misses   = newDesc(namespace, "redis", "pool_misses_total", "Times a free connection was not found in the pool.")
+		timeouts = newDesc(namespace, "redis", "pool_timeouts_total", "Times waiting for a connection timed out.")
+		total    = newDesc(namespace, "redis", "pool_connections", "Connections in the pool.")
+		idle     = newDesc(namespace, "redis", "pool_idle_connections", "Idle connections in the pool.")
+		stale    = newDesc(namespace, "redis", "pool_stale_connections_total", "Stale connections removed from the pool.")
+	)
+
+	return &statsCollector{
+		descs: []*prometheus.Desc{hits, misses, timeouts, total, idle, stale},
+		collect: func(ch chan<- prometheus.Metric) {
+			s := stats()
+			if s == nil {
+				s = &redis.PoolStats{}
+			}
+			ch <- prometheus.MustNewConstMetric(hits, prometheus.CounterValue, float64(s.Hits))
+			ch <- prometheus.MustNewConstMetric(misses, prometheus.CounterValue, float64(s.Misses))
+			ch <- prometheus.MustNewConstMetric(timeouts, prometheus.CounterValue, float64(s.Timeouts))
+			ch <- prometheus.MustNewConstMetric(total, prometheus.GaugeValue, float64(s.TotalConns))
+			ch <- prometheus.MustNewConstMetric(idle, prometheus.GaugeValue, float64(s.IdleConns))
+			ch <- prometheus.MustNewConstMetric(stale, prometheus.CounterValue, float64(s.StaleConns))
+		},
+	}
+}
+
+// NewRateLimitCollector exposes the rejections and the circuit breaker of a
+// rate limiter, as returned by middleware.RateLimiter.Stats
+func NewRateLimitCollector(namespace string, stats func() middleware.RateLimitStats) prometheus.Collector {
+	var (
+		rejected    = newDesc(namespace, "ratelimit", "rejected_total", "Requests rejected for exceeding a rate limit.")
+		fallback    = newDesc(namespace, "ratelimit", "fallback_requests_total", "Requests limited in memory while Redis was unavailable.")
+		state       = newDesc(namespace, "ratelimit", "circuit_breaker_state", "Current state of the Redis circuit breaker (1 for the current state).", "state")
+		transitions = newDesc(namespace, "ratelimit", "circuit_breaker_transitions_total", "State changes of the Redis circuit breaker.")
+	)
+
+	return &statsCollector{
+		descs: []*prometheus.Desc{rejected, fallback, state, transitions},
+		collect: func(ch chan<- prometheus.Metric) {
+			s := stats()
+			ch <- prometheus.MustNewConstMetric(rejected, prometheus.CounterValue, float64(s.Rejected))
+			ch <- prometheus.MustNewConstMetric(fallback, prometheus.CounterValue, float64(s.FallbackRequests))
+			ch <- prometheus.MustNewConstMetric(transitions, prometheus.CounterValue, float64(s.BreakerTransitions))
+			for _, name := range breakerStates {
+				value := 0.0
+				if name == s.BreakerState {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(state, prometheus.GaugeValue, value, name)
+			}
+		},
+	}
+}
+
+// NewLoadShedCollector exposes the adaptive limit of a load shedder, as
+// returned by middleware.LoadShedder.Stats
+func NewLoadShedCollector(namespace string, stats func() middleware.LoadShedStats) prometheus.Collector {
+	var (
+		limit    = newDesc(namespace, "loadshed", "limit", "Current in-flight request limit.")
+		inFlight = newDesc(namespace, "loadshed", "in_flight", "Requests admitted and in flight.")
+		shed     = newDesc(namespace, "loadshed", "shed_total", "Requests shed with 503.")
+		latency  = newDesc(namespace, "loadshed", "latency_seconds", "Smoothed request latency.")
+		baseline = newDesc(namespace, "loadshed", "baseline_latency_seconds", "No-load latency baseline.")
+	)
+
+	return &statsCollector{
+		descs: []*prometheus.Desc{limit, inFlight, shed, latency, baseline},
+		collect: func(ch chan<- prometheus.Metric) {
+			s := stats()
+			ch <- prometheus.MustNewConstMetric(limit, prometheus.GaugeValue, float64(s.Limit))
+			ch <- prometheus.MustNewConstMetric(inFlight, prometheus.GaugeValue, float64(s.InFlight))
+			ch <- prometheus.MustNewConstMetric(shed, prometheus.CounterValue, float64(s.Shed))
+			ch <- prometheus.MustNewConstMetric(latency, prometheus.GaugeValue, s.Latency.Seconds())
+			ch <- prometheus.MustNewConstMetric(baseline, prometheus.GaugeValue, s.Baseline.Seconds())
+		},
+	}
+}
+
+// NewLogSamplingCollector exposes the log records dropped by sampling, as
+// returned by logger.Logger.SamplingStats
+func NewLogSamplingCollector(namespace string, stats func() logger.SamplingStats) prometheus.Collector {
+	dropped := newDesc(namespace, "log", "sampled_out_total", "Log records dropped by sampling.")
+
+	return &statsCollector{
+		descs: []*prometheus.Desc{dropped},
+		collect: func(ch chan<- prometheus.Metric) {
+			ch <- prometheus.MustNewConstMetric(dropped, prometheus.CounterValue, float64(stats().Dropped))
+		},
+	}
+}
+
+// NewAccessLogCollector exposes the access log lines dropped by route
+// sampling, as returned by middleware.AccessLog.Stats
+func NewAccessLogCollector(namespace string, stats func() middleware.AccessLogStats) prometheus.Collector {
+	dropped := newDesc(namespace, "http", "access_log_sampled_out_total", "Access log lines dropped by route sampling.")
+
+	return &statsCollector{
+		descs: []*prometheus.Desc{dropped},
+		collect: func(ch chan<- prometheus.Metric) {
+			ch <- prometheus.MustNewConstMetric(dropped, prometheus.CounterValue, float64(stats().Dropped))
+		},
+	}
+}
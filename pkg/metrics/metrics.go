@@ -0,0 +1,90 @@
+// Package metrics expõe métricas no formato do Prometheus.
+//
+// Cada Metrics tem o próprio registry, com:
+// - as métricas RED (taxa, erros e duração) das requisições HTTP, rotuladas
+// pelo padrão da rota do chi, método e classe do status
+// - os coletores do runtime Go e do processo
+// - os coletores registrados pela aplicação, como os pools do banco e do
+// Redis, o rate limiter, o load shedder e os logs descartados por amostragem
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds a registry and the HTTP metrics recorded by Middleware
+type Metrics struct {
+	namespace string
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	inFlight  prometheus.Gauge
+}
+
+// New creates the metrics, prefixing every name with namespace
+func New(namespace string) *Metrics {
+	m := &Metrics{
+		namespace: namespace,
+		registry:  prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests handled, by route, method and status class.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Time to handle HTTP requests, by route, method and status class.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "HTTP requests being handled.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: namespace}),
+		m.requests,
+		m.duration,
+		m.inFlight,
+	)
+
+	return m
+}
+
+// Namespace returns the prefix of the metric names
+func (m *Metrics) Namespace() string {
+	return m.namespace
+}
+
+// Register adds collectors to the registry
+func (m *Metrics) Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := m.registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Registry returns the registry, to gather the metrics directly
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry: m.registry,
+	})
+}
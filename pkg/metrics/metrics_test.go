@@ -0,0 +1,138 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/metrics"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func assertMetric(t *testing.T, body, line string) {
+	t.Helper()
+	if !strings.Contains(body, line) {
+		t.Errorf("expected metrics to contain %q", line)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m := metrics.New("test")
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware())
+	r.Route("/api/v1", func(v1 chi.Router) {
+		v1.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		v1.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		})
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/users/2", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/users", nil),
+		httptest.NewRequest(http.MethodGet, "/wp-admin/login.php", nil),
+		httptest.NewRequest("PROPFIND", "/api/v1/users", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t, m)
+	assertMetric(t, body, `test_http_requests_total{method="GET",route="/api/v1/users/{id}",status="2xx"} 2`)
+	assertMetric(t, body, `test_http_requests_total{method="POST",route="/api/v1/users",status="4xx"} 1`)
+	assertMetric(t, body, `test_http_requests_total{method="GET",route="unmatched",status="4xx"} 1`)
+	assertMetric(t, body, `method="OTHER"`)
+	assertMetric(t, body, `test_http_request_duration_seconds_count{method="GET",route="/api/v1/users/{id}",status="2xx"} 2`)
+	assertMetric(t, body, "test_http_requests_in_flight 0")
+
+	if strings.Contains(body, "/api/v1/users/1") || strings.Contains(body, "wp-admin") {
+		t.Error("expected raw paths never to become labels")
+	}
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	m := metrics.New("test")
+
+	r := chi.NewRouter()
+	r.Use(middleware.Recovery(logger.New(&logger.Config{
+		Level:  logger.LevelError,
+		Format: logger.FormatText,
+	})))
+	r.Use(m.Middleware())
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assertMetric(t, scrape(t, m), `test_http_requests_total{method="GET",route="/panic",status="5xx"} 1`)
+}
+
+func TestCollectors(t *testing.T) {
+	m := metrics.New("test")
+
+	err := m.Register(
+		metrics.NewDatabaseCollector(m.Namespace(), func() sql.DBStats {
+			return sql.DBStats{MaxOpenConnections: 25, OpenConnections: 4, InUse: 3, Idle: 1, WaitCount: 7, WaitDuration: 2 * time.Second}
+		}),
+		metrics.NewCacheCollector(m.Namespace(), func() *redis.PoolStats {
+			return &redis.PoolStats{Hits: 10, Misses: 2, TotalConns: 5, IdleConns: 3}
+		}),
+		metrics.NewRateLimitCollector(m.Namespace(), func() middleware.RateLimitStats {
+			return middleware.RateLimitStats{BreakerState: "open", BreakerTransitions: 1, Rejected: 42}
+		}),
+		metrics.NewLoadShedCollector(m.Namespace(), func() middleware.LoadShedStats {
+			return middleware.LoadShedStats{Limit: 80, InFlight: 12, Shed: 3, Latency: 50 * time.Millisecond}
+		}),
+		metrics.NewLogSamplingCollector(m.Namespace(), func() logger.SamplingStats {
+			return logger.SamplingStats{Dropped: 120}
+		}),
+		metrics.NewAccessLogCollector(m.Namespace(), func() middleware.AccessLogStats {
+			return middleware.AccessLogStats{Dropped: 9}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	body := scrape(t, m)
+	for _, line := range []string{
+		"test_db_max_open_connections 25",
+		"test_db_in_use_connections 3",
+		"test_db_wait_count_total 7",
+		"test_db_wait_duration_seconds_total 2",
+		"test_redis_pool_hits_total 10",
+		"test_redis_pool_idle_connections 3",
+		"test_ratelimit_rejected_total 42",
+		`test_ratelimit_circuit_breaker_state{state="open"} 1`,
+		`test_ratelimit_circuit_breaker_state{state="closed"} 0`,
+		"test_loadshed_limit 80",
+		"test_loadshed_shed_total 3",
+		"test_loadshed_latency_seconds 0.05",
+		"test_log_sampled_out_total 120",
+		"test_http_access_log_sampled_out_total 9",
+	} {
+		assertMetric(t, body, line)
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels requests no route matched, so scanning random paths
+// cannot create a series per path
+const unmatchedRoute = "unmatched"
+
+// Middleware records the count, duration and in-flight gauge of requests.
+// It must run on the chi router, whose route pattern is read once the
+// request is handled.
+func (m *Metrics) Middleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			completed := false
+
+			defer func() {
+				code := ww.Status()
+				if !completed {
+					// Panicking; Recovery responds with 500 further up
+					code = http.StatusInternalServerError
+				}
+				status := statusClass(code)
+				route := routePattern(r)
+				method := methodLabel(r.Method)
+
+				m.requests.WithLabelValues(route, method, status).Inc()
+				m.duration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+			}()
+
+			next.ServeHTTP(ww, r)
+			completed = true
+		})
+	}
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return unmatchedRoute
+}
+
+// statusClass returns "2xx" for 201; a handler that wrote nothing sent 200
+func statusClass(status int) string {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// methodLabel keeps the method label bounded to the standard methods
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
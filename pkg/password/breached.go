@@ -0,0 +1,107 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1" // #nosec G505 - SHA-1 is the format of breached password lists, not used for security
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/marcelofabianov/fault"
+)
+
+// ErrInvalidBreachedList is returned when a breached password list cannot
+// be read
+var ErrInvalidBreachedList = fault.New(
+	"invalid breached password list",
+	fault.WithCode(fault.Internal),
+)
+
+// BreachedSet is an in-memory set of breached password SHA-1 hashes. Like
+// the k-anonymity range API of Have I Been Pwned, hashes are bucketed by
+// their first 20 bits; each bucket keeps only the next 64 bits, sorted,
+// so an entry costs 8 bytes and a lookup is a binary search.
+type BreachedSet struct {
+	buckets map[uint32][]uint64
+	size    int
+}
+
+// LoadBreachedSet reads a breached password list from path
+func LoadBreachedSet(path string) (*BreachedSet, error) {
+	file, err := os.Open(path) // #nosec G304 - path comes from configuration
+	if err != nil {
+		return nil, fault.Wrap(ErrInvalidBreachedList, "failed to open breached password list",
+			fault.WithCode(fault.Internal),
+			fault.WithContext("path", path),
+		)
+	}
+	defer func() { _ = file.Close() }()
+
+	return NewBreachedSet(file)
+}
+
+// NewBreachedSet reads one uppercase or lowercase hex SHA-1 hash per line,
+// optionally followed by ":<count>" as in the Have I Been Pwned downloads.
+// Empty lines and lines starting with # are ignored.
+func NewBreachedSet(r io.Reader) (*BreachedSet, error) {
+	set := &BreachedSet{buckets: make(map[uint32][]uint64)}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		sum, err := hex.DecodeString(hash)
+		if err != nil || len(sum) != sha1.Size {
+			return nil, fault.Wrap(ErrInvalidBreachedList, "expected a hex SHA-1 hash",
+				fault.WithCode(fault.Internal),
+				fault.WithContext("line", line),
+			)
+		}
+
+		prefix, suffix := splitHash(sum)
+		set.buckets[prefix] = append(set.buckets[prefix], suffix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fault.Wrap(ErrInvalidBreachedList, "failed to read breached password list",
+			fault.WithCode(fault.Internal),
+		)
+	}
+
+	for prefix, suffixes := range set.buckets {
+		slices.Sort(suffixes)
+		suffixes = slices.Compact(suffixes)
+		set.buckets[prefix] = slices.Clip(suffixes)
+		set.size += len(suffixes)
+	}
+
+	return set, nil
+}
+
+// Contains reports whether password is in the set
+func (s *BreachedSet) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password)) // #nosec G401 - matches the list format
+	prefix, suffix := splitHash(sum[:])
+	_, found := slices.BinarySearch(s.buckets[prefix], suffix)
+	return found
+}
+
+// Len returns the number of distinct hashes in the set
+func (s *BreachedSet) Len() int {
+	return s.size
+}
+
+// splitHash returns the first 20 bits of sum and the 64 bits after them
+func splitHash(sum []byte) (uint32, uint64) {
+	prefix := binary.BigEndian.Uint32(sum[0:4]) >> 12
+	suffix := binary.BigEndian.Uint64(sum[2:10])<<4 | uint64(sum[10]>>4)
+	return prefix, suffix
+}
@@ -0,0 +1,59 @@
+package password
+
+import (
+	"crypto/sha1" // #nosec G505 - test fixture in the list format
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password)) // #nosec G401 - test fixture
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestBreachedSet(t *testing.T) {
+	t.Run("finds listed passwords", func(t *testing.T) {
+		list := "# breached\n" +
+			sha1Hex("password") + ":3861493\n" +
+			"\n" +
+			strings.ToLower(sha1Hex("123456")) + "\n" +
+			sha1Hex("password") + "\n"
+
+		set, err := NewBreachedSet(strings.NewReader(list))
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, set.Len())
+		assert.True(t, set.Contains("password"))
+		assert.True(t, set.Contains("123456"))
+		assert.False(t, set.Contains("Test@123!"))
+	})
+
+	t.Run("rejects malformed lines", func(t *testing.T) {
+		_, err := NewBreachedSet(strings.NewReader("password\n"))
+
+		assert.True(t, errors.Is(err, ErrInvalidBreachedList))
+	})
+
+	t.Run("loads from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		require.NoError(t, os.WriteFile(path, []byte(sha1Hex("qwerty")+"\n"), 0o600))
+
+		set, err := LoadBreachedSet(path)
+
+		require.NoError(t, err)
+		assert.True(t, set.Contains("qwerty"))
+	})
+
+	t.Run("returns error for missing file", func(t *testing.T) {
+		_, err := LoadBreachedSet(filepath.Join(t.TempDir(), "missing.txt"))
+
+		assert.True(t, errors.Is(err, ErrInvalidBreachedList))
+	})
+}
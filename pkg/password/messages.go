@@ -0,0 +1,24 @@
+package password
+
+import "github.com/marcelofabianov/course/pkg/i18n"
+
+// ErrorMessages translates the errors of this package
+var ErrorMessages = i18n.ErrorCatalog{
+	ErrPolicyViolation: {i18n.LocalePtBR: "a senha não atende à política"},
+}
+
+// Messages translates policy violations, keyed by "password.<rule>"; {0}
+// is the param of the rule
+var Messages = i18n.Catalog{
+	i18n.LocalePtBR: {
+		"password." + RuleMinLength:    "a senha deve ter pelo menos {0} caracteres",
+		"password." + RuleMaxLength:    "a senha deve ter no máximo {0} caracteres",
+		"password." + RuleUppercase:    "a senha deve conter uma letra maiúscula",
+		"password." + RuleLowercase:    "a senha deve conter uma letra minúscula",
+		"password." + RuleDigit:        "a senha deve conter um número",
+		"password." + RuleSymbol:       "a senha deve conter um símbolo",
+		"password." + RuleMaxRepeated:  "a senha não deve repetir um caractere mais de {0} vezes seguidas",
+		"password." + RulePersonalInfo: "a senha não deve conter seu nome ou email",
+		"password." + RuleBreached:     "a senha apareceu em um vazamento de dados",
+	},
+}
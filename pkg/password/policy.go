@@ -0,0 +1,191 @@
+// Package password define a política de senhas da aplicação: tamanho,
+// classes de caracteres, repetições, dados pessoais e senhas vazadas.
+//
+// As violações são retornadas como um único erro fault com um detalhe por
+// regra violada, para que o cliente possa exibir todas de uma vez.
+package password
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/marcelofabianov/fault"
+)
+
+// ErrPolicyViolation is returned when a password breaks the policy
+var ErrPolicyViolation = fault.New(
+	"password does not meet the policy",
+	fault.WithCode(fault.Invalid),
+)
+
+// Rules reported in the details of ErrPolicyViolation
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleUppercase    = "uppercase"
+	RuleLowercase    = "lowercase"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RuleMaxRepeated  = "max_repeated"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+)
+
+// minPersonalInfoLength skips name parts too short to be meaningful, such
+// as "da" or "de"
+const minPersonalInfoLength = 3
+
+type PolicyConfig struct {
+	MinLength        int
+	MaxLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// MaxRepeated is the longest run of the same character; 0 disables it
+	MaxRepeated int
+	// ForbidPersonalInfo rejects passwords containing the name or the email
+	// local part of the user
+	ForbidPersonalInfo bool
+}
+
+func DefaultPolicyConfig() PolicyConfig {
+	return PolicyConfig{
+		MinLength:          8,
+		MaxLength:          72,
+		RequireUppercase:   true,
+		RequireLowercase:   true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		MaxRepeated:        3,
+		ForbidPersonalInfo: true,
+	}
+}
+
+// UserInfo is the personal data a password must not contain
+type UserInfo struct {
+	Name  string
+	Email string
+}
+
+type Policy struct {
+	cfg      PolicyConfig
+	breached *BreachedSet
+}
+
+// NewPolicy creates a policy; breached may be nil to skip the breached
+// password check
+func NewPolicy(cfg PolicyConfig, breached *BreachedSet) *Policy {
+	return &Policy{cfg: cfg, breached: breached}
+}
+
+// Validate checks password against every rule and returns
+// ErrPolicyViolation with one detail per broken rule
+func (p *Policy) Validate(password string, user UserInfo) error {
+	var violations []*fault.Error
+	violate := func(rule, message string, param any) {
+		violations = append(violations, fault.New(message,
+			fault.WithCode(fault.Invalid),
+			fault.WithContext("field", "password"),
+			fault.WithContext("rule", rule),
+			fault.WithContext("param", param),
+		))
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		violate(RuleMinLength, "password must have at least "+strconv.Itoa(p.cfg.MinLength)+" characters", p.cfg.MinLength)
+	}
+	if p.cfg.MaxLength > 0 && length > p.cfg.MaxLength {
+		violate(RuleMaxLength, "password must have at most "+strconv.Itoa(p.cfg.MaxLength)+" characters", p.cfg.MaxLength)
+	}
+
+	classes := characterClasses(password)
+	if p.cfg.RequireUppercase && !classes.upper {
+		violate(RuleUppercase, "password must contain an uppercase letter", nil)
+	}
+	if p.cfg.RequireLowercase && !classes.lower {
+		violate(RuleLowercase, "password must contain a lowercase letter", nil)
+	}
+	if p.cfg.RequireDigit && !classes.digit {
+		violate(RuleDigit, "password must contain a digit", nil)
+	}
+	if p.cfg.RequireSymbol && !classes.symbol {
+		violate(RuleSymbol, "password must contain a symbol", nil)
+	}
+
+	if p.cfg.MaxRepeated > 0 && longestRun(password) > p.cfg.MaxRepeated {
+		violate(RuleMaxRepeated, "password must not repeat a character more than "+strconv.Itoa(p.cfg.MaxRepeated)+" times in a row", p.cfg.MaxRepeated)
+	}
+
+	if p.cfg.ForbidPersonalInfo && containsPersonalInfo(password, user) {
+		violate(RulePersonalInfo, "password must not contain your name or email", nil)
+	}
+
+	if p.breached != nil && p.breached.Contains(password) {
+		violate(RuleBreached, "password appeared in a data breach", nil)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return fault.Wrap(ErrPolicyViolation, ErrPolicyViolation.Error(),
+		fault.WithCode(fault.Invalid),
+		fault.WithDetails(violations...),
+	)
+}
+
+type classes struct {
+	upper, lower, digit, symbol bool
+}
+
+func characterClasses(password string) classes {
+	var c classes
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			c.upper = true
+		case unicode.IsLower(r):
+			c.lower = true
+		case unicode.IsDigit(r):
+			c.digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			c.symbol = true
+		}
+	}
+	return c
+}
+
+func longestRun(password string) int {
+	longest, run := 0, 0
+	var previous rune = -1
+	for _, r := range password {
+		if r == previous {
+			run++
+		} else {
+			run = 1
+			previous = r
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func containsPersonalInfo(password string, user UserInfo) bool {
+	lowered := strings.ToLower(password)
+
+	parts := strings.Fields(strings.ToLower(user.Name))
+	if local, _, found := strings.Cut(strings.ToLower(user.Email), "@"); found {
+		parts = append(parts, local)
+	}
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= minPersonalInfoLength && strings.Contains(lowered, part) {
+			return true
+		}
+	}
+	return false
+}
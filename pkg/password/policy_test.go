@@ -0,0 +1,83 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/marcelofabianov/fault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUser = UserInfo{Name: "John Doe", Email: "jdoe@example.com"}
+
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+	require.True(t, errors.Is(err, ErrPolicyViolation))
+
+	faultErr, ok := fault.AsFault(err)
+	require.True(t, ok)
+
+	rules := make([]string, 0, len(faultErr.Details))
+	for _, detail := range faultErr.Details {
+		assert.Equal(t, "password", detail.Context["field"])
+		rules = append(rules, detail.Context["rule"].(string))
+	}
+	return rules
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	policy := NewPolicy(DefaultPolicyConfig(), nil)
+
+	t.Run("accepts a strong password", func(t *testing.T) {
+		assert.NoError(t, policy.Validate("Test@123!", testUser))
+	})
+
+	t.Run("reports every broken rule", func(t *testing.T) {
+		err := policy.Validate("aaaa", testUser)
+
+		assert.ElementsMatch(t, []string{
+			RuleMinLength, RuleUppercase, RuleDigit, RuleSymbol, RuleMaxRepeated,
+		}, violatedRules(t, err))
+	})
+
+	t.Run("rejects passwords longer than the maximum", func(t *testing.T) {
+		err := policy.Validate("Aa1!"+strings.Repeat("xy", 40), testUser)
+
+		assert.Equal(t, []string{RuleMaxLength}, violatedRules(t, err))
+	})
+
+	t.Run("rejects the user's name", func(t *testing.T) {
+		err := policy.Validate("Doe@2024!", testUser)
+
+		assert.Equal(t, []string{RulePersonalInfo}, violatedRules(t, err))
+	})
+
+	t.Run("rejects the email local part", func(t *testing.T) {
+		err := policy.Validate("JDoe#9876x", UserInfo{Email: "jdoe@example.com"})
+
+		assert.Equal(t, []string{RulePersonalInfo}, violatedRules(t, err))
+	})
+
+	t.Run("ignores short name parts", func(t *testing.T) {
+		err := policy.Validate("Dajoe@123", UserInfo{Name: "Ana da Silva"})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("honors disabled rules", func(t *testing.T) {
+		relaxed := NewPolicy(PolicyConfig{MinLength: 4}, nil)
+
+		assert.NoError(t, relaxed.Validate("aaaa", testUser))
+	})
+
+	t.Run("rejects breached passwords", func(t *testing.T) {
+		breached, err := NewBreachedSet(strings.NewReader(sha1Hex("Test@123!") + "\n"))
+		require.NoError(t, err)
+
+		err = NewPolicy(DefaultPolicyConfig(), breached).Validate("Test@123!", testUser)
+
+		assert.Equal(t, []string{RuleBreached}, violatedRules(t, err))
+	})
+}
@@ -0,0 +1,72 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tenantA = "6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a"
+
+func TestParse(t *testing.T) {
+	t.Run("accepts and normalizes UUIDs", func(t *testing.T) {
+		id, err := Parse(" 6F1C1D0E-6A56-4A2F-9D62-0B8D1C4C3B7A ")
+		require.NoError(t, err)
+		assert.Equal(t, ID(tenantA), id)
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		_, err := Parse("acme")
+		assert.True(t, errors.Is(err, ErrInvalidID))
+	})
+}
+
+func TestContext(t *testing.T) {
+	t.Run("round trips the tenant", func(t *testing.T) {
+		ctx := WithID(context.Background(), ID(tenantA))
+
+		id, ok := FromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, ID(tenantA), id)
+	})
+
+	t.Run("reports missing tenant", func(t *testing.T) {
+		_, ok := FromContext(context.Background())
+		assert.False(t, ok)
+
+		_, ok = FromContext(WithID(context.Background(), ""))
+		assert.False(t, ok)
+	})
+}
+
+func TestVerifiedFromContext(t *testing.T) {
+	t.Run("returns tenants from verified credentials", func(t *testing.T) {
+		id, ok := VerifiedFromContext(WithVerifiedID(context.Background(), ID(tenantA)))
+
+		assert.True(t, ok)
+		assert.Equal(t, ID(tenantA), id)
+	})
+
+	t.Run("ignores unverified tenants", func(t *testing.T) {
+		_, ok := VerifiedFromContext(WithID(context.Background(), ID(tenantA)))
+		assert.False(t, ok)
+	})
+
+	t.Run("ignores a verified tenant replaced by an unverified one", func(t *testing.T) {
+		ctx := WithVerifiedID(context.Background(), ID(tenantA))
+		ctx = WithID(ctx, "0b8d1c4c-3b7a-4a2f-9d62-6f1c1d0e6a56")
+
+		_, ok := VerifiedFromContext(ctx)
+		assert.False(t, ok)
+	})
+}
+
+func TestKey(t *testing.T) {
+	ctx := WithID(context.Background(), ID(tenantA))
+
+	assert.Equal(t, "tenant:"+tenantA+":user:1", Key(ctx, "user:1"))
+	assert.Equal(t, "user:1", Key(context.Background(), "user:1"))
+}
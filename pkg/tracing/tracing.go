@@ -0,0 +1,111 @@
+// Package tracing configura o rastreamento distribuído com OpenTelemetry.
+//
+// Setup registra o TracerProvider global, exportando os spans por OTLP/HTTP
+// ou, sem endpoint, para a saída padrão, e o propagador W3C (traceparent e
+// baggage). Sem Setup, o provider global é um no-op, então a instrumentação
+// de pkg/database, pkg/cache, dos middlewares HTTP e dos casos de uso pode
+// ficar sempre ativa.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/marcelofabianov/course/config"
+)
+
+// instrumentationName is the scope of every span of the application
+const instrumentationName = "github.com/marcelofabianov/course"
+
+// Setup installs the global tracer provider and W3C propagator. The caller
+// must shut the provider down to flush pending spans.
+func Setup(ctx context.Context, cfg *config.Config) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.General.ServiceName),
+		semconv.DeploymentEnvironmentName(cfg.General.Env),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider, nil
+}
+
+// newExporter exports over OTLP/HTTP, or to stdout for local use when no
+// endpoint is set
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	if cfg.Endpoint == "" {
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	}
+	return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+}
+
+// Tracer returns the tracer of the application
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span as a child of the span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End ends span, recording err as its error when not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Propagator returns the global propagator, to extract or inject the trace
+// context of requests
+func Propagator() propagation.TextMapPropagator {
+	return otel.GetTextMapPropagator()
+}
+
+// TraceID returns the trace ID of the span in ctx, or "" without one
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// SpanID returns the span ID of the span in ctx, or "" without one
+func SpanID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasSpanID() {
+		return sc.SpanID().String()
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	previous := otel.GetTracerProvider()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := useRecorder(t)
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	if tracing.TraceID(ctx) == "" || tracing.SpanID(ctx) == "" {
+		t.Fatal("expected the context to carry the span")
+	}
+
+	_, child := tracing.Start(ctx, "child")
+	tracing.End(child, errors.New("boom"))
+	tracing.End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("expected the child to be parented to the span in ctx")
+	}
+	if spans[0].Status().Code != codes.Error || len(spans[0].Events()) != 1 {
+		t.Error("expected the error to be recorded on the child")
+	}
+	if spans[1].Status().Code == codes.Error {
+		t.Error("expected the parent to end without error")
+	}
+}
+
+func TestTraceID_WithoutSpan(t *testing.T) {
+	if tracing.TraceID(context.Background()) != "" || tracing.SpanID(context.Background()) != "" {
+		t.Error("expected no IDs without a span")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	cfg := &config.Config{
+		General: config.GeneralConfig{ServiceName: "course-api", Env: "test"},
+		Tracing: config.TracingConfig{Enabled: true, SampleRatio: 1},
+	}
+
+	provider, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	defer provider.Shutdown(context.Background())
+
+	if otel.GetTracerProvider() != provider {
+		t.Error("expected the provider to be installed globally")
+	}
+	if fields := otel.GetTextMapPropagator().Fields(); !slices.Contains(fields, "traceparent") {
+		t.Errorf("expected the W3C propagator, got %v", fields)
+	}
+}
@@ -0,0 +1,93 @@
+package validation
+
+import (
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	pt_BR_translations "github.com/go-playground/validator/v10/translations/pt_BR"
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/i18n"
+)
+
+// ErrorMessages translates the errors of this package
+var ErrorMessages = i18n.ErrorCatalog{
+	ErrValidationFailed: {i18n.LocalePtBR: "falha na validação"},
+	ErrInvalidInput:     {i18n.LocalePtBR: "entrada inválida para validação"},
+}
+
+// defaultTranslations registers the messages of the built-in tags
+var defaultTranslations = map[string]func(*validator.Validate, ut.Translator) error{
+	i18n.LocaleEN:   en_translations.RegisterDefaultTranslations,
+	i18n.LocalePtBR: pt_BR_translations.RegisterDefaultTranslations,
+}
+
+// customTagMessages are the messages of the Brazilian validators; {0} is
+// the field
+var customTagMessages = i18n.Catalog{
+	i18n.LocaleEN: {
+		"cpf":   "{0} must be a valid CPF",
+		"cnpj":  "{0} must be a valid CNPJ",
+		"cep":   "{0} must be a valid CEP",
+		"phone": "{0} must be a valid phone number",
+	},
+	i18n.LocalePtBR: {
+		"cpf":   "{0} deve ser um CPF válido",
+		"cnpj":  "{0} deve ser um CNPJ válido",
+		"cep":   "{0} deve ser um CEP válido",
+		"phone": "{0} deve ser um telefone válido",
+	},
+}
+
+// RegisterTranslations registers the messages of the built-in and custom
+// tags for every locale of bundle. Failed fields are then reported in the
+// locale of the context passed to Struct.
+func RegisterTranslations(v Validator, bundle *i18n.Bundle) error {
+	vi, ok := v.(*validatorImpl)
+	if !ok {
+		return fault.Wrap(ErrInvalidInput, "translations require a validator created by New")
+	}
+
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	for _, locale := range bundle.Supported() {
+		trans := bundle.Translator(locale)
+
+		if register, ok := defaultTranslations[locale]; ok {
+			if err := register(vi.validate, trans); err != nil {
+				return fault.Wrap(err, "failed to register validator translations",
+					fault.WithCode(fault.Internal),
+					fault.WithContext("locale", locale),
+				)
+			}
+		}
+
+		for tag, text := range customTagMessages[locale] {
+			if err := vi.validate.RegisterTranslation(tag, trans, addTranslation(tag, text), translateField); err != nil {
+				return fault.Wrap(err, "failed to register validator translation",
+					fault.WithCode(fault.Internal),
+					fault.WithContext("locale", locale),
+					fault.WithContext("tag", tag),
+				)
+			}
+		}
+	}
+
+	vi.translated = true
+	return nil
+}
+
+func addTranslation(tag, text string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, text, true)
+	}
+}
+
+func translateField(trans ut.Translator, fe validator.FieldError) string {
+	message, err := trans.T(fe.Tag(), fe.Field())
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
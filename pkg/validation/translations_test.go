@@ -0,0 +1,66 @@
+package validation_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/i18n"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/validation"
+)
+
+func TestRegisterTranslations(t *testing.T) {
+	log := logger.New(&logger.Config{
+		Level:       logger.LevelInfo,
+		Format:      logger.FormatText,
+		ServiceName: "test",
+		Environment: "test",
+	})
+
+	bundle, err := i18n.NewBundle(i18n.LocaleEN)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+
+	v := validation.New(log, nil)
+	if err := validation.RegisterBrazilianValidators(v); err != nil {
+		t.Fatalf("failed to register validators: %v", err)
+	}
+	if err := validation.RegisterTranslations(v, bundle); err != nil {
+		t.Fatalf("failed to register translations: %v", err)
+	}
+
+	type TestStruct struct {
+		Name string `json:"name" validate:"required"`
+		CPF  string `json:"cpf" validate:"cpf"`
+	}
+
+	messages := func(locale string) []string {
+		ctx := i18n.WithLocalizer(context.Background(), bundle.Localizer(locale))
+		err := v.Struct(ctx, TestStruct{CPF: "111.111.111-11"})
+
+		faultErr, ok := fault.AsFault(err)
+		if !ok || len(faultErr.Details) != 2 {
+			t.Fatalf("expected 2 field errors, got %v", err)
+		}
+		return []string{faultErr.Details[0].Message, faultErr.Details[1].Message}
+	}
+
+	ptBR := messages(i18n.LocalePtBR)
+	if ptBR[0] != "name é um campo obrigatório" {
+		t.Errorf("unexpected pt-BR required message: %q", ptBR[0])
+	}
+	if ptBR[1] != "cpf deve ser um CPF válido" {
+		t.Errorf("unexpected pt-BR cpf message: %q", ptBR[1])
+	}
+
+	en := messages(i18n.LocaleEN)
+	if en[0] != "name is a required field" {
+		t.Errorf("unexpected en required message: %q", en[0])
+	}
+	if en[1] != "cpf must be a valid CPF" {
+		t.Errorf("unexpected en cpf message: %q", en[1])
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"strings"
 	"sync"
 
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	"github.com/marcelofabianov/course/pkg/i18n"
 	"github.com/marcelofabianov/course/pkg/logger"
 	"github.com/marcelofabianov/fault"
 )
@@ -25,13 +27,11 @@ type validatorImpl struct {
 	mu               sync.RWMutex
 	sensitiveFields  map[string]bool
 	customValidators map[string]validator.Func
+	translated       bool
 }
 
 var (
-	defaultSensitiveFields = []string{
-		"password", "senha", "token", "secret", "apikey", "api_key",
-		"credit_card", "card_number", "cvv", "pin", "private_key",
-	}
+	defaultSensitiveFields = logger.DefaultSensitiveKeys
 
 	ErrValidationFailed = fault.New(
 		"validation failed",
@@ -91,7 +91,7 @@ func (vi *validatorImpl) Struct(ctx context.Context, s any) error {
 
 	if valErrs, ok := err.(validator.ValidationErrors); ok {
 		sanitized := vi.sanitizeStruct(s)
-		faultErr := vi.buildValidationError(valErrs)
+		faultErr := vi.buildValidationError(ctx, valErrs)
 
 		if vi.config.EnableLogging {
 			vi.log.ErrorContext(ctx, "Struct validation failed",
@@ -189,9 +189,15 @@ func (vi *validatorImpl) RegisterCustom(tag string, fn validator.Func) error {
 	return nil
 }
 
-func (vi *validatorImpl) buildValidationError(valErrs validator.ValidationErrors) error {
-	var messages []string
+// buildValidationError returns ErrValidationFailed with the messages of the
+// failed fields joined, and one detail per failed field carrying the field,
+// rule and param in its context. Messages are in the locale of ctx once
+// translations are registered.
+func (vi *validatorImpl) buildValidationError(ctx context.Context, valErrs validator.ValidationErrors) error {
 	contexts := make(map[string]interface{})
+	messages := make([]string, 0, len(valErrs))
+	details := make([]*fault.Error, 0, len(valErrs))
+	trans := vi.translator(ctx)
 
 	for i, fieldErr := range valErrs {
 		msg := fmt.Sprintf("field '%s' failed validation '%s'",
@@ -203,8 +209,18 @@ func (vi *validatorImpl) buildValidationError(valErrs validator.ValidationErrors
 			msg += fmt.Sprintf(" (param: %s)", fieldErr.Param())
 		}
 
+		if trans != nil {
+			msg = fieldErr.Translate(trans)
+		}
+
 		messages = append(messages, msg)
 		contexts[fmt.Sprintf("error_%d", i)] = msg
+		details = append(details, fault.New(msg,
+			fault.WithCode(fault.Invalid),
+			fault.WithContext("field", fieldPath(fieldErr)),
+			fault.WithContext("rule", fieldErr.Tag()),
+			fault.WithContext("param", fieldErr.Param()),
+		))
 	}
 
 	return fault.Wrap(
@@ -213,9 +229,34 @@ func (vi *validatorImpl) buildValidationError(valErrs validator.ValidationErrors
 		fault.WithContext("validation_errors", contexts),
 		fault.WithContext("error_count", len(valErrs)),
 		fault.WithCode(fault.Invalid),
+		fault.WithDetails(details...),
 	)
 }
 
+// translator returns the translator of the locale of ctx, or nil when
+// translations are not registered
+func (vi *validatorImpl) translator(ctx context.Context) ut.Translator {
+	vi.mu.RLock()
+	defer vi.mu.RUnlock()
+
+	if !vi.translated {
+		return nil
+	}
+	if localizer := i18n.FromContext(ctx); localizer != nil {
+		return localizer.Translator()
+	}
+	return nil
+}
+
+// fieldPath returns the JSON path of a failed field without the root
+// struct, e.g. "address.city" for "UserInput.address.city"
+func fieldPath(fieldErr validator.FieldError) string {
+	if _, path, found := strings.Cut(fieldErr.Namespace(), "."); found {
+		return path
+	}
+	return fieldErr.Field()
+}
+
 func (vi *validatorImpl) sanitizeStruct(s any) map[string]interface{} {
 	result := make(map[string]interface{})
 
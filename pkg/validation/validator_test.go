@@ -7,6 +7,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/marcelofabianov/course/pkg/logger"
 	"github.com/marcelofabianov/course/pkg/validation"
+	"github.com/marcelofabianov/fault"
 )
 
 func TestNew(t *testing.T) {
@@ -91,6 +92,39 @@ func TestValidator_Struct(t *testing.T) {
 		}
 	})
 
+	t.Run("reports one detail per failed field", func(t *testing.T) {
+		type Address struct {
+			City string `json:"city" validate:"required"`
+		}
+		type TestStruct struct {
+			Name    string  `json:"name" validate:"required,min=3"`
+			Address Address `json:"address"`
+		}
+
+		err := v.Struct(ctx, TestStruct{Name: "Jo"})
+
+		faultErr, ok := fault.AsFault(err)
+		if !ok {
+			t.Fatalf("expected a fault error, got %v", err)
+		}
+		if len(faultErr.Details) != 2 {
+			t.Fatalf("expected 2 details, got %d", len(faultErr.Details))
+		}
+
+		name := faultErr.Details[0].Context
+		if name["field"] != "name" || name["rule"] != "min" || name["param"] != "3" {
+			t.Errorf("unexpected name detail context: %v", name)
+		}
+		if city := faultErr.Details[1].Context; city["field"] != "address.city" || city["rule"] != "required" {
+			t.Errorf("unexpected city detail context: %v", city)
+		}
+
+		expected := "field 'name' failed validation 'min' (param: 3); field 'city' failed validation 'required'"
+		if faultErr.Message != expected {
+			t.Errorf("expected the joined field messages, got %q", faultErr.Message)
+		}
+	})
+
 	t.Run("nil struct returns error", func(t *testing.T) {
 		err := v.Struct(ctx, nil)
 		if err == nil {
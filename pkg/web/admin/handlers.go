@@ -0,0 +1,183 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/web"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+var (
+	ErrInvalidLogLevel = fault.New(
+		"invalid log level",
+		fault.WithCode(fault.Invalid),
+	)
+	ErrInvalidDebugTokenTTL = fault.New(
+		"invalid debug token ttl",
+		fault.WithCode(fault.Invalid),
+	)
+)
+
+var startTime = time.Now()
+
+type BuildInfoResponse struct {
+	Service     string    `json:"service"`
+	Environment string    `json:"environment"`
+	Module      string    `json:"module"`
+	Version     string    `json:"version"`
+	Revision    string    `json:"revision,omitempty"`
+	RevisionAt  string    `json:"revision_time,omitempty"`
+	Modified    bool      `json:"modified"`
+	GoVersion   string    `json:"go_version"`
+	GOOS        string    `json:"goos"`
+	GOARCH      string    `json:"goarch"`
+	NumCPU      int       `json:"num_cpu"`
+	GOMAXPROCS  int       `json:"gomaxprocs"`
+	Goroutines  int       `json:"goroutines"`
+	StartedAt   time.Time `json:"started_at"`
+	Uptime      string    `json:"uptime"`
+}
+
+type LogLevelRequest struct {
+	Level logger.LogLevel `json:"level"`
+}
+
+type LogLevelResponse struct {
+	Level    logger.LogLevel `json:"level"`
+	Previous logger.LogLevel `json:"previous,omitempty"`
+}
+
+// DebugTokenRequest issues a token for Subject, a user ID, or for any
+// request when empty. TTL is a duration such as "15m", defaulting to the
+// configured maximum.
+type DebugTokenRequest struct {
+	Subject string `json:"subject"`
+	TTL     string `json:"ttl"`
+}
+
+// BuildInfoHandler reports the module and VCS stamps of the binary and the
+// state of the runtime
+func BuildInfoHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := BuildInfoResponse{
+			Service:     cfg.General.ServiceName,
+			Environment: cfg.General.Env,
+			GoVersion:   runtime.Version(),
+			GOOS:        runtime.GOOS,
+			GOARCH:      runtime.GOARCH,
+			NumCPU:      runtime.NumCPU(),
+			GOMAXPROCS:  runtime.GOMAXPROCS(0),
+			Goroutines:  runtime.NumGoroutine(),
+			StartedAt:   startTime,
+			Uptime:      time.Since(startTime).Round(time.Second).String(),
+		}
+
+		if info, ok := debug.ReadBuildInfo(); ok {
+			response.Module = info.Main.Path
+			response.Version = info.Main.Version
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					response.Revision = setting.Value
+				case "vcs.time":
+					response.RevisionAt = setting.Value
+				case "vcs.modified":
+					response.Modified = setting.Value == "true"
+				}
+			}
+		}
+
+		web.Success(w, r, http.StatusOK, response)
+	}
+}
+
+// ConfigHandler dumps the loaded configuration with its secrets redacted
+func ConfigHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		web.Success(w, r, http.StatusOK, cfg.Redacted())
+	}
+}
+
+func GetLogLevelHandler(log *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		web.Success(w, r, http.StatusOK, LogLevelResponse{Level: log.Level()})
+	}
+}
+
+// SetLogLevelHandler changes the level of every logger of the application
+// until the next change or restart
+func SetLogLevelHandler(log *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := web.DecodeJSON[LogLevelRequest](r, nil)
+		if err != nil {
+			web.Error(w, r, err)
+			return
+		}
+
+		if !req.Level.IsValid() {
+			web.Error(w, r, fault.Wrap(ErrInvalidLogLevel, ErrInvalidLogLevel.Error(),
+				fault.WithCode(fault.Invalid),
+				fault.WithContext("level", string(req.Level)),
+			))
+			return
+		}
+
+		previous := log.Level()
+		if err := log.SetLevel(req.Level); err != nil {
+			web.Error(w, r, fault.Wrap(err, "failed to change log level", fault.WithCode(fault.Internal)))
+			return
+		}
+
+		// Warn, so the change is logged at every level but error
+		web.GetLogger(r.Context()).Warn("log level changed",
+			"previous", string(previous),
+			"level", string(req.Level),
+		)
+
+		web.Success(w, r, http.StatusOK, LogLevelResponse{Level: req.Level, Previous: previous})
+	}
+}
+
+// IssueDebugTokenHandler signs a token that logs the requests carrying it
+// at debug level
+func IssueDebugTokenHandler(debugLogging *middleware.DebugLogging) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := web.DecodeJSON[DebugTokenRequest](r, nil)
+		if err != nil {
+			web.Error(w, r, err)
+			return
+		}
+
+		var ttl time.Duration
+		if req.TTL != "" {
+			ttl, err = time.ParseDuration(req.TTL)
+			if err != nil {
+				web.Error(w, r, fault.Wrap(ErrInvalidDebugTokenTTL, ErrInvalidDebugTokenTTL.Error(),
+					fault.WithCode(fault.Invalid),
+					fault.WithContext("ttl", req.TTL),
+				))
+				return
+			}
+		}
+
+		token, err := debugLogging.Issue(req.Subject, ttl)
+		if err != nil {
+			web.Error(w, r, err)
+			return
+		}
+
+		web.GetLogger(r.Context()).Warn("debug token issued",
+			"subject", token.Subject,
+			"expires_at", token.ExpiresAt,
+		)
+
+		web.Created(w, r, token)
+	}
+}
@@ -0,0 +1,58 @@
+// Package admin monta o roteador do listener interno de operação, servido
+// por web.NewAdminServer em outra porta e nunca pelo roteador público.
+//
+// Ele expõe:
+// - o net/http/pprof em /debug/pprof/ e o expvar em /debug/vars
+// - as informações do build e do runtime em /buildinfo
+// - a configuração, sem senhas, segredos e chaves, em /config
+// - as métricas do Prometheus em /metrics
+// - o nível de log, lido com GET e alterado em tempo de execução com PUT, em
+// /loglevel
+// - a emissão de tokens de debug em /debug-tokens, que ligam o log em nível
+// debug para uma requisição ou usuário sem alterar o nível global
+package admin
+
+import (
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/metrics"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+type RouterConfig struct {
+	Config  *config.Config
+	Logger  *logger.Logger
+	Metrics *metrics.Metrics
+	// DebugLogging issues the debug tokens; nil disables them
+	DebugLogging *middleware.DebugLogging
+}
+
+func NewRouter(cfg RouterConfig) *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(middleware.Recovery(cfg.Logger))
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger(cfg.Logger))
+	r.Use(chimiddleware.NoCache)
+
+	// pprof under /debug/pprof/ and expvar under /debug/vars
+	r.Mount("/debug", chimiddleware.Profiler())
+
+	r.Get("/buildinfo", BuildInfoHandler(cfg.Config))
+	r.Get("/config", ConfigHandler(cfg.Config))
+	r.Get("/loglevel", GetLogLevelHandler(cfg.Logger))
+	r.Put("/loglevel", SetLogLevelHandler(cfg.Logger))
+
+	if cfg.DebugLogging != nil {
+		r.Post("/debug-tokens", IssueDebugTokenHandler(cfg.DebugLogging))
+	}
+
+	if cfg.Metrics != nil {
+		r.Handle("/metrics", cfg.Metrics.Handler())
+	}
+
+	return r
+}
@@ -0,0 +1,193 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/metrics"
+	"github.com/marcelofabianov/course/pkg/web/admin"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+func newRouter(t *testing.T) (http.Handler, *logger.Logger) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.General.ServiceName = "course-api"
+	cfg.General.Env = "development"
+	cfg.Database.Credentials.Password = "db-password"
+	cfg.JWT.AccessSecret = "access-secret"
+
+	log := logger.New(&logger.Config{
+		Level:  logger.LevelInfo,
+		Format: logger.FormatJSON,
+		Output: io.Discard,
+	})
+
+	return admin.NewRouter(admin.RouterConfig{
+		Config:  cfg,
+		Logger:  log,
+		Metrics: metrics.New("test"),
+		DebugLogging: middleware.NewDebugLogging(config.DebugLoggingConfig{
+			Enabled:    true,
+			HeaderName: "X-Debug-Token",
+			Secret:     "test-debug-secret-with-32-bytes!!!",
+			MaxTTL:     time.Hour,
+		}, "", nil),
+	}), log
+}
+
+func serve(r http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouter_Pprof(t *testing.T) {
+	r, _ := newRouter(t)
+
+	w := serve(r, http.MethodGet, "/debug/pprof/", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "goroutine") {
+		t.Error("expected the pprof index")
+	}
+
+	w = serve(r, http.MethodGet, "/debug/pprof/goroutine?debug=1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	w = serve(r, http.MethodGet, "/debug/vars", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "memstats") {
+		t.Errorf("expected expvar, got %d", w.Code)
+	}
+}
+
+func TestRouter_BuildInfo(t *testing.T) {
+	r, _ := newRouter(t)
+
+	w := serve(r, http.MethodGet, "/buildinfo", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var info admin.BuildInfoResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if info.Service != "course-api" || info.GoVersion == "" || info.Goroutines == 0 {
+		t.Errorf("unexpected build info: %+v", info)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Error("expected admin responses not to be cached")
+	}
+}
+
+func TestRouter_Config(t *testing.T) {
+	r, _ := newRouter(t)
+
+	w := serve(r, http.MethodGet, "/config", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	if strings.Contains(body, "db-password") || strings.Contains(body, "access-secret") {
+		t.Error("expected secrets to be redacted")
+	}
+	if !strings.Contains(body, "course-api") || !strings.Contains(body, "[REDACTED]") {
+		t.Errorf("unexpected config dump: %s", body)
+	}
+}
+
+func TestRouter_Metrics(t *testing.T) {
+	r, _ := newRouter(t)
+
+	w := serve(r, http.MethodGet, "/metrics", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "test_http_requests_in_flight") {
+		t.Error("expected the application metrics")
+	}
+}
+
+func TestRouter_LogLevel(t *testing.T) {
+	r, log := newRouter(t)
+
+	w := serve(r, http.MethodGet, "/loglevel", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"level":"info"`) {
+		t.Fatalf("expected level info, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(r, http.MethodPut, "/loglevel", `{"level":"debug"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp admin.LogLevelResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if resp.Level != logger.LevelDebug || resp.Previous != logger.LevelInfo {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if log.Level() != logger.LevelDebug {
+		t.Errorf("expected logger level debug, got %s", log.Level())
+	}
+
+	for _, body := range []string{`{"level":"verbose"}`, `{"lvl":"debug"}`, `not json`} {
+		w = serve(r, http.MethodPut, "/loglevel", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+	if log.Level() != logger.LevelDebug {
+		t.Errorf("expected rejected changes to keep debug, got %s", log.Level())
+	}
+
+	w = serve(r, http.MethodPost, "/loglevel", `{"level":"warn"}`)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestRouter_DebugTokens(t *testing.T) {
+	r, _ := newRouter(t)
+
+	w := serve(r, http.MethodPost, "/debug-tokens", `{"subject":"user-1","ttl":"15m"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var token middleware.DebugToken
+	if err := json.Unmarshal(w.Body.Bytes(), &token); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if token.Token == "" || token.Header != "X-Debug-Token" || token.Subject != "user-1" {
+		t.Errorf("unexpected token: %+v", token)
+	}
+	if until := time.Until(token.ExpiresAt); until <= 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("expected the token to expire in 15m, got %s", until)
+	}
+
+	for _, body := range []string{`{"ttl":"forever"}`, `{"ttl":"2h"}`, `{"ttl":"-1m"}`} {
+		w = serve(r, http.MethodPost, "/debug-tokens", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
@@ -8,16 +8,23 @@ import (
 	"github.com/marcelofabianov/course/config"
 	_ "github.com/marcelofabianov/course/docs" // Swagger docs
 	"github.com/marcelofabianov/course/pkg/cache"
+	"github.com/marcelofabianov/course/pkg/i18n"
 	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/metrics"
 	"github.com/marcelofabianov/course/pkg/web"
 	"github.com/marcelofabianov/course/pkg/web/middleware"
 )
 
 type RouterConfig struct {
-	Config  *config.Config
-	Logger  *logger.Logger
-	Cache   *cache.Cache
-	Routers []web.Router
+	Config      *config.Config
+	Logger      *logger.Logger
+	Cache       *cache.Cache
+	I18n        *i18n.Bundle
+	LoadShedder *middleware.LoadShedder
+	Metrics     *metrics.Metrics
+	// DebugLogging honours the debug tokens; nil ignores them
+	DebugLogging *middleware.DebugLogging
+	Routers      []web.Router
 }
 
 func NewRouter(cfg RouterConfig) *chi.Mux {
@@ -26,11 +33,42 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 	securityLogger := middleware.NewSecurityLogger(cfg.Logger)
 
 	r.Use(middleware.Recovery(cfg.Logger))
+	if cfg.Metrics != nil {
+		r.Use(cfg.Metrics.Middleware())
+	}
+	// Before RequestID, which falls back to the trace ID
+	if cfg.Config.Tracing.Enabled {
+		r.Use(middleware.Tracing())
+	}
 	r.Use(middleware.RequestID())
-	r.Use(middleware.RealIP())
-	r.Use(middleware.Logger(cfg.Logger))
+	r.Use(middleware.RealIP(middleware.NewClientIPResolver(
+		cfg.Config.HTTP.RateLimit.TrustedProxies,
+		securityLogger,
+	)))
+	var accessRoutes map[string]float64
+	if cfg.Config.Logger.Sampling.Enabled {
+		accessRoutes = cfg.Config.Logger.Sampling.AccessRoutes
+	}
+	accessLog := middleware.NewAccessLog(cfg.Logger, accessRoutes)
+	r.Use(accessLog.Handle())
+	if cfg.Metrics != nil {
+		if err := cfg.Metrics.Register(metrics.NewAccessLogCollector(cfg.Metrics.Namespace(), accessLog.Stats)); err != nil {
+			cfg.Logger.Warn("access log metrics not registered", "error", err.Error())
+		}
+	}
+	if cfg.DebugLogging != nil {
+		r.Use(cfg.DebugLogging.Handle())
+	}
+
+	if cfg.I18n != nil {
+		r.Use(middleware.Locale(cfg.I18n, cfg.Config.I18n.CookieName))
+	}
 	r.Use(middleware.SecurityHeaders(cfg.Config.HTTP.SecurityHeaders))
 
+	if cfg.LoadShedder != nil {
+		r.Use(cfg.LoadShedder.Shed())
+	}
+
 	// HTTPS enforcement
 	if cfg.Config.HTTP.TLS.Enabled && cfg.Config.HTTP.TLS.HTTPSOnly {
 		r.Use(middleware.HTTPSOnly(middleware.HTTPSOnlyConfig{
@@ -49,6 +87,17 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 		r.Use(chimiddleware.Compress(cfg.Config.HTTP.Compression.Level))
 	}
 
+	// Tenant must be resolved before rate limiting so per-user limits are
+	// scoped to verified tenants
+	if cfg.Config.Tenant.Enabled {
+		tenantResolver := middleware.NewTenantResolver(
+			cfg.Config.Tenant,
+			cfg.Config.JWT.AccessSecret,
+			securityLogger,
+		)
+		r.Use(tenantResolver.Resolve())
+	}
+
 	if cfg.Config.HTTP.RateLimit.Enabled && cfg.Cache != nil {
 		rateLimiter := middleware.NewRateLimiter(
 			cfg.Cache.Client(),
@@ -56,11 +105,21 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 			cfg.Config.HTTP.RateLimit.TrustedProxies,
 			securityLogger,
 		)
+		if fallback := cfg.Config.HTTP.RateLimit.Fallback; fallback.Enabled {
+			rateLimiter.SetFallback(middleware.NewLocalLimiter(fallback.MaxKeys, fallback.Replicas))
+		}
 		r.Use(rateLimiter.GlobalLimit(
 			cfg.Config.HTTP.RateLimit.Global.Limit,
 			cfg.Config.HTTP.RateLimit.Global.Window,
 			cfg.Config.HTTP.RateLimit.Global.Burst,
 		))
+		r.Use(rateLimiter.RouteLimits(r, cfg.Config.HTTP.RateLimit.Routes))
+
+		if cfg.Metrics != nil {
+			if err := cfg.Metrics.Register(metrics.NewRateLimitCollector(cfg.Metrics.Namespace(), rateLimiter.Stats)); err != nil {
+				cfg.Logger.Warn("rate limit metrics not registered", "error", err.Error())
+			}
+		}
 	}
 
 	r.Use(chimiddleware.Heartbeat("/ping"))
@@ -69,13 +128,19 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 	r.Get("/health", web.LivenessHandler)
 	r.Get("/health/ready", web.ReadinessHandler())
 
+	// With the admin listener, metrics are served there only
+	if cfg.Metrics != nil && !cfg.Config.Admin.Enabled {
+		r.Handle(cfg.Config.Metrics.Path, cfg.Metrics.Handler())
+	}
+
 	r.Route("/api/v1", func(v1 chi.Router) {
 		v1.Use(middleware.Timeout(cfg.Config.HTTP.RequestTimeout))
 		v1.Use(middleware.AcceptJSON())
-		v1.Use(chimiddleware.AllowContentType("application/json"))
+		v1.Use(chimiddleware.AllowContentType("application/json", "text/csv", "application/x-ndjson", "application/jsonl"))
 
+		var csrf *middleware.CSRFProtection
 		if cfg.Config.HTTP.CSRF.Enabled {
-			csrf := middleware.NewCSRFProtection(
+			csrf = middleware.NewCSRFProtection(
 				cfg.Config.HTTP.CSRF.Secret,
 				cfg.Config.HTTP.CSRF.CookieName,
 				cfg.Config.HTTP.CSRF.HeaderName,
@@ -84,7 +149,19 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 				true,
 				securityLogger,
 			)
+			csrf.SetPreviousSecret(cfg.Config.HTTP.CSRF.PreviousSecret, cfg.Config.HTTP.CSRF.RotatedAt)
+			csrf.SetOriginCheck(cfg.Config.HTTP.CSRF.CheckOrigin, cfg.Config.HTTP.CSRF.TrustedOrigins)
+			csrf.SetSessionCookie(cfg.Config.HTTP.CSRF.SessionCookie)
+			v1.Use(csrf.Session())
 			v1.Use(csrf.Protect())
+		}
+
+		// After CSRF, so rejected requests never claim an idempotency key
+		if cfg.Config.HTTP.Idempotency.Enabled && cfg.Cache != nil {
+			v1.Use(middleware.NewIdempotency(cfg.Cache, cfg.Config.HTTP.Idempotency).Handle())
+		}
+
+		if csrf != nil {
 			v1.Get("/csrf-token", csrf.GetTokenHandler())
 		}
 
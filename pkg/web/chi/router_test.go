@@ -0,0 +1,105 @@
+package chi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/web"
+	webchi "github.com/marcelofabianov/course/pkg/web/chi"
+)
+
+type formRouter struct{}
+
+func (formRouter) RegisterRoutes(r chi.Router) {
+	r.Post("/forms", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func newCSRFRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	t.Setenv("APP_HTTP_CSRF_ENABLED", "true")
+	t.Setenv("APP_HTTP_CSRF_SECRET", "a-csrf-secret-with-at-least-32-characters")
+	t.Setenv("APP_HTTP_CSRF_CHECK_ORIGIN", "false")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Skip("Config not available")
+	}
+
+	return webchi.NewRouter(webchi.RouterConfig{
+		Config:  cfg,
+		Logger:  logger.New(&logger.Config{Level: logger.LevelError, Format: logger.FormatText}),
+		Routers: []web.Router{formRouter{}},
+	})
+}
+
+// browser keeps the cookies a client received, like a browser would
+type browser struct {
+	cookies map[string]*http.Cookie
+}
+
+func (b *browser) do(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	req.RemoteAddr = "203.0.113.7:4321" // every browser is behind the same NAT
+	for _, cookie := range b.cookies {
+		req.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		b.cookies[cookie.Name] = cookie
+	}
+	return w
+}
+
+func (b *browser) csrfToken(t *testing.T, router http.Handler) string {
+	t.Helper()
+
+	w := b.do(router, httptest.NewRequest(http.MethodGet, "/api/v1/csrf-token", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the token endpoint, got %d", w.Code)
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	return body.Token
+}
+
+func (b *browser) submit(router http.Handler, token string) int {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/forms", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	return b.do(router, req).Code
+}
+
+func TestNewRouter_CSRFTokensAreBoundToTheSession(t *testing.T) {
+	router := newCSRFRouter(t)
+	alice := &browser{cookies: map[string]*http.Cookie{}}
+	mallory := &browser{cookies: map[string]*http.Cookie{}}
+
+	token := alice.csrfToken(t, router)
+	if alice.cookies["session_id"] == nil {
+		t.Fatal("expected the token endpoint to start a session")
+	}
+
+	if status := alice.submit(router, token); status != http.StatusNoContent {
+		t.Errorf("expected the session's own token to be accepted, got %d", status)
+	}
+
+	// Same IP, own session, stolen token and cookie value
+	mallory.csrfToken(t, router)
+	mallory.cookies["csrf_token"] = alice.cookies["csrf_token"]
+	if status := mallory.submit(router, token); status != http.StatusForbidden {
+		t.Errorf("expected another session's token to be rejected, got %d", status)
+	}
+}
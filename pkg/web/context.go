@@ -11,8 +11,17 @@ type contextKey string
 const (
 	LoggerCtxKey    contextKey = "logger"
 	RequestIDCtxKey contextKey = "request_id"
+	SessionIDCtxKey contextKey = "session_id"
+	ClientIPCtxKey  contextKey = "client_ip"
+	PrincipalCtxKey contextKey = "principal"
 )
 
+// Principal is the user authenticated by the access token of a request
+type Principal struct {
+	UserID string
+	Role   string
+}
+
 func GetLogger(ctx context.Context) *logger.Logger {
 	if log, ok := ctx.Value(LoggerCtxKey).(*logger.Logger); ok {
 		return log
@@ -39,3 +48,40 @@ func GetRequestID(ctx context.Context) string {
 func SetRequestID(ctx context.Context, id string) context.Context {
 	return context.WithValue(ctx, RequestIDCtxKey, id)
 }
+
+// GetSessionID returns the id of the browser session set by the CSRF
+// Session middleware, used to bind CSRF tokens; empty when the request has
+// no session
+func GetSessionID(ctx context.Context) string {
+	if id, ok := ctx.Value(SessionIDCtxKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+func SetSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, SessionIDCtxKey, id)
+}
+
+// GetClientIP returns the client IP resolved by the RealIP middleware
+func GetClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(ClientIPCtxKey).(string); ok {
+		return ip
+	}
+	return ""
+}
+
+func SetClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ClientIPCtxKey, ip)
+}
+
+// GetPrincipal returns the authenticated user of the request; false when
+// the request is anonymous
+func GetPrincipal(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(PrincipalCtxKey).(Principal)
+	return principal, ok
+}
+
+func SetPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, PrincipalCtxKey, principal)
+}
@@ -28,3 +28,24 @@ func TestSetAndGetRequestID(t *testing.T) {
 		t.Errorf("expected test-request-id, got %s", requestID)
 	}
 }
+
+func TestSetAndGetSessionID(t *testing.T) {
+	ctx := context.Background()
+
+	if sessionID := GetSessionID(ctx); sessionID != "" {
+		t.Errorf("expected empty session ID, got %s", sessionID)
+	}
+
+	ctx = SetSessionID(ctx, "test-session")
+	if sessionID := GetSessionID(ctx); sessionID != "test-session" {
+		t.Errorf("expected test-session, got %s", sessionID)
+	}
+}
+
+func TestSetAndGetClientIP(t *testing.T) {
+	ctx := SetClientIP(context.Background(), "192.0.2.60")
+
+	if ip := GetClientIP(ctx); ip != "192.0.2.60" {
+		t.Errorf("expected 192.0.2.60, got %s", ip)
+	}
+}
@@ -0,0 +1,149 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/validation"
+)
+
+var (
+	ErrInvalidBody = fault.New(
+		"invalid request body",
+		fault.WithCode(fault.Invalid),
+	)
+	ErrBodyTooLarge = fault.New(
+		"request body too large",
+		fault.WithCode(fault.Invalid),
+	)
+)
+
+// DecodeJSON decodes the body of r into a T and validates it with v, when
+// not nil. Unlike a plain json.Decoder, it rejects unknown fields and more
+// than one JSON value. Decoding errors wrap ErrInvalidBody, or
+// ErrBodyTooLarge past the MaxBytesReader limit, with the cause as a detail
+// carrying the field, rule and param, like validation errors do.
+func DecodeJSON[T any](r *http.Request, v validation.Validator) (T, error) {
+	var value T
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&value); err != nil {
+		return value, decodeError(err, dec.InputOffset())
+	}
+
+	// Anything but EOF after the value, even valid JSON, is rejected
+	if err := dec.Decode(&json.RawMessage{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return value, decodeError(err, dec.InputOffset())
+		}
+		return value, invalidBody("request body must contain a single JSON value", dec.InputOffset())
+	}
+
+	if v != nil {
+		if err := v.Struct(r.Context(), &value); err != nil {
+			return value, err
+		}
+	}
+
+	return value, nil
+}
+
+func decodeError(err error, offset int64) error {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return fault.Wrap(ErrBodyTooLarge, ErrBodyTooLarge.Error(),
+			fault.WithCode(fault.Invalid),
+			fault.WithContext("max_bytes", maxBytesErr.Limit),
+		)
+	case errors.Is(err, io.EOF):
+		return invalidBody("request body is empty", 0)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidBody("request body contains incomplete JSON", offset)
+	case errors.As(err, &syntaxErr):
+		return invalidBody(fmt.Sprintf("request body contains malformed JSON at offset %d", syntaxErr.Offset), syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		expected := jsonType(typeErr.Type)
+		return invalidField(
+			fmt.Sprintf("field '%s' must be %s", typeErr.Field, expected),
+			typeErr.Field, "type", expected, typeErr.Offset,
+		)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return invalidField(
+			fmt.Sprintf("field '%s' is not allowed", field),
+			field, "unknown", "", offset,
+		)
+	default:
+		return fault.Wrap(ErrInvalidBody, ErrInvalidBody.Error(),
+			fault.WithCode(fault.Invalid),
+			fault.WithWrappedErr(err),
+		)
+	}
+}
+
+func invalidBody(message string, offset int64) error {
+	return fault.Wrap(ErrInvalidBody, ErrInvalidBody.Error(),
+		fault.WithCode(fault.Invalid),
+		fault.WithContext("offset", offset),
+		fault.WithDetails(fault.New(message,
+			fault.WithCode(fault.Invalid),
+			fault.WithContext("offset", offset),
+		)),
+	)
+}
+
+func invalidField(message, field, rule, param string, offset int64) error {
+	return fault.Wrap(ErrInvalidBody, ErrInvalidBody.Error(),
+		fault.WithCode(fault.Invalid),
+		fault.WithContext("offset", offset),
+		fault.WithDetails(fault.New(message,
+			fault.WithCode(fault.Invalid),
+			fault.WithContext("field", field),
+			fault.WithContext("rule", rule),
+			fault.WithContext("param", param),
+			fault.WithContext("offset", offset),
+		)),
+	)
+}
+
+// jsonType names the JSON type a Go type decodes from
+func jsonType(t reflect.Type) string {
+	if t == nil {
+		return "a valid value"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	default:
+		return "a valid value"
+	}
+}
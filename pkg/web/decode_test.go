@@ -0,0 +1,107 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/validation"
+)
+
+type decodeAddress struct {
+	City string `json:"city"`
+}
+
+type decodeInput struct {
+	Name    string        `json:"name" validate:"required"`
+	Age     int           `json:"age"`
+	Address decodeAddress `json:"address"`
+}
+
+func decodeRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+}
+
+func TestDecodeJSON(t *testing.T) {
+	input, err := DecodeJSON[decodeInput](decodeRequest(`{"name":"Ana","age":30,"address":{"city":"Recife"}}`), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if input.Name != "Ana" || input.Age != 30 || input.Address.City != "Recife" {
+		t.Errorf("unexpected input: %+v", input)
+	}
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		field   string
+		rule    string
+		message string
+	}{
+		{name: "empty body", body: "", message: "request body is empty"},
+		{name: "malformed JSON", body: `{"name":}`, message: "request body contains malformed JSON at offset 9"},
+		{name: "incomplete JSON", body: `{"name":"Ana"`, message: "request body contains incomplete JSON"},
+		{name: "trailing value", body: `{"name":"Ana"}{}`, message: "request body must contain a single JSON value"},
+		{name: "trailing garbage", body: `{"name":"Ana"} x`, message: "request body must contain a single JSON value"},
+		{name: "unknown field", body: `{"name":"Ana","admin":true}`, field: "admin", rule: "unknown", message: "field 'admin' is not allowed"},
+		{name: "type mismatch", body: `{"age":"30"}`, field: "age", rule: "type", message: "field 'age' must be a number"},
+		{name: "nested type mismatch", body: `{"address":{"city":1}}`, field: "address.city", rule: "type", message: "field 'address.city' must be a string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeJSON[decodeInput](decodeRequest(tt.body), nil)
+			if !errors.Is(err, ErrInvalidBody) {
+				t.Fatalf("expected ErrInvalidBody, got %v", err)
+			}
+
+			response := fault.ToResponse(err)
+			if response.StatusCode != http.StatusBadRequest || len(response.Details) != 1 {
+				t.Fatalf("unexpected response: %+v", response)
+			}
+
+			detail := response.Details[0]
+			if detail.Message != tt.message {
+				t.Errorf("expected message %q, got %q", tt.message, detail.Message)
+			}
+			if field, _ := detail.Context["field"].(string); field != tt.field {
+				t.Errorf("expected field %q, got %q", tt.field, field)
+			}
+			if rule, _ := detail.Context["rule"].(string); rule != tt.rule {
+				t.Errorf("expected rule %q, got %q", tt.rule, rule)
+			}
+			if _, ok := detail.Context["offset"]; !ok {
+				t.Error("expected the offset in the detail context")
+			}
+		})
+	}
+}
+
+func TestDecodeJSON_BodyTooLarge(t *testing.T) {
+	r := decodeRequest(`{"name":"` + strings.Repeat("a", 64) + `"}`)
+	r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 16)
+
+	_, err := DecodeJSON[decodeInput](r, nil)
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestDecodeJSON_Validates(t *testing.T) {
+	v := validation.New(logger.New(&logger.Config{
+		Level:  logger.LevelError,
+		Format: logger.FormatText,
+	}), nil)
+
+	_, err := DecodeJSON[decodeInput](decodeRequest(`{"age":30}`), v)
+	if !errors.Is(err, validation.ErrValidationFailed) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
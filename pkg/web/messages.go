@@ -0,0 +1,9 @@
+package web
+
+import "github.com/marcelofabianov/course/pkg/i18n"
+
+// ErrorMessages translates the errors of this package
+var ErrorMessages = i18n.ErrorCatalog{
+	ErrInvalidBody:  {i18n.LocalePtBR: "corpo da requisição inválido"},
+	ErrBodyTooLarge: {i18n.LocalePtBR: "corpo da requisição muito grande"},
+}
@@ -32,6 +32,7 @@ func acceptsJSON(accept string) bool {
 
 	acceptableTypes := []string{
 		"application/json",
+		"application/problem+json",
 		"application/*",
 		"*/*",
 	}
@@ -16,6 +16,7 @@ func TestAcceptJSON(t *testing.T) {
 	}{
 		{"empty accept header", "", http.StatusOK},
 		{"application/json", "application/json", http.StatusOK},
+		{"application/problem+json", "application/problem+json", http.StatusOK},
 		{"application/*", "application/*", http.StatusOK},
 		{"*/*", "*/*", http.StatusOK},
 		{"text/html", "text/html", http.StatusNotAcceptable},
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+var (
+	ErrUnauthenticated = fault.New(
+		"authentication is required",
+		fault.WithCode(fault.Unauthorized),
+	)
+
+	ErrForbidden = fault.New(
+		"access to this resource is not allowed",
+		fault.WithCode(fault.Forbidden),
+	)
+)
+
+// Authenticator issues and authenticates HS256 bearer access tokens, signed
+// with the access secret and issued by the configured issuer. The sub claim
+// names the user, the role claim its role and the tenant claim, the one the
+// TenantResolver reads, its tenant.
+type Authenticator struct {
+	secret         []byte
+	issuer         string
+	ttl            time.Duration
+	tenantClaim    string
+	securityLogger *SecurityLogger
+}
+
+// AccessToken is an issued access token
+type AccessToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func NewAuthenticator(cfg config.JWTConfig, tenantClaim string, secLogger *SecurityLogger) *Authenticator {
+	return &Authenticator{
+		secret:         []byte(cfg.AccessSecret),
+		issuer:         cfg.Issuer,
+		ttl:            cfg.AccessTokenTTL,
+		tenantClaim:    tenantClaim,
+		securityLogger: secLogger,
+	}
+}
+
+// Issue signs an access token for the user subject with role, bound to
+// tenantID unless it is zero
+func (a *Authenticator) Issue(subject, role string, tenantID tenant.ID) (*AccessToken, error) {
+	now := time.Now()
+	claims := map[string]any{
+		"sub":  subject,
+		"role": role,
+		"iss":  a.issuer,
+		"iat":  now.Unix(),
+		"exp":  now.Add(a.ttl).Unix(),
+	}
+	if !tenantID.IsZero() && a.tenantClaim != "" {
+		claims[a.tenantClaim] = tenantID.String()
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fault.Wrap(err, "failed to encode access token", fault.WithCode(fault.Internal))
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) +
+		"." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(unsigned))
+
+	return &AccessToken{
+		AccessToken: unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)),
+		TokenType:   "Bearer",
+		ExpiresIn:   int(a.ttl.Seconds()),
+	}, nil
+}
+
+// Authenticate rejects requests without a valid access token and stores
+// the principal of the others in the context
+func (a *Authenticator) Authenticate() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, reason := a.principal(r)
+			if reason != "" {
+				if a.securityLogger != nil {
+					a.securityLogger.LogEvent(EventInvalidAuth, SeverityMedium, r, map[string]string{
+						"reason": reason,
+					})
+				}
+				web.Error(w, r, ErrUnauthenticated)
+				return
+			}
+
+			ctx := web.SetPrincipal(r.Context(), principal)
+			ctx = web.SetLogger(ctx, web.GetLogger(ctx).With("user_id", principal.UserID))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// principal returns the principal of r, or why its token was rejected
+func (a *Authenticator) principal(r *http.Request) (web.Principal, string) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return web.Principal{}, "missing token"
+	}
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return web.Principal{}, "unsupported scheme"
+	}
+
+	claims, ok := verifyAccessToken(strings.TrimPrefix(auth, "Bearer "), a.secret)
+	if !ok {
+		return web.Principal{}, "invalid token"
+	}
+
+	// verifyAccessToken accepts tokens without exp; access tokens must expire
+	if _, ok := claims["exp"].(float64); !ok {
+		return web.Principal{}, "token without expiration"
+	}
+	if issuer, _ := claims["iss"].(string); issuer != a.issuer {
+		return web.Principal{}, "unexpected issuer"
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return web.Principal{}, "token without subject"
+	}
+	role, _ := claims["role"].(string)
+
+	return web.Principal{UserID: subject, Role: role}, ""
+}
+
+// RequireRole lets through only the principals with one of roles. It must
+// run after Authenticate.
+func RequireRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := web.GetPrincipal(r.Context())
+			if !ok {
+				web.Error(w, r, ErrUnauthenticated)
+				return
+			}
+			if !slices.Contains(roles, principal.Role) {
+				web.Error(w, r, ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSelfOrRole lets through the principal named by the URL parameter
+// param and the principals with one of roles. It must run after
+// Authenticate.
+func RequireSelfOrRole(param string, roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := web.GetPrincipal(r.Context())
+			if !ok {
+				web.Error(w, r, ErrUnauthenticated)
+				return
+			}
+			if !strings.EqualFold(principal.UserID, chi.URLParam(r, param)) && !slices.Contains(roles, principal.Role) {
+				web.Error(w, r, ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/web"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+const authUserID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+func newAuthenticator() *middleware.Authenticator {
+	return middleware.NewAuthenticator(config.JWTConfig{
+		AccessSecret:   tenantSecret,
+		Issuer:         "course-api",
+		AccessTokenTTL: 15 * time.Minute,
+	}, "tenant_id", &middleware.SecurityLogger{})
+}
+
+func accessClaims(sub, role string) map[string]any {
+	return map[string]any{
+		"sub":  sub,
+		"role": role,
+		"iss":  "course-api",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func serveProtected(req *http.Request, guards ...func(http.Handler) http.Handler) (*httptest.ResponseRecorder, web.Principal) {
+	var principal web.Principal
+
+	r := chi.NewRouter()
+	r.Use(newAuthenticator().Authenticate())
+	r.With(guards...).Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = web.GetPrincipal(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, principal
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	t.Run("stores the principal of a valid token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+authUserID, nil)
+		req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, accessClaims(authUserID, "common")))
+
+		w, principal := serveProtected(req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if principal.UserID != authUserID || principal.Role != "common" {
+			t.Errorf("unexpected principal %+v", principal)
+		}
+	})
+
+	rejected := map[string]func(t *testing.T) string{
+		"missing token": func(t *testing.T) string { return "" },
+		"basic scheme":  func(t *testing.T) string { return "Basic dXNlcjpwYXNz" },
+		"wrong secret": func(t *testing.T) string {
+			return "Bearer " + signTenantToken(t, "another-secret-with-at-least-32-bytes", accessClaims(authUserID, "common"))
+		},
+		"expired token": func(t *testing.T) string {
+			claims := accessClaims(authUserID, "common")
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return "Bearer " + signTenantToken(t, tenantSecret, claims)
+		},
+		"token without expiration": func(t *testing.T) string {
+			claims := accessClaims(authUserID, "common")
+			delete(claims, "exp")
+			return "Bearer " + signTenantToken(t, tenantSecret, claims)
+		},
+		"another issuer": func(t *testing.T) string {
+			claims := accessClaims(authUserID, "common")
+			claims["iss"] = "other-api"
+			return "Bearer " + signTenantToken(t, tenantSecret, claims)
+		},
+		"token without subject": func(t *testing.T) string {
+			return "Bearer " + signTenantToken(t, tenantSecret, accessClaims("", "admin"))
+		},
+	}
+
+	for name, header := range rejected {
+		t.Run("rejects "+name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/"+authUserID, nil)
+			if value := header(t); value != "" {
+				req.Header.Set("Authorization", value)
+			}
+
+			w, _ := serveProtected(req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	t.Run("allows the listed roles", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+authUserID, nil)
+		req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, accessClaims(authUserID, "admin")))
+
+		w, _ := serveProtected(req, middleware.RequireRole("admin"))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("forbids other roles", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+authUserID, nil)
+		req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, accessClaims(authUserID, "common")))
+
+		w, _ := serveProtected(req, middleware.RequireRole("admin"))
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects requests without principal", func(t *testing.T) {
+		handler := middleware.RequireRole("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", w.Code)
+		}
+	})
+}
+
+func TestRequireSelfOrRole(t *testing.T) {
+	other := "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+	cases := []struct {
+		name   string
+		sub    string
+		role   string
+		target string
+		status int
+	}{
+		{"allows the user itself", authUserID, "common", authUserID, http.StatusOK},
+		{"compares ids case-insensitively", authUserID, "common", "0F8FAD5B-D9CB-469F-A165-70867728950E", http.StatusOK},
+		{"allows the listed roles on other users", authUserID, "admin", other, http.StatusOK},
+		{"forbids other users", authUserID, "common", other, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/"+tc.target, nil)
+			req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, accessClaims(tc.sub, tc.role)))
+
+			w, _ := serveProtected(req, middleware.RequireSelfOrRole("id", "admin"))
+
+			if w.Code != tc.status {
+				t.Errorf("expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Issue(t *testing.T) {
+	token, err := newAuthenticator().Issue(authUserID, "admin", tenantA)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if token.TokenType != "Bearer" || token.ExpiresIn != 900 {
+		t.Errorf("unexpected token %+v", token)
+	}
+
+	t.Run("authenticates the issued token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+authUserID, nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+		w, principal := serveProtected(req)
+
+		if w.Code != http.StatusOK || principal.UserID != authUserID || principal.Role != "admin" {
+			t.Errorf("expected the issued principal, got %+v (status %d)", principal, w.Code)
+		}
+	})
+
+	t.Run("binds the token to the tenant", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+		_, id := serveTenant(config.TenantConfig{Enabled: true, Claim: "tenant_id", DefaultID: tenantDefault}, req)
+
+		if id != tenantA {
+			t.Errorf("expected tenant %s, got %q", tenantA, id)
+		}
+	})
+}
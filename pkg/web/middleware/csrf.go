@@ -8,34 +8,52 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+const (
+	// csrfNonceSize is the length of the random nonce embedded in tokens
+	csrfNonceSize = 16
+
+	// csrfMACContext separates CSRF MACs from other uses of the secret
+	csrfMACContext = "csrf-token-v1"
+
+	// csrfSessionIDSize is the length of the random session ids
+	csrfSessionIDSize = 32
 )
 
+// CSRFProtection implements stateless double-submit CSRF tokens. A token is
+// "<unix timestamp>:<nonce>:<mac>", where mac is the HMAC-SHA256 of the
+// session id, timestamp and nonce, so a token only verifies for the session
+// it was issued to and cannot be forged without the secret.
 type CSRFProtection struct {
 	secret         []byte
+	previous       []byte
+	rotatedAt      time.Time
 	cookieName     string
 	headerName     string
 	ttl            time.Duration
 	exemptPaths    map[string]bool
+	sessionCookie  string
 	enabled        bool
+	checkOrigin    bool
+	trustedOrigins map[string]bool
 	securityLogger *SecurityLogger
 }
 
 func NewCSRFProtection(secret, cookieName, headerName string, ttl time.Duration, exempt []string, enabled bool, secLogger *SecurityLogger) *CSRFProtection {
-	secretBytes, err := base64.StdEncoding.DecodeString(secret)
-	if err != nil || len(secretBytes) < 32 {
-		secretBytes = []byte(secret)
-	}
-
 	exemptMap := make(map[string]bool, len(exempt))
 	for _, path := range exempt {
 		exemptMap[path] = true
 	}
 
 	return &CSRFProtection{
-		secret:         secretBytes,
+		secret:         decodeCSRFSecret(secret),
 		cookieName:     cookieName,
 		headerName:     headerName,
 		ttl:            ttl,
@@ -45,14 +63,101 @@ func NewCSRFProtection(secret, cookieName, headerName string, ttl time.Duration,
 	}
 }
 
+func decodeCSRFSecret(secret string) []byte {
+	secretBytes, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(secretBytes) < 32 {
+		return []byte(secret)
+	}
+	return secretBytes
+}
+
+// SetPreviousSecret keeps accepting tokens signed with the secret replaced
+// at rotatedAt. They are accepted for one TTL after the rotation, which is
+// when the last of them expires anyway.
+func (c *CSRFProtection) SetPreviousSecret(secret string, rotatedAt time.Time) {
+	if secret == "" {
+		c.previous = nil
+		return
+	}
+	c.previous = decodeCSRFSecret(secret)
+	c.rotatedAt = rotatedAt
+}
+
+// SetOriginCheck enables checking, in addition to the token, that unsafe
+// requests come from the same host or a trusted origin, using the Origin
+// header or, when absent, the Referer. Origins are "scheme://host[:port]".
+func (c *CSRFProtection) SetOriginCheck(enabled bool, trustedOrigins []string) {
+	c.checkOrigin = enabled
+	c.trustedOrigins = make(map[string]bool, len(trustedOrigins))
+	for _, origin := range trustedOrigins {
+		c.trustedOrigins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+}
+
+// SetSessionCookie names the cookie Session keeps the session id in
+func (c *CSRFProtection) SetSessionCookie(name string) {
+	c.sessionCookie = name
+}
+
+// Session stores the session id of the request in the context, issuing a
+// random one in the session cookie when the request has none, so tokens
+// are bound to the browser session rather than the client IP. It must run
+// before Protect and the token handler.
+func (c *CSRFProtection) Session() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c.sessionCookie == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var sessionID string
+			if cookie, err := r.Cookie(c.sessionCookie); err == nil && isSessionID(cookie.Value) {
+				sessionID = cookie.Value
+			} else {
+				id := make([]byte, csrfSessionIDSize)
+				if _, err := rand.Read(id); err != nil {
+					http.Error(w, "Failed to start session", http.StatusInternalServerError)
+					return
+				}
+				sessionID = base64.RawURLEncoding.EncodeToString(id)
+				http.SetCookie(w, &http.Cookie{
+					Name:     c.sessionCookie,
+					Value:    sessionID,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   true,
+					SameSite: http.SameSiteStrictMode,
+				})
+			}
+
+			next.ServeHTTP(w, r.WithContext(web.SetSessionID(r.Context(), sessionID)))
+		})
+	}
+}
+
+// isSessionID reports whether value has the shape of an issued session id
+func isSessionID(value string) bool {
+	id, err := base64.RawURLEncoding.DecodeString(value)
+	return err == nil && len(id) == csrfSessionIDSize
+}
+
 func (c *CSRFProtection) Protect() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !c.enabled || c.isSafeMethod(r.Method) || c.isExempt(r.URL.Path) {
+			if !c.enabled || isSafeMethod(r.Method) || c.isExempt(r.URL.Path) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			if c.checkOrigin && !c.isAllowedOrigin(r) {
+				if c.securityLogger != nil {
+					c.securityLogger.LogCSRFViolation(r, "origin_mismatch")
+				}
+				http.Error(w, "CSRF origin not allowed", http.StatusForbidden)
+				return
+			}
+
 			cookie, err := r.Cookie(c.cookieName)
 			if err != nil {
 				if c.securityLogger != nil {
@@ -85,29 +190,37 @@ func (c *CSRFProtection) Protect() func(next http.Handler) http.Handler {
 	}
 }
 
+// getSessionID returns the value tokens are bound to: the session id set by
+// Session, otherwise the client IP
 func (c *CSRFProtection) getSessionID(r *http.Request) string {
-	if sessionID := r.Context().Value("session_id"); sessionID != nil {
-		return sessionID.(string)
+	if sessionID := web.GetSessionID(r.Context()); sessionID != "" {
+		return sessionID
 	}
-	return getRealIP(r)
+
+	return ClientIP(r)
 }
 
 func (c *CSRFProtection) GenerateToken(sessionID string) (string, error) {
-	timestamp := time.Now().Unix()
-	random := make([]byte, 16)
-	if _, err := rand.Read(random); err != nil {
+	nonce := make([]byte, csrfNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
 		return "", err
 	}
 
-	h := hmac.New(sha256.New, c.secret)
-	h.Write([]byte(sessionID))
-	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
-	h.Write(random)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	encodedNonce := base64.RawURLEncoding.EncodeToString(nonce)
+	mac := csrfMAC(c.secret, sessionID, timestamp, encodedNonce)
 
-	tokenBytes := h.Sum(nil)
-	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+	return timestamp + ":" + encodedNonce + ":" + base64.RawURLEncoding.EncodeToString(mac), nil
+}
 
-	return strconv.FormatInt(timestamp, 10) + ":" + token, nil
+func csrfMAC(secret []byte, sessionID, timestamp, nonce string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(csrfMACContext))
+	for _, part := range []string{sessionID, timestamp, nonce} {
+		// Length prefixes keep ("ab", "c") and ("a", "bc") distinct
+		h.Write([]byte(strconv.Itoa(len(part)) + ":" + part))
+	}
+	return h.Sum(nil)
 }
 
 func (c *CSRFProtection) SetTokenCookie(w http.ResponseWriter, token string) {
@@ -150,28 +263,64 @@ func (c *CSRFProtection) validateToken(sessionID, cookieToken, headerToken strin
 	}
 
 	parts := strings.Split(cookieToken, ":")
-	if len(parts) != 2 {
+	if len(parts) != 3 {
 		return false
 	}
+	timestamp, nonce, encodedMAC := parts[0], parts[1], parts[2]
 
-	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	issuedAt, err := strconv.ParseInt(timestamp, 10, 64)
 	if err != nil {
 		return false
 	}
 
-	if time.Since(time.Unix(timestamp, 0)) > c.ttl {
+	issued := time.Unix(issuedAt, 0)
+	now := time.Now()
+	// A small allowance covers clock skew between replicas
+	if now.Sub(issued) > c.ttl || issued.After(now.Add(time.Minute)) {
 		return false
 	}
 
-	expectedToken, err := c.GenerateToken(sessionID)
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
 	if err != nil {
 		return false
 	}
 
-	return strings.HasPrefix(expectedToken, parts[0]+":")
+	if hmac.Equal(mac, csrfMAC(c.secret, sessionID, timestamp, nonce)) {
+		return true
+	}
+
+	if c.previous != nil && now.Before(c.rotatedAt.Add(c.ttl)) && !issued.After(c.rotatedAt) {
+		return hmac.Equal(mac, csrfMAC(c.previous, sessionID, timestamp, nonce))
+	}
+
+	return false
+}
+
+// isAllowedOrigin checks the Origin header, falling back to the Referer.
+// Requests carrying neither are left to the token check, since some
+// clients omit both.
+func (c *CSRFProtection) isAllowedOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" || source == "null" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return r.Header.Get("Origin") != "null"
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return c.trustedOrigins[strings.ToLower(u.Scheme+"://"+u.Host)]
 }
 
-func (c *CSRFProtection) isSafeMethod(method string) bool {
+func isSafeMethod(method string) bool {
 	return method == http.MethodGet ||
 		method == http.MethodHead ||
 		method == http.MethodOptions
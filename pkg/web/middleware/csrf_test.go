@@ -1,12 +1,14 @@
 package middleware_test
 
 import (
-	"context"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/marcelofabianov/course/pkg/web"
 	"github.com/marcelofabianov/course/pkg/web/middleware"
 )
 
@@ -140,9 +142,8 @@ func TestCSRFProtection_ValidToken(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	type contextKey string
 	req := httptest.NewRequest(http.MethodPost, "/test", nil)
-	req = req.WithContext(context.WithValue(req.Context(), contextKey("session_id"), "test-session"))
+	req = req.WithContext(web.SetSessionID(req.Context(), "test-session"))
 	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
 	req.Header.Set("X-CSRF-Token", token)
 	w := httptest.NewRecorder()
@@ -154,6 +155,151 @@ func TestCSRFProtection_ValidToken(t *testing.T) {
 	}
 }
 
+func newCSRFRequest(sessionID, token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req = req.WithContext(web.SetSessionID(req.Context(), sessionID))
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	req.Header.Set("X-CSRF-Token", token)
+	return req
+}
+
+func serveCSRF(csrf *middleware.CSRFProtection, req *http.Request) int {
+	handler := csrf.Protect()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestCSRFProtection_ForgedToken(t *testing.T) {
+	csrf := middleware.NewCSRFProtection("secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+
+	token, err := csrf.GenerateToken("test-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ":")
+
+	forged := map[string]string{
+		"well-formed garbage": strconv.FormatInt(time.Now().Unix(), 10) + ":anything",
+		"tampered nonce":      parts[0] + ":AAAAAAAAAAAAAAAAAAAAAA:" + parts[2],
+		"tampered timestamp":  strconv.FormatInt(time.Now().Unix()+1, 10) + ":" + parts[1] + ":" + parts[2],
+		"tampered mac":        parts[0] + ":" + parts[1] + ":" + strings.Repeat("A", len(parts[2])),
+	}
+
+	for name, value := range forged {
+		t.Run(name, func(t *testing.T) {
+			if code := serveCSRF(csrf, newCSRFRequest("test-session", value)); code != http.StatusForbidden {
+				t.Errorf("expected status 403, got %d", code)
+			}
+		})
+	}
+}
+
+func TestCSRFProtection_OtherSession(t *testing.T) {
+	csrf := middleware.NewCSRFProtection("secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+
+	token, err := csrf.GenerateToken("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := serveCSRF(csrf, newCSRFRequest("session-2", token)); code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", code)
+	}
+}
+
+func TestCSRFProtection_OtherSecret(t *testing.T) {
+	issuer := middleware.NewCSRFProtection("other-secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+	csrf := middleware.NewCSRFProtection("secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+
+	token, err := issuer.GenerateToken("test-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := serveCSRF(csrf, newCSRFRequest("test-session", token)); code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", code)
+	}
+}
+
+func TestCSRFProtection_SecretRotation(t *testing.T) {
+	old := middleware.NewCSRFProtection("old-secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+	token, err := old.GenerateToken("test-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("accepts previous secret within one TTL", func(t *testing.T) {
+		csrf := middleware.NewCSRFProtection("new-secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+		csrf.SetPreviousSecret("old-secret", time.Now().Add(time.Second))
+
+		if code := serveCSRF(csrf, newCSRFRequest("test-session", token)); code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", code)
+		}
+	})
+
+	t.Run("rejects previous secret after one TTL", func(t *testing.T) {
+		csrf := middleware.NewCSRFProtection("new-secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+		csrf.SetPreviousSecret("old-secret", time.Now().Add(-2*time.Hour))
+
+		if code := serveCSRF(csrf, newCSRFRequest("test-session", token)); code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", code)
+		}
+	})
+
+	t.Run("rejects previous secret for tokens issued after rotation", func(t *testing.T) {
+		csrf := middleware.NewCSRFProtection("new-secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+		csrf.SetPreviousSecret("old-secret", time.Now().Add(-time.Minute))
+
+		if code := serveCSRF(csrf, newCSRFRequest("test-session", token)); code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", code)
+		}
+	})
+}
+
+func TestCSRFProtection_OriginCheck(t *testing.T) {
+	csrf := middleware.NewCSRFProtection("secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
+	csrf.SetOriginCheck(true, []string{"https://app.example.com"})
+
+	token, err := csrf.GenerateToken("test-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		origin  string
+		referer string
+		want    int
+	}{
+		{name: "same host", origin: "https://example.com", want: http.StatusOK},
+		{name: "trusted origin", origin: "https://app.example.com", want: http.StatusOK},
+		{name: "untrusted origin", origin: "https://evil.example.net", want: http.StatusForbidden},
+		{name: "referer fallback", referer: "https://evil.example.net/form", want: http.StatusForbidden},
+		{name: "opaque origin", origin: "null", want: http.StatusForbidden},
+		{name: "no origin or referer", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newCSRFRequest("test-session", token)
+			req.Host = "example.com"
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+
+			if code := serveCSRF(csrf, req); code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, code)
+			}
+		})
+	}
+}
+
 func TestCSRFProtection_GetTokenHandler(t *testing.T) {
 	csrf := middleware.NewCSRFProtection("secret", "csrf_token", "X-CSRF-Token", time.Hour, []string{}, true, &middleware.SecurityLogger{})
 
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+var ErrDebugTokenTTL = fault.New(
+	"debug token ttl must be positive and within the maximum",
+	fault.WithCode(fault.Invalid),
+)
+
+// DebugLogging logs the requests carrying a valid debug token at debug
+// level, without changing the level of the application. A token covers
+// every request that carries it or, issued for a subject, only the
+// requests whose access token names that user in its sub claim.
+type DebugLogging struct {
+	header         string
+	secret         []byte
+	maxTTL         time.Duration
+	jwtSecret      []byte
+	securityLogger *SecurityLogger
+}
+
+// DebugToken is an issued token and the header to send it in
+type DebugToken struct {
+	Token     string    `json:"token"`
+	Header    string    `json:"header"`
+	Subject   string    `json:"subject,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type debugClaims struct {
+	Subject   string `json:"sub,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func NewDebugLogging(cfg config.DebugLoggingConfig, jwtSecret string, secLogger *SecurityLogger) *DebugLogging {
+	return &DebugLogging{
+		header:         cfg.HeaderName,
+		secret:         []byte(cfg.Secret),
+		maxTTL:         cfg.MaxTTL,
+		jwtSecret:      []byte(jwtSecret),
+		securityLogger: secLogger,
+	}
+}
+
+// Issue signs a token valid for ttl, or for the maximum ttl when zero. An
+// empty subject makes it valid for any request.
+func (d *DebugLogging) Issue(subject string, ttl time.Duration) (DebugToken, error) {
+	if ttl == 0 {
+		ttl = d.maxTTL
+	}
+	if ttl < 0 || ttl > d.maxTTL {
+		return DebugToken{}, fault.Wrap(ErrDebugTokenTTL, ErrDebugTokenTTL.Error(),
+			fault.WithCode(fault.Invalid),
+			fault.WithContext("max_ttl", d.maxTTL.String()),
+		)
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	payload, err := json.Marshal(debugClaims{Subject: subject, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return DebugToken{}, fault.Wrap(err, "failed to encode debug token", fault.WithCode(fault.Internal))
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return DebugToken{
+		Token:     encoded + "." + base64.RawURLEncoding.EncodeToString(d.sign(encoded)),
+		Header:    d.header,
+		Subject:   subject,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Handle must run after Logger, whose request logger it replaces
+func (d *DebugLogging) Handle() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(d.header)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, ok := d.verify(token)
+			if !ok {
+				if d.securityLogger != nil {
+					d.securityLogger.LogEvent(EventInvalidDebugToken, SeverityMedium, r, nil)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if claims.Subject != "" && claims.Subject != d.userID(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := logger.WithLevelOverride(r.Context(), logger.LevelDebug)
+			log := web.GetLogger(ctx).WithLevel(logger.LevelDebug).With("debug_logging", true)
+			log.Debug("debug logging enabled by token",
+				"subject", claims.Subject,
+				"expires_at", time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339),
+			)
+
+			next.ServeHTTP(w, r.WithContext(web.SetLogger(ctx, log)))
+		})
+	}
+}
+
+func (d *DebugLogging) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func (d *DebugLogging) verify(token string) (debugClaims, bool) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return debugClaims{}, false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, d.sign(payload)) {
+		return debugClaims{}, false
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return debugClaims{}, false
+	}
+
+	var claims debugClaims
+	if err := json.Unmarshal(decoded, &claims); err != nil {
+		return debugClaims{}, false
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return debugClaims{}, false
+	}
+
+	return claims, true
+}
+
+// userID returns the sub claim of a verified bearer access token
+func (d *DebugLogging) userID(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") || len(d.jwtSecret) == 0 {
+		return ""
+	}
+
+	claims, ok := verifyAccessToken(strings.TrimPrefix(auth, "Bearer "), d.jwtSecret)
+	if !ok {
+		return ""
+	}
+
+	sub, _ := claims["sub"].(string)
+	return sub
+}
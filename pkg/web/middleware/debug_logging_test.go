@@ -0,0 +1,132 @@
+package middleware_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/web"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+const debugSecret = "test-debug-secret-with-32-bytes!!!"
+
+func newDebugLogging() *middleware.DebugLogging {
+	return middleware.NewDebugLogging(config.DebugLoggingConfig{
+		Enabled:    true,
+		HeaderName: "X-Debug-Token",
+		Secret:     debugSecret,
+		MaxTTL:     time.Hour,
+	}, tenantSecret, nil)
+}
+
+// serveDebug runs a request through Logger and DebugLogging, returning what
+// the handler logged at debug level
+func serveDebug(d *middleware.DebugLogging, req *http.Request) string {
+	var buf bytes.Buffer
+	log := logger.New(&logger.Config{
+		Level:  logger.LevelInfo,
+		Format: logger.FormatJSON,
+		Output: &buf,
+	})
+
+	handler := middleware.Logger(log)(d.Handle()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		web.GetLogger(r.Context()).Debug("handler debug")
+		log.Slog().DebugContext(r.Context(), "context debug")
+	})))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	return buf.String()
+}
+
+func TestDebugLogging(t *testing.T) {
+	d := newDebugLogging()
+
+	anyone, err := d.Issue("", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+	if anyone.Header != "X-Debug-Token" || time.Until(anyone.ExpiresAt) > 10*time.Minute {
+		t.Errorf("unexpected token: %+v", anyone)
+	}
+
+	userToken, err := d.Issue("user-1", 0)
+	if err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+	if time.Until(userToken.ExpiresAt) < 59*time.Minute {
+		t.Errorf("expected the max ttl by default, got %s", userToken.ExpiresAt)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		subject   string
+		wantDebug bool
+	}{
+		{name: "no token", wantDebug: false},
+		{name: "token for any request", token: anyone.Token, wantDebug: true},
+		{name: "token for the user", token: userToken.Token, subject: "user-1", wantDebug: true},
+		{name: "token for another user", token: userToken.Token, subject: "user-2", wantDebug: false},
+		{name: "user token without access token", token: userToken.Token, wantDebug: false},
+		{name: "tampered token", token: anyone.Token + "x", wantDebug: false},
+		{name: "malformed token", token: "not-a-token", wantDebug: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.token != "" {
+				req.Header.Set("X-Debug-Token", tt.token)
+			}
+			if tt.subject != "" {
+				req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, map[string]any{
+					"sub": tt.subject,
+					"exp": time.Now().Add(time.Hour).Unix(),
+				}))
+			}
+
+			logs := serveDebug(d, req)
+
+			for _, msg := range []string{"handler debug", "context debug"} {
+				if got := strings.Contains(logs, msg); got != tt.wantDebug {
+					t.Errorf("expected %q logged = %v, logs: %s", msg, tt.wantDebug, logs)
+				}
+			}
+			if !strings.Contains(logs, "request completed") {
+				t.Error("expected the info logs either way")
+			}
+		})
+	}
+}
+
+func TestDebugLogging_Issue(t *testing.T) {
+	d := newDebugLogging()
+
+	for _, ttl := range []time.Duration{-time.Minute, 2 * time.Hour} {
+		if _, err := d.Issue("", ttl); err == nil {
+			t.Errorf("expected ttl %s to be rejected", ttl)
+		}
+	}
+
+	other := middleware.NewDebugLogging(config.DebugLoggingConfig{
+		HeaderName: "X-Debug-Token",
+		Secret:     "another-debug-secret-with-32-bytes",
+		MaxTTL:     time.Hour,
+	}, tenantSecret, nil)
+	forged, err := other.Issue("", time.Minute)
+	if err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Debug-Token", forged.Token)
+	if strings.Contains(serveDebug(d, req), "handler debug") {
+		t.Error("expected a token signed with another secret to be ignored")
+	}
+}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/cache"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+// maxIdempotencyKeyLength bounds client keys, which are usually UUIDs
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrIdempotencyKeyInvalid = fault.New(
+		"idempotency key is invalid",
+		fault.WithCode(fault.Invalid),
+	)
+	ErrIdempotencyKeyInUse = fault.New(
+		"a request with this idempotency key is in progress",
+		fault.WithCode(fault.Conflict),
+	)
+	ErrIdempotencyKeyReused = fault.New(
+		"idempotency key was used with a different request",
+		fault.WithCode(fault.DomainViolation),
+	)
+)
+
+// idempotencyRecord is stored under each key: first as a lock while the
+// request runs, then as the response to replay
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Pending     bool        `json:"pending,omitempty"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Idempotency runs unsafe requests carrying an idempotency key at most once
+// per key. Retries with the same method, path and body get the stored
+// response; a concurrent duplicate gets 409 and a different request with the
+// same key gets 422. Keys are scoped to the credentials of the request, or
+// its client IP when anonymous, and to the tenant by the cache, so clients
+// cannot see each other's responses.
+type Idempotency struct {
+	cache *cache.Cache
+	cfg   config.IdempotencyConfig
+}
+
+func NewIdempotency(c *cache.Cache, cfg config.IdempotencyConfig) *Idempotency {
+	return &Idempotency{
+		cache: c,
+		cfg:   cfg,
+	}
+}
+
+func (i *Idempotency) Handle() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(i.cfg.HeaderName)
+			if !i.cfg.Enabled || key == "" || isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				web.Error(w, r, fault.Wrap(ErrIdempotencyKeyInvalid, "idempotency key is too long",
+					fault.WithContext("max_length", maxIdempotencyKeyLength),
+				))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				web.Error(w, r, fault.Wrap(err, "failed to read request body", fault.WithCode(fault.Invalid)))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := idempotencyStoreKey(r, key)
+			fingerprint := idempotencyFingerprint(r, body)
+
+			record, err := i.lock(r.Context(), storeKey, fingerprint)
+			if err != nil {
+				web.Error(w, r, err)
+				return
+			}
+			if record != nil {
+				replayIdempotentResponse(w, record)
+				return
+			}
+
+			recorder := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// The request context may be done by now, but the lock must
+				// still be released or replaced
+				ctx := context.WithoutCancel(r.Context())
+				if !completed || recorder.status >= http.StatusInternalServerError {
+					_ = i.cache.Delete(ctx, storeKey)
+					return
+				}
+				i.store(ctx, storeKey, fingerprint, recorder)
+			}()
+
+			next.ServeHTTP(recorder, r)
+			completed = true
+		})
+	}
+}
+
+// lock claims key for the request, returning the stored record when the
+// key was already used for the same request and has a response
+func (i *Idempotency) lock(ctx context.Context, key, fingerprint string) (*idempotencyRecord, error) {
+	pending, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, Pending: true})
+	if err != nil {
+		return nil, fault.Wrap(err, "failed to encode idempotency record", fault.WithCode(fault.Internal))
+	}
+
+	ok, err := i.cache.SetNX(ctx, key, pending, i.cfg.LockTTL)
+	if err != nil {
+		return nil, idempotencyStoreError(err)
+	}
+	if ok {
+		return nil, nil
+	}
+
+	data, err := i.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		// Released between both calls; the client can simply retry
+		return nil, ErrIdempotencyKeyInUse
+	}
+	if err != nil {
+		return nil, idempotencyStoreError(err)
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, fault.Wrap(err, "failed to decode idempotency record", fault.WithCode(fault.Internal))
+	}
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		return nil, ErrIdempotencyKeyReused
+	case record.Pending:
+		return nil, ErrIdempotencyKeyInUse
+	default:
+		return &record, nil
+	}
+}
+
+func (i *Idempotency) store(ctx context.Context, key, fingerprint string, recorder *idempotencyRecorder) {
+	header := recorder.Header().Clone()
+	header.Del("Date")
+	header.Del("Content-Length")
+
+	data, err := json.Marshal(idempotencyRecord{
+		Fingerprint: fingerprint,
+		Status:      recorder.status,
+		Header:      header,
+		Body:        recorder.body.Bytes(),
+	})
+	if err == nil {
+		err = i.cache.Set(ctx, key, data, i.cfg.TTL)
+	}
+	if err != nil {
+		// Without a record, a retry runs the request again
+		web.GetLogger(ctx).ErrorContext(ctx, "failed to store idempotent response",
+			"error", err.Error(),
+		)
+		_ = i.cache.Delete(ctx, key)
+	}
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, record *idempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	_, _ = w.Write(record.Body)
+}
+
+func idempotencyStoreError(err error) error {
+	return fault.Wrap(err, "idempotency store unavailable", fault.WithCode(fault.InfraError))
+}
+
+// idempotencyStoreKey scopes key to the credentials of the request, the
+// bearer token or else the session, without storing them. Anonymous
+// requests are scoped to the client IP, so clients behind the same address
+// still share keys.
+func idempotencyStoreKey(r *http.Request, key string) string {
+	var scope string
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scope = "auth:" + auth
+	} else if session := web.GetSessionID(r.Context()); session != "" {
+		scope = "session:" + session
+	} else {
+		scope = "ip:" + ClientIP(r)
+	}
+
+	sum := sha256.Sum256([]byte(scope + "\x00" + key))
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
+
+// idempotencyFingerprint identifies the request a key was first used with
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	sum := sha256.Sum256([]byte(r.Method + "\x00" + r.URL.RequestURI() + "\x00" + hex.EncodeToString(bodySum[:])))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyRecorder captures the response while writing it through
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *idempotencyRecorder) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *idempotencyRecorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
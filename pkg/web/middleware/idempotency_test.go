@@ -0,0 +1,181 @@
+package middleware_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/cache"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+func newTestIdempotency(t *testing.T) *middleware.Idempotency {
+	t.Helper()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Skip("Config not available")
+	}
+
+	mr := miniredis.RunT(t)
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	cfg.Redis.Credentials.Host = host
+	cfg.Redis.Credentials.Port, _ = strconv.Atoi(port)
+
+	c, _ := cache.New(cfg)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	return middleware.NewIdempotency(c, config.IdempotencyConfig{
+		Enabled:    true,
+		HeaderName: "Idempotency-Key",
+		TTL:        time.Hour,
+		LockTTL:    time.Minute,
+	})
+}
+
+func idempotentRequest(method, key, body string) *http.Request {
+	req := httptest.NewRequest(method, "/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	req.Header.Set("Authorization", "Bearer token")
+	return req
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	idem := newTestIdempotency(t)
+
+	var calls atomic.Int32
+	handler := idem.Handle()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Location", "/users/1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+
+	for range 2 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, idempotentRequest(http.MethodPost, "key-1", `{"name":"a"}`))
+
+		if w.Code != http.StatusCreated || w.Body.String() != `{"id":"1"}` {
+			t.Errorf("unexpected response: %d %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("Location") != "/users/1" {
+			t.Error("expected headers to be replayed")
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", calls.Load())
+	}
+
+	t.Run("rejects the key with a different payload", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, idempotentRequest(http.MethodPost, "key-1", `{"name":"b"}`))
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+
+	t.Run("scopes keys to the credentials", func(t *testing.T) {
+		req := idempotentRequest(http.MethodPost, "key-1", `{"name":"a"}`)
+		req.Header.Set("Authorization", "Bearer other")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Header().Get("Idempotent-Replayed") != "" || calls.Load() != 2 {
+			t.Error("expected another client's key not to be replayed")
+		}
+	})
+
+	t.Run("scopes anonymous keys to the client IP", func(t *testing.T) {
+		anonymous := func(remoteAddr string) *httptest.ResponseRecorder {
+			req := idempotentRequest(http.MethodPost, "key-2", `{"name":"a"}`)
+			req.Header.Del("Authorization")
+			req.RemoteAddr = remoteAddr
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		anonymous("192.0.2.1:1234")
+		if w := anonymous("192.0.2.2:1234"); w.Header().Get("Idempotent-Replayed") != "" {
+			t.Error("expected another address's key not to be replayed")
+		}
+		if w := anonymous("192.0.2.1:5678"); w.Header().Get("Idempotent-Replayed") != "true" {
+			t.Error("expected the key to be replayed to the same address")
+		}
+		if calls.Load() != 4 {
+			t.Errorf("expected one run per address, got %d runs", calls.Load())
+		}
+	})
+
+	t.Run("ignores safe methods", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, idempotentRequest(http.MethodGet, "key-1", ""))
+
+		if calls.Load() != 5 {
+			t.Error("expected GET requests to always run")
+		}
+	})
+}
+
+func TestIdempotency_ConcurrentDuplicate(t *testing.T) {
+	idem := newTestIdempotency(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := idem.Handle()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "key-1", "{}"))
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest(http.MethodPost, "key-1", "{}"))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", w.Code)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestIdempotency_ReleasesOnServerError(t *testing.T) {
+	idem := newTestIdempotency(t)
+
+	var calls atomic.Int32
+	handler := idem.Handle()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	for range 2 {
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "key-1", "{}"))
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("expected a retry after a server error to run again, ran %d times", calls.Load())
+	}
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+const (
+	// loadShedBackoff is the multiplicative decrease applied when latency
+	// rises above the tolerated level
+	loadShedBackoff = 0.9
+
+	// loadShedSmoothing is the weight of each new sample in the latency
+	// moving average
+	loadShedSmoothing = 0.1
+
+	// loadShedBaselineWindow is how long the lowest latency seen is kept as
+	// the no-load baseline before it is sampled again
+	loadShedBaselineWindow = 30 * time.Second
+
+	// loadShedDegradedFor is how long the instance reports itself degraded
+	// after shedding a request
+	loadShedDegradedFor = 10 * time.Second
+)
+
+// ErrOverloaded answers shed requests and is reported by the readiness
+// check while requests are shed
+var ErrOverloaded = fault.New(
+	"service is overloaded",
+	fault.WithCode(web.CodeUnavailable),
+)
+
+// Priority decides which requests are shed first
+type Priority int
+
+const (
+	// PriorityLow requests are shed once in-flight requests reach
+	// lowPriorityShare of the limit
+	PriorityLow Priority = iota
+	PriorityNormal
+	// PriorityCritical requests, such as health checks, are never shed
+	PriorityCritical
+)
+
+const lowPriorityShare = 0.8
+
+// LoadShedder caps in-flight requests with an AIMD limit driven by latency.
+// While the smoothed latency stays under Tolerance times the no-load
+// baseline, the limit grows by about one per limit's worth of requests;
+// above it, the limit shrinks by 10%, at most once per smoothed latency.
+type LoadShedder struct {
+	mu           sync.Mutex
+	enabled      bool
+	limit        float64
+	minLimit     float64
+	maxLimit     float64
+	tolerance    float64
+	inFlight     int
+	smoothed     time.Duration
+	baseline     time.Duration
+	windowMin    time.Duration
+	windowStart  time.Time
+	lastDecrease time.Time
+	lastShed     time.Time
+	shed         uint64
+	critical     []string
+	low          []string
+	now          func() time.Time
+}
+
+// LoadShedStats are the current state and counters of a LoadShedder
+type LoadShedStats struct {
+	Limit    int
+	InFlight int
+	Shed     uint64
+	Latency  time.Duration
+	Baseline time.Duration
+}
+
+func NewLoadShedder(cfg config.LoadSheddingConfig) *LoadShedder {
+	return &LoadShedder{
+		enabled:   cfg.Enabled,
+		limit:     float64(cfg.InitialLimit),
+		minLimit:  float64(cfg.MinLimit),
+		maxLimit:  float64(cfg.MaxLimit),
+		tolerance: cfg.Tolerance,
+		critical:  cfg.CriticalPaths,
+		low:       cfg.LowPriorityPaths,
+		now:       time.Now,
+	}
+}
+
+// Shed rejects requests with 503 when the in-flight limit is reached
+func (s *LoadShedder) Shed() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			priority := s.priority(r.URL.Path)
+			if !s.enabled || priority == PriorityCritical {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			inFlight, retryAfter, ok := s.acquire(priority)
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+				web.Error(w, r, ErrOverloaded)
+				return
+			}
+
+			start := s.now()
+			defer func() {
+				s.release(s.now().Sub(start), inFlight)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *LoadShedder) priority(path string) Priority {
+	for _, prefix := range s.critical {
+		if matchesPathPrefix(path, prefix) {
+			return PriorityCritical
+		}
+	}
+	for _, prefix := range s.low {
+		if matchesPathPrefix(path, prefix) {
+			return PriorityLow
+		}
+	}
+	return PriorityNormal
+}
+
+// matchesPathPrefix matches whole segments, so "/health" covers
+// "/health/ready" but not "/healthz"
+func matchesPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// acquire admits a request, returning the in-flight count it found, or
+// how long the client should wait when it is shed
+func (s *LoadShedder) acquire(priority Priority) (int, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	capacity := s.limit
+	if priority == PriorityLow {
+		capacity *= lowPriorityShare
+	}
+
+	if float64(s.inFlight) >= math.Floor(capacity) {
+		s.shed++
+		s.lastShed = s.now()
+		return 0, max(s.smoothed, time.Second), false
+	}
+
+	inFlight := s.inFlight
+	s.inFlight++
+	return inFlight, 0, true
+}
+
+func (s *LoadShedder) release(latency time.Duration, inFlight int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	now := s.now()
+
+	if s.smoothed == 0 {
+		s.smoothed = latency
+	} else {
+		s.smoothed = time.Duration((1-loadShedSmoothing)*float64(s.smoothed) + loadShedSmoothing*float64(latency))
+	}
+
+	if s.windowMin == 0 || latency < s.windowMin {
+		s.windowMin = latency
+	}
+	if s.baseline == 0 || s.windowMin < s.baseline {
+		s.baseline = s.windowMin
+	}
+	if now.Sub(s.windowStart) >= loadShedBaselineWindow {
+		// Resampling lets the baseline rise after, say, a deploy that makes
+		// every request slower
+		s.baseline = s.windowMin
+		s.windowMin = 0
+		s.windowStart = now
+	}
+
+	if float64(s.smoothed) > s.tolerance*float64(s.baseline) {
+		if now.Sub(s.lastDecrease) >= s.smoothed {
+			s.limit = max(s.minLimit, s.limit*loadShedBackoff)
+			s.lastDecrease = now
+		}
+		return
+	}
+
+	// Only grow while the limit is actually in use
+	if float64(inFlight+1) >= s.limit/2 {
+		s.limit = min(s.maxLimit, s.limit+1/s.limit)
+	}
+}
+
+// Stats returns the current limit and counters
+func (s *LoadShedder) Stats() LoadShedStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return LoadShedStats{
+		Limit:    int(s.limit),
+		InFlight: s.inFlight,
+		Shed:     s.shed,
+		Latency:  s.smoothed,
+		Baseline: s.baseline,
+	}
+}
+
+// Name implements web.HealthChecker
+func (s *LoadShedder) Name() string {
+	return "load"
+}
+
+// Check implements web.HealthChecker, failing for a while after a request
+// is shed so the readiness probe reports the instance as degraded
+func (s *LoadShedder) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.lastShed.IsZero() && s.now().Sub(s.lastShed) < loadShedDegradedFor {
+		return fmt.Errorf("%w: %d requests in flight, limit %d", ErrOverloaded, s.inFlight, int(s.limit))
+	}
+	return nil
+}
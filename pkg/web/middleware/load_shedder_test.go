@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+func newTestLoadShedder(initial int) *LoadShedder {
+	return NewLoadShedder(config.LoadSheddingConfig{
+		Enabled:          true,
+		InitialLimit:     initial,
+		MinLimit:         1,
+		MaxLimit:         100,
+		Tolerance:        2,
+		CriticalPaths:    []string{"/health"},
+		LowPriorityPaths: []string{"/import"},
+	})
+}
+
+func TestLoadShedder_Shed(t *testing.T) {
+	shedder := newTestLoadShedder(2)
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	handler := shedder.Shed()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started.Done()
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	var done sync.WaitGroup
+	for range 2 {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			serve("/slow")
+		}()
+	}
+	started.Wait()
+
+	w := serve("/users")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After on shed requests")
+	}
+
+	t.Run("answers with problem details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Accept", web.ProblemContentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var problem web.Problem
+		if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+			t.Fatalf("failed to decode problem: %v", err)
+		}
+		if w.Code != http.StatusServiceUnavailable || problem.Status != http.StatusServiceUnavailable || problem.Code != string(web.CodeUnavailable) {
+			t.Errorf("unexpected problem %+v (status %d)", problem, w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Error("expected Retry-After on shed requests")
+		}
+	})
+
+	if w := serve("/health/ready"); w.Code != http.StatusOK {
+		t.Errorf("expected critical paths to never be shed, got %d", w.Code)
+	}
+
+	if err := shedder.Check(context.Background()); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("expected the readiness check to report overload, got %v", err)
+	}
+
+	close(release)
+	done.Wait()
+
+	if w := serve("/users"); w.Code != http.StatusOK {
+		t.Errorf("expected requests to be admitted again, got %d", w.Code)
+	}
+	if stats := shedder.Stats(); stats.Shed != 2 || stats.InFlight != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestLoadShedder_LowPriorityShedFirst(t *testing.T) {
+	shedder := newTestLoadShedder(5)
+
+	for range 4 {
+		if _, _, ok := shedder.acquire(PriorityNormal); !ok {
+			t.Fatal("expected normal requests under the limit to be admitted")
+		}
+	}
+
+	if _, _, ok := shedder.acquire(PriorityLow); ok {
+		t.Error("expected low priority requests to be shed at 80% of the limit")
+	}
+	if _, _, ok := shedder.acquire(PriorityNormal); !ok {
+		t.Error("expected normal requests to be admitted up to the limit")
+	}
+	if shedder.priority("/import/jobs") != PriorityLow || shedder.priority("/imports") != PriorityNormal {
+		t.Error("expected priorities to match whole path segments")
+	}
+}
+
+func TestLoadShedder_AdaptsToLatency(t *testing.T) {
+	shedder := newTestLoadShedder(10)
+	now := time.Unix(1_700_000_000, 0)
+	shedder.now = func() time.Time { return now }
+
+	for range 20 {
+		now = now.Add(time.Second)
+		shedder.inFlight++
+		shedder.release(10*time.Millisecond, 9)
+	}
+	grown := shedder.Stats().Limit
+	if grown <= 10 {
+		t.Fatalf("expected the limit to grow under load with low latency, got %d", grown)
+	}
+
+	for range 20 {
+		now = now.Add(time.Second)
+		shedder.inFlight++
+		shedder.release(200*time.Millisecond, 9)
+	}
+	if limit := shedder.Stats().Limit; limit >= grown {
+		t.Errorf("expected the limit to back off when latency rises, got %d", limit)
+	}
+
+	disabled := NewLoadShedder(config.LoadSheddingConfig{})
+	if err := disabled.Check(context.Background()); err != nil {
+		t.Errorf("expected a disabled shedder to be healthy, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+)
+
+// localLimiterShards spreads keys over independent locks
+const localLimiterShards = 16
+
+// LocalLimiter is an in-process token bucket limiter used while Redis is
+// unavailable. Each instance only sees its own traffic, so limits are
+// divided by the estimated number of replicas. Buckets live in sharded
+// maps bounded by LRU eviction, so a flood of distinct keys cannot grow
+// memory without limit.
+type LocalLimiter struct {
+	shards   [localLimiterShards]*localShard
+	replicas int
+	now      func() time.Time
+}
+
+type localShard struct {
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+	maxKeys int
+}
+
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// NewLocalLimiter keeps at most about maxKeys buckets and divides limits
+// by replicas
+func NewLocalLimiter(maxKeys, replicas int) *LocalLimiter {
+	l := &LocalLimiter{
+		replicas: max(replicas, 1),
+		now:      time.Now,
+	}
+
+	perShard := max(maxKeys/localLimiterShards, 1)
+	for i := range l.shards {
+		l.shards[i] = &localShard{
+			buckets: make(map[string]*list.Element),
+			lru:     list.New(),
+			maxKeys: perShard,
+		}
+	}
+
+	return l
+}
+
+// Allow takes a token for key, reporting the outcome like redis_rate so
+// both limiters set the same headers
+func (l *LocalLimiter) Allow(key string, limit redis_rate.Limit) *redis_rate.Result {
+	limit = l.scale(limit)
+	ratePerSecond := float64(limit.Rate) / limit.Period.Seconds()
+	burst := float64(limit.Burst)
+
+	shard := l.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := l.now()
+	bucket := shard.bucket(key, burst, now)
+
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = math.Min(burst, bucket.tokens+elapsed*ratePerSecond)
+	bucket.last = now
+
+	result := &redis_rate.Result{Limit: limit, RetryAfter: -1}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = 1
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / ratePerSecond)
+	}
+	result.Remaining = int(bucket.tokens)
+	result.ResetAfter = secondsToDuration((burst - bucket.tokens) / ratePerSecond)
+
+	return result
+}
+
+// Len returns the number of buckets held
+func (l *LocalLimiter) Len() int {
+	total := 0
+	for _, shard := range l.shards {
+		shard.mu.Lock()
+		total += shard.lru.Len()
+		shard.mu.Unlock()
+	}
+	return total
+}
+
+func (l *LocalLimiter) scale(limit redis_rate.Limit) redis_rate.Limit {
+	if l.replicas > 1 {
+		limit.Rate = max(int(math.Ceil(float64(limit.Rate)/float64(l.replicas))), 1)
+		limit.Burst = max(int(math.Ceil(float64(limit.Burst)/float64(l.replicas))), 1)
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Rate
+	}
+	return limit
+}
+
+func (l *LocalLimiter) shard(key string) *localShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return l.shards[h.Sum32()%localLimiterShards]
+}
+
+// bucket returns the bucket for key, creating a full one and evicting the
+// least recently used when the shard is full
+func (s *localShard) bucket(key string, burst float64, now time.Time) *tokenBucket {
+	if element, ok := s.buckets[key]; ok {
+		s.lru.MoveToFront(element)
+		return element.Value.(*tokenBucket)
+	}
+
+	if s.lru.Len() >= s.maxKeys {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.buckets, oldest.Value.(*tokenBucket).key)
+	}
+
+	bucket := &tokenBucket{key: key, tokens: burst, last: now}
+	s.buckets[key] = s.lru.PushFront(bucket)
+	return bucket
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
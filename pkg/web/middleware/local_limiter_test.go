@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+)
+
+func TestLocalLimiter_Allow(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := NewLocalLimiter(100, 1)
+	l.now = func() time.Time { return now }
+
+	limit := redis_rate.Limit{Rate: 2, Period: time.Minute, Burst: 2}
+
+	for i := range 2 {
+		if res := l.Allow("client", limit); res.Allowed != 1 {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+
+	res := l.Allow("client", limit)
+	if res.Allowed != 0 {
+		t.Fatal("expected the third request to be limited")
+	}
+	if res.RetryAfter != 30*time.Second {
+		t.Errorf("expected retry after 30s, got %s", res.RetryAfter)
+	}
+
+	if res := l.Allow("other", limit); res.Allowed != 1 {
+		t.Error("expected other keys to have their own bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	if res := l.Allow("client", limit); res.Allowed != 1 {
+		t.Error("expected a token to be refilled after 30s")
+	}
+}
+
+func TestLocalLimiter_ScalesByReplicas(t *testing.T) {
+	l := NewLocalLimiter(100, 4)
+
+	res := l.Allow("client", redis_rate.Limit{Rate: 10, Period: time.Minute, Burst: 10})
+	if res.Limit.Rate != 3 || res.Limit.Burst != 3 {
+		t.Errorf("expected the limit to be split across 4 replicas, got %+v", res.Limit)
+	}
+
+	res = l.Allow("client", redis_rate.Limit{Rate: 1, Period: time.Minute, Burst: 1})
+	if res.Limit.Rate != 1 {
+		t.Errorf("expected at least one request per window, got %d", res.Limit.Rate)
+	}
+}
+
+func TestLocalLimiter_EvictsLeastRecentlyUsed(t *testing.T) {
+	l := NewLocalLimiter(localLimiterShards, 1)
+	limit := redis_rate.Limit{Rate: 1, Period: time.Hour, Burst: 1}
+
+	for i := range 1000 {
+		l.Allow("client-"+strconv.Itoa(i), limit)
+	}
+
+	if l.Len() > localLimiterShards {
+		t.Errorf("expected at most %d buckets, got %d", localLimiterShards, l.Len())
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcelofabianov/course/pkg/i18n"
+)
+
+// Locale stores the localizer of the request in the context. The locale is
+// the user preference in the preferenceCookie, when set and supported,
+// otherwise the best match of Accept-Language, otherwise the default.
+func Locale(bundle *i18n.Bundle, preferenceCookie string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := ""
+			if preferenceCookie != "" {
+				if cookie, err := r.Cookie(preferenceCookie); err == nil {
+					locale = bundle.Match(cookie.Value)
+				}
+			}
+			if locale == "" {
+				locale = bundle.Match(parseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+			}
+
+			w.Header().Add("Vary", "Accept-Language")
+
+			ctx := i18n.WithLocalizer(r.Context(), bundle.Localizer(locale))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language
+// header by decreasing quality, without "*" and q=0 entries
+func parseAcceptLanguage(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, entry := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, len(languages))
+	for i, l := range languages {
+		tags[i] = l.tag
+	}
+	return tags
+}
@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcelofabianov/course/pkg/i18n"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+func TestLocale(t *testing.T) {
+	bundle, err := i18n.NewBundle(i18n.LocaleEN)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		cookie         string
+		expected       string
+	}{
+		{"no preference", "", "", i18n.LocaleEN},
+		{"accept language", "pt-BR,pt;q=0.9,en;q=0.8", "", i18n.LocalePtBR},
+		{"quality order", "en;q=0.5, pt;q=0.8", "", i18n.LocalePtBR},
+		{"unsupported language", "fr-FR, de;q=0.5", "", i18n.LocaleEN},
+		{"excluded language", "pt;q=0, en-GB;q=0.3", "", i18n.LocaleEN},
+		{"cookie wins", "en-US", "pt-BR", i18n.LocalePtBR},
+		{"unsupported cookie", "pt-BR", "fr", i18n.LocalePtBR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var locale string
+			handler := middleware.Locale(bundle, "locale")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				locale = i18n.Locale(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "locale", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if locale != tt.expected {
+				t.Errorf("expected locale %s, got %s", tt.expected, locale)
+			}
+			if w.Header().Get("Vary") != "Accept-Language" {
+				t.Error("expected Vary: Accept-Language")
+			}
+		})
+	}
+}
@@ -1,43 +1,130 @@
 package middleware
 
 import (
+	"math/rand/v2"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
 	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/tracing"
 	"github.com/marcelofabianov/course/pkg/web"
 )
 
+// AccessLogStats counts the access log lines dropped by route sampling
+type AccessLogStats struct {
+	Dropped uint64
+}
+
+// AccessLog stores the request logger in the context and logs one line per
+// completed request. Routes can keep only a share of their lines; server
+// errors are always logged, at error level. Log sampling never drops these
+// lines, their sampling is decided here.
+type AccessLog struct {
+	log     *logger.Logger
+	routes  map[string]*accessLogRoute
+	dropped atomic.Uint64
+}
+
+type accessLogRoute struct {
+	ratio   float64
+	dropped atomic.Uint64 // since the last line logged for the route
+}
+
+// NewAccessLog samples the routes keyed by "[METHOD ]chi pattern" with
+// their ratio; requests no route pattern matches, such as /ping, are keyed
+// by path
+func NewAccessLog(log *logger.Logger, routes map[string]float64) *AccessLog {
+	a := &AccessLog{
+		log:    log,
+		routes: make(map[string]*accessLogRoute, len(routes)),
+	}
+	for route, ratio := range routes {
+		a.routes[routeLimitKey(route)] = &accessLogRoute{ratio: ratio}
+	}
+	return a
+}
+
 func Logger(log *logger.Logger) func(http.Handler) http.Handler {
+	return NewAccessLog(log, nil).Handle()
+}
+
+func (a *AccessLog) Handle() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			requestID := middleware.GetReqID(r.Context())
+			requestID := web.GetRequestID(r.Context())
 
-			ctxLogger := log.With(
+			args := []any{
 				"request_id", requestID,
 				"method", r.Method,
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
-			)
+				"client_ip", ClientIP(r),
+			}
+			if traceID := tracing.TraceID(r.Context()); traceID != "" {
+				args = append(args, "trace_id", traceID, "span_id", tracing.SpanID(r.Context()))
+			}
+			ctxLogger := a.log.With(args...)
 
 			ctx := web.SetLogger(r.Context(), ctxLogger)
-			ctx = web.SetRequestID(ctx, requestID)
 
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			next.ServeHTTP(ww, r.WithContext(ctx))
 
-			ctxLogger.Info("request completed",
+			attrs := []any{
 				"request_id", requestID,
 				"method", r.Method,
 				"path", r.URL.Path,
 				"status", ww.Status(),
 				"duration", time.Since(start).String(),
 				"bytes_written", ww.BytesWritten(),
-			)
+			}
+
+			if route := a.route(r); route != nil {
+				if ww.Status() < http.StatusInternalServerError && rand.Float64() >= route.ratio {
+					route.dropped.Add(1)
+					a.dropped.Add(1)
+					return
+				}
+				if dropped := route.dropped.Swap(0); dropped > 0 {
+					attrs = append(attrs, "sampled_out", dropped)
+				}
+			}
+
+			// The line was sampled above; the logger must not drop it again
+			logCtx := logger.Unsampled(r.Context())
+			if ww.Status() >= http.StatusInternalServerError {
+				ctxLogger.ErrorContext(logCtx, "request completed", attrs...)
+				return
+			}
+			ctxLogger.InfoContext(logCtx, "request completed", attrs...)
 		})
 	}
 }
+
+// Stats returns the access log lines dropped so far
+func (a *AccessLog) Stats() AccessLogStats {
+	return AccessLogStats{Dropped: a.dropped.Load()}
+}
+
+// route returns the sampling of the route of r, once it was routed
+func (a *AccessLog) route(r *http.Request) *accessLogRoute {
+	if len(a.routes) == 0 {
+		return nil
+	}
+
+	pattern := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		pattern = normalizeRoutePattern(rctx.RoutePattern())
+	}
+
+	if route, ok := a.routes[r.Method+" "+pattern]; ok {
+		return route
+	}
+	return a.routes[pattern]
+}
@@ -0,0 +1,120 @@
+package middleware_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&logger.Config{
+		Level:  logger.LevelInfo,
+		Format: logger.FormatJSON,
+		Output: &buf,
+	})
+
+	accessLog := middleware.NewAccessLog(log, map[string]float64{
+		"/health":                0,
+		"/ping":                  0,
+		"GET /api/v1/users/{id}": 0,
+		"/api/v1/reports":        1,
+	})
+
+	r := chi.NewRouter()
+	r.Use(accessLog.Handle())
+	r.Use(chimiddleware.Heartbeat("/ping"))
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/api/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	r.Delete("/api/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/api/v1/reports", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, req := range []struct {
+		method, path string
+	}{
+		{http.MethodGet, "/health"},
+		{http.MethodGet, "/health"},
+		{http.MethodGet, "/ping"},
+		{http.MethodGet, "/api/v1/users/1"},
+		{http.MethodGet, "/api/v1/users/broken"},
+		{http.MethodDelete, "/api/v1/users/1"},
+		{http.MethodGet, "/api/v1/reports"},
+		{http.MethodGet, "/unknown"},
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	logs := buf.String()
+	for _, path := range []string{`"path":"/health"`, `"path":"/ping"`, `"path":"/api/v1/users/1","status":200`} {
+		if strings.Contains(logs, path) {
+			t.Errorf("expected %s to be sampled out", path)
+		}
+	}
+	for _, line := range []string{
+		`"path":"/api/v1/users/broken","status":500`,
+		`"method":"DELETE","path":"/api/v1/users/1"`,
+		`"path":"/api/v1/reports"`,
+		`"path":"/unknown"`,
+	} {
+		if !strings.Contains(logs, line) {
+			t.Errorf("expected logs to contain %s", line)
+		}
+	}
+	if !strings.Contains(logs, `"sampled_out":1`) {
+		t.Error("expected the server error line to report the sampled out request")
+	}
+
+	if got := accessLog.Stats().Dropped; got != 4 {
+		t.Errorf("expected 4 dropped lines, got %d", got)
+	}
+}
+
+func TestAccessLog_BypassesLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&logger.Config{
+		Level:    logger.LevelInfo,
+		Format:   logger.FormatJSON,
+		Output:   &buf,
+		Sampling: &logger.SamplingConfig{First: 1, Tick: time.Minute},
+	})
+
+	r := chi.NewRouter()
+	r.Use(middleware.NewAccessLog(log, nil).Handle())
+	r.Get("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	r.Get("/denied", func(w http.ResponseWriter, r *http.Request) {
+		middleware.NewSecurityLogger(log).LogEvent(middleware.EventInvalidAuth, middleware.SeverityMedium, r, nil)
+	})
+
+	for range 3 {
+		for _, path := range []string{"/ok", "/broken", "/denied"} {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+	}
+
+	logs := buf.String()
+	if got := strings.Count(logs, `"msg":"request completed"`); got != 9 {
+		t.Errorf("expected every access line to be logged, got %d", got)
+	}
+	if got := strings.Count(logs, `"msg":"security_event"`); got != 3 {
+		t.Errorf("expected every security event to be logged, got %d", got)
+	}
+	if got := strings.Count(logs, `"level":"ERROR","msg":"request completed"`); got != 3 {
+		t.Errorf("expected server errors to be logged at error level, got %d", got)
+	}
+}
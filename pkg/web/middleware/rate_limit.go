@@ -1,25 +1,50 @@
 package middleware
 
 import (
+	"context"
+	"expvar"
 	"fmt"
-	"net"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/redis/go-redis/v9"
 	"github.com/sony/gobreaker"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/web"
 )
 
+// rateLimitVars publishes the counters of every RateLimiter, at
+// /debug/vars when expvar is served
+var rateLimitVars = expvar.NewMap("ratelimit")
+
 type RateLimiter struct {
 	redis          *redis.Client
 	limiter        *redis_rate.Limiter
 	enabled        bool
 	circuitBreaker *gobreaker.CircuitBreaker
-	trustedProxies []net.IPNet
+	fallback       *LocalLimiter
+	clientIP       *ClientIPResolver
 	securityLogger *SecurityLogger
+
+	breakerTransitions atomic.Uint64
+	rejected           atomic.Uint64
+	fallbackRequests   atomic.Uint64
+}
+
+// RateLimitStats are the counters of a RateLimiter
+type RateLimitStats struct {
+	BreakerState       string
+	BreakerTransitions uint64
+	Rejected           uint64
+	FallbackRequests   uint64
 }
 
 type RateLimitStrategy func(r *http.Request) string
@@ -32,9 +57,15 @@ type RateLimitRule struct {
 }
 
 func NewRateLimiter(redisClient *redis.Client, enabled bool, trustedProxyCIDRs []string, secLogger *SecurityLogger) *RateLimiter {
-	trustedProxies := parseTrustedProxies(trustedProxyCIDRs)
+	rl := &RateLimiter{
+		redis:          redisClient,
+		limiter:        redis_rate.NewLimiter(redisClient),
+		enabled:        enabled,
+		clientIP:       NewClientIPResolver(trustedProxyCIDRs, secLogger),
+		securityLogger: secLogger,
+	}
 
-	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+	rl.circuitBreaker = gobreaker.NewCircuitBreaker(gobreaker.Settings{
 		Name:        "redis-rate-limiter",
 		MaxRequests: 3,
 		Interval:    10 * time.Second,
@@ -43,75 +74,74 @@ func NewRateLimiter(redisClient *redis.Client, enabled bool, trustedProxyCIDRs [
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
 			return counts.Requests >= 3 && failureRatio >= 0.6
 		},
+		OnStateChange: rl.onBreakerStateChange,
 	})
 
-	return &RateLimiter{
-		redis:          redisClient,
-		limiter:        redis_rate.NewLimiter(redisClient),
-		enabled:        enabled,
-		circuitBreaker: cb,
-		trustedProxies: trustedProxies,
-		securityLogger: secLogger,
-	}
+	return rl
 }
 
-func parseTrustedProxies(cidrs []string) []net.IPNet {
-	var proxies []net.IPNet
-	for _, cidr := range cidrs {
-		_, ipnet, err := net.ParseCIDR(cidr)
-		if err == nil {
-			proxies = append(proxies, *ipnet)
-		}
-	}
-	return proxies
+// SetFallback limits requests locally while Redis is unavailable, instead
+// of failing them
+func (rl *RateLimiter) SetFallback(fallback *LocalLimiter) {
+	rl.fallback = fallback
 }
 
-func (rl *RateLimiter) isTrustedProxy(ip string) bool {
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return false
+// Stats returns the counters of the limiter
+func (rl *RateLimiter) Stats() RateLimitStats {
+	return RateLimitStats{
+		BreakerState:       rl.circuitBreaker.State().String(),
+		BreakerTransitions: rl.breakerTransitions.Load(),
+		Rejected:           rl.rejected.Load(),
+		FallbackRequests:   rl.fallbackRequests.Load(),
 	}
+}
 
-	for _, ipnet := range rl.trustedProxies {
-		if ipnet.Contains(parsedIP) {
-			return true
-		}
+func (rl *RateLimiter) onBreakerStateChange(name string, from, to gobreaker.State) {
+	rl.breakerTransitions.Add(1)
+	rateLimitVars.Add("breaker_transitions", 1)
+	state := new(expvar.String)
+	state.Set(to.String())
+	rateLimitVars.Set("breaker_state", state)
+
+	severity := SeverityLow
+	switch to {
+	case gobreaker.StateOpen:
+		severity = SeverityHigh
+	case gobreaker.StateHalfOpen:
+		severity = SeverityMedium
 	}
-	return false
+
+	rl.securityLogger.LogServiceEvent(EventCircuitBreakerStateChange, severity, map[string]string{
+		"breaker":  name,
+		"from":     from.String(),
+		"to":       to.String(),
+		"fallback": strconv.FormatBool(rl.fallback != nil),
+	})
 }
 
+// ByIP keys by the client IP resolved by RealIP, resolving it here when
+// the middleware did not run
 func ByIP(rl *RateLimiter) RateLimitStrategy {
 	return func(r *http.Request) string {
-		remoteIP := parseIP(r.RemoteAddr)
-
-		if rl.isTrustedProxy(remoteIP) {
-			if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-				clientIP := strings.Split(xff, ",")[0]
-				clientIP = strings.TrimSpace(clientIP)
-				return clientIP
-			}
-		} else if r.Header.Get("X-Forwarded-For") != "" {
-			if rl.securityLogger != nil {
-				rl.securityLogger.LogIPSpoofing(r, r.Header.Get("X-Forwarded-For"))
-			}
+		if ip := web.GetClientIP(r.Context()); ip != "" {
+			return ip
 		}
-
-		return remoteIP
+		return rl.clientIP.Resolve(r)
 	}
 }
 
-func parseIP(addr string) string {
-	host, _, err := net.SplitHostPort(addr)
-	if err != nil {
-		return addr
-	}
-	return host
-}
-
+// ByUser keys by the principal set by Authenticate, scoped to the tenant
+// when it comes from a verified token, and by IP for anonymous requests. IP
+// keys are never scoped: the tenant header is chosen by the client, and a
+// new tenant per request would get a fresh bucket every time.
 func ByUser(rl *RateLimiter) RateLimitStrategy {
 	return func(r *http.Request) string {
-		if userID := r.Context().Value("user_id"); userID != nil {
-			return fmt.Sprintf("user:%v", userID)
+		if principal, ok := web.GetPrincipal(r.Context()); ok {
+			key := "user:" + principal.UserID
+			if id, ok := tenant.VerifiedFromContext(r.Context()); ok {
+				key = "tenant:" + id.String() + ":" + key
+			}
+			return key
 		}
 		return ByIP(rl)(r)
 	}
@@ -139,7 +169,7 @@ func Composite(strategies ...RateLimitStrategy) RateLimitStrategy {
 func (rl *RateLimiter) Limit(rule RateLimitRule) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !rl.enabled || rl.redis == nil {
+			if !rl.enabled || (rl.redis == nil && rl.fallback == nil) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -156,9 +186,7 @@ func (rl *RateLimiter) Limit(rule RateLimitRule) func(next http.Handler) http.Ha
 				Burst:  rule.Burst,
 			}
 
-			result, err := rl.circuitBreaker.Execute(func() (interface{}, error) {
-				return rl.limiter.Allow(r.Context(), key, limit)
-			})
+			res, err := rl.allow(r.Context(), key, limit)
 			if err != nil {
 				if rl.securityLogger != nil {
 					rl.securityLogger.LogEvent(
@@ -172,14 +200,13 @@ func (rl *RateLimiter) Limit(rule RateLimitRule) func(next http.Handler) http.Ha
 				return
 			}
 
-			res := result.(*redis_rate.Result)
-			resetTime := time.Now().Add(res.ResetAfter)
-			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rule.Limit))
-			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
-			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
+			setRateLimitHeaders(w, res)
 
 			if res.Allowed == 0 {
-				w.Header().Set("Retry-After", strconv.Itoa(int(res.RetryAfter.Seconds())))
+				rl.rejected.Add(1)
+				rateLimitVars.Add("rejected", 1)
+
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
 
 				if rl.securityLogger != nil {
 					rl.securityLogger.LogRateLimitExceeded(r, rule.Limit, rule.Window.String())
@@ -194,6 +221,26 @@ func (rl *RateLimiter) Limit(rule RateLimitRule) func(next http.Handler) http.Ha
 	}
 }
 
+// allow takes a token from Redis through the circuit breaker, or from the
+// fallback when Redis is missing, failing or the breaker is open
+func (rl *RateLimiter) allow(ctx context.Context, key string, limit redis_rate.Limit) (*redis_rate.Result, error) {
+	if rl.redis != nil {
+		result, err := rl.circuitBreaker.Execute(func() (interface{}, error) {
+			return rl.limiter.Allow(ctx, key, limit)
+		})
+		if err == nil {
+			return result.(*redis_rate.Result), nil
+		}
+		if rl.fallback == nil {
+			return nil, err
+		}
+	}
+
+	rl.fallbackRequests.Add(1)
+	rateLimitVars.Add("fallback_requests", 1)
+	return rl.fallback.Allow(key, limit), nil
+}
+
 func (rl *RateLimiter) GlobalLimit(limit int, window time.Duration, burst int) func(next http.Handler) http.Handler {
 	return rl.Limit(RateLimitRule{
 		Limit:    limit,
@@ -220,3 +267,126 @@ func (rl *RateLimiter) PerRouteLimit(route string, limit int, window time.Durati
 		Strategy: ByRoute(route, rl),
 	})
 }
+
+// CredentialLimiter limits the routes that check a password, per action and
+// user, or per IP for anonymous requests. It is built with its own enabled
+// RateLimiter, so the limit holds even with rate limiting disabled.
+type CredentialLimiter struct {
+	limiter *RateLimiter
+	rate    config.RateConfig
+}
+
+func NewCredentialLimiter(limiter *RateLimiter, rate config.RateConfig) *CredentialLimiter {
+	return &CredentialLimiter{limiter: limiter, rate: rate}
+}
+
+// Limit limits action; on authenticated routes it must run after
+// Authenticate to key by user
+func (c *CredentialLimiter) Limit(action string) func(next http.Handler) http.Handler {
+	byUser := ByUser(c.limiter)
+	return c.limiter.Limit(RateLimitRule{
+		Limit:  c.rate.Limit,
+		Window: c.rate.Window,
+		Burst:  c.rate.Burst,
+		Strategy: func(r *http.Request) string {
+			return "credentials:" + action + ":" + byUser(r)
+		},
+	})
+}
+
+// RouteLimits applies the limit configured for the route a request
+// matches, on top of any global limit. Limits are keyed by chi route
+// pattern, optionally prefixed by a method as in "POST /api/v1/users"; a
+// limit for the method wins over one for the pattern alone. routes is
+// searched per request, so it may be the router this middleware is
+// installed on, before its routes are registered.
+func (rl *RateLimiter) RouteLimits(routes chi.Routes, limits map[string]config.RateConfig) func(next http.Handler) http.Handler {
+	rules := make(map[string]RateLimitRule, len(limits))
+	for route, rate := range limits {
+		key := routeLimitKey(route)
+		rules[key] = RateLimitRule{
+			Limit:    rate.Limit,
+			Window:   rate.Window,
+			Burst:    rate.Burst,
+			Strategy: ByRoute(key, rl),
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		limited := make(map[string]http.Handler, len(rules))
+		for key, rule := range rules {
+			limited[key] = rl.Limit(rule)(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(limited) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			path := r.URL.RawPath
+			if path == "" {
+				path = r.URL.Path
+			}
+
+			pattern := routes.Find(chi.NewRouteContext(), r.Method, path)
+			if pattern == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			pattern = normalizeRoutePattern(pattern)
+			if handler, ok := limited[r.Method+" "+pattern]; ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			if handler, ok := limited[pattern]; ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeLimitKey normalizes a configured route, "[METHOD ]pattern"
+func routeLimitKey(route string) string {
+	fields := strings.Fields(route)
+	if len(fields) == 2 {
+		return strings.ToUpper(fields[0]) + " " + normalizeRoutePattern(fields[1])
+	}
+	return normalizeRoutePattern(strings.TrimSpace(route))
+}
+
+// normalizeRoutePattern drops the trailing slash chi leaves on patterns
+// such as r.Route("/users", ...) with r.Post("/", ...)
+func normalizeRoutePattern(pattern string) string {
+	if len(pattern) > 1 {
+		return strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+// setRateLimitHeaders sets the RateLimit-* headers of the IETF
+// ratelimit-headers draft. When several limits apply to a request, the one
+// with the fewest requests remaining is reported.
+func setRateLimitHeaders(w http.ResponseWriter, res *redis_rate.Result) {
+	header := w.Header()
+	if current, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err == nil && current < res.Remaining {
+		return
+	}
+
+	header.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Rate))
+	header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit.Rate, ceilSeconds(res.Limit.Period)))
+}
+
+// ceilSeconds rounds d up to whole seconds, as the headers require
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
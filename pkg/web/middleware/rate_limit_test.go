@@ -1,8 +1,10 @@
 package middleware_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -10,6 +12,9 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/redis/go-redis/v9"
 
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/web"
 	"github.com/marcelofabianov/course/pkg/web/middleware"
 )
 
@@ -128,3 +133,233 @@ func BenchmarkRateLimiter(b *testing.B) {
 		r.ServeHTTP(w, req)
 	}
 }
+
+func TestRateLimiter_TenantScopedKeys(t *testing.T) {
+	tenantA := "6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a"
+	tenantB := "0b8d1c4c-3b7a-4a2f-9d62-6f1c1d0e6a56"
+
+	newHandler := func(t *testing.T, rule func(*middleware.RateLimiter) func(http.Handler) http.Handler) http.Handler {
+		mr := miniredis.RunT(t)
+		redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		limiter := middleware.NewRateLimiter(redisClient, true, []string{}, &middleware.SecurityLogger{})
+
+		return rule(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+
+	request := func(handler http.Handler, ctx func(context.Context) context.Context) int {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		req = req.WithContext(ctx(req.Context()))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("IP keys ignore the tenant", func(t *testing.T) {
+		handler := newHandler(t, func(rl *middleware.RateLimiter) func(http.Handler) http.Handler {
+			return rl.GlobalLimit(1, time.Minute, 1)
+		})
+		withTenant := func(id string) func(context.Context) context.Context {
+			return func(ctx context.Context) context.Context {
+				return tenant.WithID(ctx, tenant.ID(id))
+			}
+		}
+
+		if code := request(handler, withTenant(tenantA)); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+		if code := request(handler, withTenant(tenantB)); code != http.StatusTooManyRequests {
+			t.Errorf("expected a new tenant header not to reset the limit, got %d", code)
+		}
+	})
+
+	t.Run("user keys are scoped to verified tenants", func(t *testing.T) {
+		handler := newHandler(t, func(rl *middleware.RateLimiter) func(http.Handler) http.Handler {
+			return rl.PerUserLimit(1, time.Minute, 1)
+		})
+		withUser := func(id string) func(context.Context) context.Context {
+			return func(ctx context.Context) context.Context {
+				ctx = web.SetPrincipal(ctx, web.Principal{UserID: "user-1"})
+				return tenant.WithVerifiedID(ctx, tenant.ID(id))
+			}
+		}
+
+		if code := request(handler, withUser(tenantA)); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+		if code := request(handler, withUser(tenantA)); code != http.StatusTooManyRequests {
+			t.Errorf("expected tenant A to be limited, got %d", code)
+		}
+		if code := request(handler, withUser(tenantB)); code != http.StatusOK {
+			t.Errorf("expected tenant B to have its own limit, got %d", code)
+		}
+	})
+}
+
+func TestCredentialLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	limiter := middleware.NewCredentialLimiter(
+		middleware.NewRateLimiter(redisClient, true, []string{}, &middleware.SecurityLogger{}),
+		config.RateConfig{Limit: 1, Window: time.Minute, Burst: 1},
+	)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	password := limiter.Limit("password")(ok)
+	login := limiter.Limit("login")(ok)
+
+	request := func(handler http.Handler, userID string) int {
+		req := httptest.NewRequest(http.MethodPut, "/test", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		if userID != "" {
+			req = req.WithContext(web.SetPrincipal(req.Context(), web.Principal{UserID: userID}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(password, "user-1"); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if code := request(password, "user-1"); code != http.StatusTooManyRequests {
+		t.Errorf("expected the user to be limited, got %d", code)
+	}
+	if code := request(password, "user-2"); code != http.StatusOK {
+		t.Errorf("expected another user to have its own limit, got %d", code)
+	}
+	if code := request(login, ""); code != http.StatusOK {
+		t.Errorf("expected another action to have its own limit, got %d", code)
+	}
+	if code := request(login, ""); code != http.StatusTooManyRequests {
+		t.Errorf("expected anonymous requests to be limited by IP, got %d", code)
+	}
+}
+
+func TestRateLimiter_RouteLimits(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	limiter := middleware.NewRateLimiter(redisClient, true, []string{}, &middleware.SecurityLogger{})
+
+	r := chi.NewRouter()
+	r.Use(limiter.GlobalLimit(100, time.Minute, 100))
+	r.Use(limiter.RouteLimits(r, map[string]config.RateConfig{
+		"POST /api/v1/users": {Limit: 2, Window: time.Minute, Burst: 2},
+		"/api/v1/users/{id}": {Limit: 1, Window: time.Minute, Burst: 1},
+	}))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Route("/api/v1/users", func(r chi.Router) {
+		r.Post("/", ok)
+		r.Get("/", ok)
+		r.Get("/{id}", ok)
+	})
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/users")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("expected the route limit to be reported, got RateLimit-Limit %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("expected RateLimit-Remaining 1, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+		t.Errorf("expected RateLimit-Policy 2;w=60, got %q", got)
+	}
+
+	request(http.MethodPost, "/api/v1/users")
+	w = request(http.MethodPost, "/api/v1/users")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	if retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retryAfter < 1 {
+		t.Errorf("expected a positive Retry-After, got %q", w.Header().Get("Retry-After"))
+	}
+	if reset, err := strconv.Atoi(w.Header().Get("RateLimit-Reset")); err != nil || reset < 1 || reset > 60 {
+		t.Errorf("expected RateLimit-Reset in seconds, got %q", w.Header().Get("RateLimit-Reset"))
+	}
+
+	if w := request(http.MethodGet, "/api/v1/users"); w.Code != http.StatusOK {
+		t.Errorf("expected other methods to keep the global limit, got %d", w.Code)
+	} else if got := w.Header().Get("RateLimit-Limit"); got != "100" {
+		t.Errorf("expected the global limit to be reported, got RateLimit-Limit %q", got)
+	}
+
+	if w := request(http.MethodGet, "/api/v1/users/a"); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if w := request(http.MethodGet, "/api/v1/users/b"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the pattern limit to be shared across ids, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_FallbackWhenRedisFails(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	limiter := middleware.NewRateLimiter(redisClient, true, []string{}, &middleware.SecurityLogger{})
+	limiter.SetFallback(middleware.NewLocalLimiter(100, 1))
+	mr.Close()
+
+	handler := limiter.GlobalLimit(3, time.Minute, 3)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := range 3 {
+		if code := request(); code != http.StatusOK {
+			t.Fatalf("expected request %d to be allowed by the fallback, got %d", i+1, code)
+		}
+	}
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Errorf("expected the fallback to limit, got %d", code)
+	}
+
+	stats := limiter.Stats()
+	if stats.BreakerState != "open" {
+		t.Errorf("expected the breaker to be open, got %s", stats.BreakerState)
+	}
+	if stats.BreakerTransitions != 1 {
+		t.Errorf("expected 1 breaker transition, got %d", stats.BreakerTransitions)
+	}
+	if stats.FallbackRequests != 4 || stats.Rejected != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRateLimiter_NoFallbackWhenRedisFails(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	limiter := middleware.NewRateLimiter(redisClient, true, []string{}, &middleware.SecurityLogger{})
+	mr.Close()
+
+	handler := limiter.GlobalLimit(3, time.Minute, 3)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
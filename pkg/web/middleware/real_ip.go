@@ -4,24 +4,170 @@ import (
 	"net"
 	"net/http"
 	"strings"
+
+	"github.com/marcelofabianov/course/pkg/web"
 )
 
-func RealIP() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-				r.RemoteAddr = realIP
-			} else if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-				ips := strings.Split(forwarded, ",")
-				if len(ips) > 0 {
-					ip := strings.TrimSpace(ips[0])
-					if net.ParseIP(ip) != nil {
-						r.RemoteAddr = ip
-					}
+// ClientIPResolver finds the client IP of a request behind reverse proxies.
+// Forwarding headers are only honored when the peer is a trusted proxy, and
+// the chain is walked from the right, skipping trusted hops, so a client
+// cannot choose its IP by sending its own X-Forwarded-For entries.
+type ClientIPResolver struct {
+	trustedProxies []net.IPNet
+	securityLogger *SecurityLogger
+}
+
+// NewClientIPResolver trusts the given CIDRs or single IPs as proxies;
+// invalid entries are ignored
+func NewClientIPResolver(trustedProxies []string, secLogger *SecurityLogger) *ClientIPResolver {
+	return &ClientIPResolver{
+		trustedProxies: parseTrustedProxies(trustedProxies),
+		securityLogger: secLogger,
+	}
+}
+
+func parseTrustedProxies(entries []string) []net.IPNet {
+	var proxies []net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if _, ipnet, err := net.ParseCIDR(entry); err == nil {
+			proxies = append(proxies, *ipnet)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return proxies
+}
+
+func (c *ClientIPResolver) isTrusted(ip net.IP) bool {
+	for _, ipnet := range c.trustedProxies {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the client IP of r, without port
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	peer := parseIP(r.RemoteAddr)
+	peerIP := net.ParseIP(peer)
+
+	forwarded := r.Header.Values("Forwarded")
+	xff := r.Header.Values("X-Forwarded-For")
+	xRealIP := r.Header.Get("X-Real-IP")
+
+	if peerIP == nil || !c.isTrusted(peerIP) {
+		if len(forwarded) > 0 || len(xff) > 0 || xRealIP != "" {
+			if c.securityLogger != nil {
+				c.securityLogger.LogIPSpoofing(r, forwardingHeaderValue(forwarded, xff, xRealIP))
+			}
+		}
+		return peer
+	}
+
+	var chain []string
+	switch {
+	case len(forwarded) > 0:
+		chain = parseForwardedFor(forwarded)
+	case len(xff) > 0:
+		for _, header := range xff {
+			chain = append(chain, strings.Split(header, ",")...)
+		}
+	case xRealIP != "":
+		chain = []string{xRealIP}
+	}
+
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := net.ParseIP(normalizeHop(chain[i]))
+		if ip == nil {
+			// Obfuscated or "unknown" hops end the chain we can verify
+			break
+		}
+		client = ip.String()
+		if !c.isTrusted(ip) {
+			break
+		}
+	}
+
+	return client
+}
+
+func forwardingHeaderValue(forwarded, xff []string, xRealIP string) string {
+	switch {
+	case len(forwarded) > 0:
+		return strings.Join(forwarded, ", ")
+	case len(xff) > 0:
+		return strings.Join(xff, ", ")
+	default:
+		return xRealIP
+	}
+}
+
+// parseForwardedFor returns the for= parameters of RFC 7239 Forwarded
+// headers, in order
+func parseForwardedFor(headers []string) []string {
+	var hops []string
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					hops = append(hops, value)
 				}
 			}
+		}
+	}
+	return hops
+}
 
-			next.ServeHTTP(w, r)
+// normalizeHop strips quotes, brackets and ports from a forwarding hop, as
+// in `"[2001:db8::17]:4711"` or `192.0.2.60:8080`
+func normalizeHop(hop string) string {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+	if strings.HasPrefix(hop, "[") {
+		if end := strings.Index(hop, "]"); end > 0 {
+			return hop[1:end]
+		}
+	}
+	if strings.Count(hop, ":") == 1 {
+		host, _, _ := strings.Cut(hop, ":")
+		return host
+	}
+	return hop
+}
+
+// RealIP resolves the client IP once per request and stores it in the
+// context, where ClientIP reads it
+func RealIP(resolver *ClientIPResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := web.SetClientIP(r.Context(), resolver.Resolve(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// ClientIP returns the IP resolved by RealIP, or the peer address when the
+// middleware did not run
+func ClientIP(r *http.Request) string {
+	if ip := web.GetClientIP(r.Context()); ip != "" {
+		return ip
+	}
+	return parseIP(r.RemoteAddr)
+}
+
+func parseIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,132 @@
+package middleware_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/marcelofabianov/course/pkg/logger"
+	"github.com/marcelofabianov/course/pkg/web"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+func TestClientIPResolver_Resolve(t *testing.T) {
+	resolver := middleware.NewClientIPResolver([]string{"10.0.0.0/8", "2001:db8:ffff::1"}, nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "peer without headers",
+			remoteAddr: "203.0.113.7:1234",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer headers are ignored",
+			remoteAddr: "203.0.113.7:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "rightmost untrusted X-Forwarded-For hop",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.2"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "all hops trusted returns the leftmost",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			expected:   "10.0.0.3",
+		},
+		{
+			name:       "Forwarded header takes precedence",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"Forwarded":       `for=192.0.2.60;proto=https, for="[2001:db8:cafe::17]:4711", for=10.0.0.2`,
+				"X-Forwarded-For": "198.51.100.1",
+			},
+			expected: "2001:db8:cafe::17",
+		},
+		{
+			name:       "Forwarded with port and mixed case",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"Forwarded": `For="192.0.2.60:8080"`},
+			expected:   "192.0.2.60",
+		},
+		{
+			name:       "obfuscated hop stops the walk",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"Forwarded": `for=192.0.2.60, for=_hidden, for=10.0.0.2`},
+			expected:   "10.0.0.2",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Real-IP": "192.0.2.60"},
+			expected:   "192.0.2.60",
+		},
+		{
+			name:       "trusted single IPv6 proxy",
+			remoteAddr: "[2001:db8:ffff::1]:443",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.60"},
+			expected:   "192.0.2.60",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			assert.Equal(t, tt.expected, resolver.Resolve(req))
+		})
+	}
+}
+
+func TestClientIPResolver_LogsSpoofing(t *testing.T) {
+	var buf bytes.Buffer
+	secLogger := middleware.NewSecurityLogger(logger.New(&logger.Config{
+		Level:  "info",
+		Format: "json",
+		Output: &buf,
+	}))
+	resolver := middleware.NewClientIPResolver([]string{"10.0.0.0/8"}, secLogger)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	resolver.Resolve(req)
+
+	assert.Contains(t, buf.String(), string(middleware.EventIPSpoofing))
+	assert.Contains(t, buf.String(), "198.51.100.1")
+}
+
+func TestRealIP(t *testing.T) {
+	resolver := middleware.NewClientIPResolver([]string{"10.0.0.0/8"}, nil)
+
+	var resolved string
+	handler := middleware.RealIP(resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resolved = web.GetClientIP(r.Context())
+		assert.Equal(t, resolved, middleware.ClientIP(r))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "192.0.2.60")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "192.0.2.60", resolved)
+	assert.Equal(t, "10.0.0.1:1234", req.RemoteAddr)
+}
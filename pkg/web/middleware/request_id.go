@@ -4,12 +4,24 @@ import (
 	"net/http"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/marcelofabianov/course/pkg/tracing"
+	"github.com/marcelofabianov/course/pkg/web"
 )
 
+// RequestID keeps the X-Request-ID of the client or assigns one. Without
+// one, the trace ID is used when the request is traced, so the same ID finds
+// the request in both logs and traces; the span records the request ID
+// either way.
 func RequestID() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			requestID := r.Header.Get("X-Request-ID")
+			if requestID == "" {
+				requestID = tracing.TraceID(r.Context())
+			}
 			if requestID == "" {
 				requestID = uuid.NewString()
 			}
@@ -17,7 +29,9 @@ func RequestID() func(http.Handler) http.Handler {
 			w.Header().Set("X-Request-ID", requestID)
 			r.Header.Set("X-Request-ID", requestID)
 
-			next.ServeHTTP(w, r)
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("request.id", requestID))
+
+			next.ServeHTTP(w, r.WithContext(web.SetRequestID(r.Context(), requestID)))
 		})
 	}
 }
@@ -12,8 +12,9 @@ func RequestSize(maxBytes int64) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.ContentLength > maxBytes {
-				err := fault.New(
-					"request body too large",
+				err := fault.Wrap(
+					web.ErrBodyTooLarge,
+					web.ErrBodyTooLarge.Error(),
 					fault.WithCode(fault.Invalid),
 					fault.WithContext("max_bytes", maxBytes),
 					fault.WithContext("content_length", r.ContentLength),
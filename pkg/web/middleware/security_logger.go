@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -21,6 +22,10 @@ const (
 	EventPasswordChanged    SecurityEventType = "password_changed"
 	EventTokenRefreshed     SecurityEventType = "token_refreshed"
 	EventTokenRevoked       SecurityEventType = "token_revoked"
+	EventTenantMismatch     SecurityEventType = "tenant_mismatch"
+	EventInvalidDebugToken  SecurityEventType = "invalid_debug_token"
+
+	EventCircuitBreakerStateChange SecurityEventType = "circuit_breaker_state_change"
 )
 
 type SecuritySeverity string
@@ -48,24 +53,47 @@ func (s *SecurityLogger) LogEvent(eventType SecurityEventType, severity Security
 	fields := []interface{}{
 		"event_type", string(eventType),
 		"severity", string(severity),
-		"ip", getRealIP(r),
+		"ip", ClientIP(r),
 		"path", r.URL.Path,
 		"method", r.Method,
 		"user_agent", r.UserAgent(),
 		"timestamp", time.Now().UTC().Format(time.RFC3339),
 	}
 
+	s.log(r.Context(), severity, fields, details)
+}
+
+// LogServiceEvent logs an event not tied to a request, such as a circuit
+// breaker opening
+func (s *SecurityLogger) LogServiceEvent(eventType SecurityEventType, severity SecuritySeverity, details map[string]string) {
+	if s == nil || s.logger == nil {
+		return
+	}
+
+	fields := []interface{}{
+		"event_type", string(eventType),
+		"severity", string(severity),
+		"timestamp", time.Now().UTC().Format(time.RFC3339),
+	}
+
+	s.log(context.Background(), severity, fields, details)
+}
+
+// log writes the event past log sampling: security events are rare when
+// all is well, and each one matters when they are not
+func (s *SecurityLogger) log(ctx context.Context, severity SecuritySeverity, fields []interface{}, details map[string]string) {
 	for k, v := range details {
 		fields = append(fields, k, v)
 	}
 
+	ctx = logger.Unsampled(ctx)
 	switch severity {
 	case SeverityCritical, SeverityHigh:
-		s.logger.Error("security_event", fields...)
+		s.logger.ErrorContext(ctx, "security_event", fields...)
 	case SeverityMedium:
-		s.logger.Warn("security_event", fields...)
+		s.logger.WarnContext(ctx, "security_event", fields...)
 	default:
-		s.logger.Info("security_event", fields...)
+		s.logger.InfoContext(ctx, "security_event", fields...)
 	}
 }
 
@@ -119,13 +147,3 @@ func boolToString(b bool) string {
 	}
 	return "false"
 }
-
-func getRealIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
-	}
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-	return r.RemoteAddr
-}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/web"
+)
+
+var (
+	ErrTenantRequired = fault.New(
+		"tenant is required",
+		fault.WithCode(fault.Invalid),
+	)
+
+	ErrTenantMismatch = fault.New(
+		"tenant does not match access token",
+		fault.WithCode(fault.Forbidden),
+	)
+)
+
+// TenantResolver resolves the tenant of each request from, in order of
+// precedence, a verified access token claim, a header or the subdomain,
+// falling back to the configured default tenant.
+type TenantResolver struct {
+	header         string
+	claim          string
+	baseDomain     string
+	defaultID      tenant.ID
+	jwtSecret      []byte
+	securityLogger *SecurityLogger
+}
+
+func NewTenantResolver(cfg config.TenantConfig, jwtSecret string, secLogger *SecurityLogger) *TenantResolver {
+	var defaultID tenant.ID
+	if id, err := tenant.Parse(cfg.DefaultID); err == nil {
+		defaultID = id
+	}
+
+	return &TenantResolver{
+		header:         cfg.Header,
+		claim:          cfg.Claim,
+		baseDomain:     strings.ToLower(strings.TrimPrefix(cfg.BaseDomain, ".")),
+		defaultID:      defaultID,
+		jwtSecret:      []byte(jwtSecret),
+		securityLogger: secLogger,
+	}
+}
+
+func (tr *TenantResolver) Resolve() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, verified, err := tr.resolve(r)
+			if err != nil {
+				web.Error(w, r, err)
+				return
+			}
+
+			withTenant := tenant.WithID
+			if verified {
+				withTenant = tenant.WithVerifiedID
+			}
+
+			ctx := withTenant(r.Context(), id)
+			ctx = web.SetLogger(ctx, web.GetLogger(ctx).With("tenant_id", id.String()))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// resolve returns the tenant of r and whether it came from a verified
+// access token claim
+func (tr *TenantResolver) resolve(r *http.Request) (tenant.ID, bool, error) {
+	var fromHeader tenant.ID
+	if tr.header != "" {
+		if value := r.Header.Get(tr.header); value != "" {
+			id, err := tenant.Parse(value)
+			if err != nil {
+				return "", false, err
+			}
+			fromHeader = id
+		}
+	}
+
+	if fromClaim, ok := tr.fromClaim(r); ok {
+		if !fromHeader.IsZero() && fromHeader != fromClaim {
+			if tr.securityLogger != nil {
+				tr.securityLogger.LogEvent(EventTenantMismatch, SeverityHigh, r, map[string]string{
+					"claim_tenant":  fromClaim.String(),
+					"header_tenant": fromHeader.String(),
+				})
+			}
+			return "", false, ErrTenantMismatch
+		}
+		return fromClaim, true, nil
+	}
+
+	if !fromHeader.IsZero() {
+		return fromHeader, false, nil
+	}
+
+	if id, ok := tr.fromSubdomain(r); ok {
+		return id, false, nil
+	}
+
+	if !tr.defaultID.IsZero() {
+		return tr.defaultID, false, nil
+	}
+
+	return "", false, ErrTenantRequired
+}
+
+// fromClaim reads the tenant claim of an HS256 bearer token signed with the
+// access secret. Tokens that fail verification are ignored.
+func (tr *TenantResolver) fromClaim(r *http.Request) (tenant.ID, bool) {
+	if tr.claim == "" || len(tr.jwtSecret) == 0 {
+		return "", false
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+
+	claims, ok := verifyAccessToken(strings.TrimPrefix(auth, "Bearer "), tr.jwtSecret)
+	if !ok {
+		return "", false
+	}
+
+	value, ok := claims[tr.claim].(string)
+	if !ok {
+		return "", false
+	}
+
+	id, err := tenant.Parse(value)
+	if err != nil {
+		return "", false
+	}
+
+	return id, true
+}
+
+// verifyAccessToken returns the claims of an unexpired HS256 token signed
+// with secret
+func verifyAccessToken(token string, secret []byte) (map[string]any, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, false
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil || header.Alg != "HS256" {
+		return nil, false
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, false
+	}
+
+	if exp, ok := claims["exp"].(float64); ok && time.Now().Unix() >= int64(exp) {
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// fromSubdomain resolves <tenant-id>.<base domain>
+func (tr *TenantResolver) fromSubdomain(r *http.Request) (tenant.ID, bool) {
+	if tr.baseDomain == "" {
+		return "", false
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	label, found := strings.CutSuffix(host, "."+tr.baseDomain)
+	if !found || label == "" || strings.Contains(label, ".") {
+		return "", false
+	}
+
+	id, err := tenant.Parse(label)
+	if err != nil {
+		return "", false
+	}
+
+	return id, true
+}
@@ -0,0 +1,190 @@
+package middleware_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marcelofabianov/course/config"
+	"github.com/marcelofabianov/course/pkg/tenant"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+const (
+	tenantSecret  = "test-access-secret-with-32-bytes!!"
+	tenantA       = "6f1c1d0e-6a56-4a2f-9d62-0b8d1c4c3b7a"
+	tenantB       = "0b8d1c4c-3b7a-4a2f-9d62-6f1c1d0e6a56"
+	tenantDefault = "00000000-0000-0000-0000-000000000000"
+)
+
+func signTenantToken(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payloadJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func serveTenant(cfg config.TenantConfig, req *http.Request) (*httptest.ResponseRecorder, tenant.ID) {
+	var resolved tenant.ID
+	handler := middleware.NewTenantResolver(cfg, tenantSecret, &middleware.SecurityLogger{}).Resolve()(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resolved, _ = tenant.FromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w, resolved
+}
+
+func TestTenantResolver(t *testing.T) {
+	cfg := config.TenantConfig{
+		Enabled:    true,
+		Header:     "X-Tenant-ID",
+		Claim:      "tenant_id",
+		BaseDomain: "course.local",
+		DefaultID:  tenantDefault,
+	}
+
+	t.Run("resolves from header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("X-Tenant-ID", tenantA)
+
+		w, id := serveTenant(cfg, req)
+
+		if w.Code != http.StatusOK || id != tenantA {
+			t.Errorf("expected tenant %s, got %q (status %d)", tenantA, id, w.Code)
+		}
+	})
+
+	t.Run("resolves from subdomain", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://"+tenantB+".course.local:8080/test", nil)
+
+		_, id := serveTenant(cfg, req)
+
+		if id != tenantB {
+			t.Errorf("expected tenant %s, got %q", tenantB, id)
+		}
+	})
+
+	t.Run("resolves from verified token claim", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, map[string]any{
+			"tenant_id": tenantA,
+			"exp":       time.Now().Add(time.Minute).Unix(),
+		}))
+
+		_, id := serveTenant(cfg, req)
+
+		if id != tenantA {
+			t.Errorf("expected tenant %s, got %q", tenantA, id)
+		}
+	})
+
+	t.Run("marks only token tenants as verified", func(t *testing.T) {
+		verified := func(req *http.Request) bool {
+			var ok bool
+			handler := middleware.NewTenantResolver(cfg, tenantSecret, &middleware.SecurityLogger{}).Resolve()(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, ok = tenant.VerifiedFromContext(r.Context())
+				}),
+			)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			return ok
+		}
+
+		fromToken := httptest.NewRequest(http.MethodGet, "/test", nil)
+		fromToken.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, map[string]any{
+			"tenant_id": tenantA,
+			"exp":       time.Now().Add(time.Minute).Unix(),
+		}))
+		fromHeader := httptest.NewRequest(http.MethodGet, "/test", nil)
+		fromHeader.Header.Set("X-Tenant-ID", tenantA)
+
+		if !verified(fromToken) {
+			t.Error("expected tenant from token to be verified")
+		}
+		if verified(fromHeader) {
+			t.Error("expected tenant from header not to be verified")
+		}
+	})
+
+	t.Run("ignores tokens with invalid signature", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+signTenantToken(t, "another-secret", map[string]any{
+			"tenant_id": tenantA,
+		}))
+
+		_, id := serveTenant(cfg, req)
+
+		if id != tenantDefault {
+			t.Errorf("expected default tenant, got %q", id)
+		}
+	})
+
+	t.Run("ignores expired tokens", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, map[string]any{
+			"tenant_id": tenantA,
+			"exp":       time.Now().Add(-time.Minute).Unix(),
+		}))
+
+		_, id := serveTenant(cfg, req)
+
+		if id != tenantDefault {
+			t.Errorf("expected default tenant, got %q", id)
+		}
+	})
+
+	t.Run("rejects header that contradicts token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("X-Tenant-ID", tenantB)
+		req.Header.Set("Authorization", "Bearer "+signTenantToken(t, tenantSecret, map[string]any{
+			"tenant_id": tenantA,
+		}))
+
+		w, _ := serveTenant(cfg, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects invalid header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("X-Tenant-ID", "acme")
+
+		w, _ := serveTenant(cfg, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("requires tenant without default", func(t *testing.T) {
+		noDefault := cfg
+		noDefault.DefaultID = ""
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+
+		w, _ := serveTenant(noDefault, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/marcelofabianov/course/pkg/tracing"
+)
+
+// Tracing starts a server span per request, continuing the trace of the
+// W3C traceparent header. The span is renamed after the chi route pattern
+// once the request is routed, so it never carries raw paths in its name.
+func Tracing() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tracing.Propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+
+			ctx, span := tracing.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					semconv.URLScheme(scheme),
+					semconv.ServerAddress(r.Host),
+					semconv.UserAgentOriginal(r.UserAgent()),
+				),
+			)
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			completed := false
+
+			defer func() {
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					if route := rctx.RoutePattern(); route != "" {
+						span.SetName(r.Method + " " + route)
+						span.SetAttributes(semconv.HTTPRoute(route))
+					}
+				}
+
+				status := ww.Status()
+				switch {
+				case !completed:
+					span.SetStatus(codes.Error, "panic")
+				case status >= http.StatusInternalServerError:
+					span.SetStatus(codes.Error, http.StatusText(status))
+				}
+				if status == 0 && completed {
+					status = http.StatusOK
+				}
+				if status != 0 {
+					span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+				}
+				span.End()
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+			completed = true
+		})
+	}
+}
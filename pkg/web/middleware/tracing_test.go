@@ -0,0 +1,114 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/marcelofabianov/course/pkg/web"
+	"github.com/marcelofabianov/course/pkg/web/middleware"
+)
+
+func useTraceRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := useTraceRecorder(t)
+
+	var requestID string
+	r := chi.NewRouter()
+	r.Use(middleware.Tracing())
+	r.Use(middleware.RequestID())
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		requestID = web.GetRequestID(r.Context())
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.SpanContext().TraceID().String() != traceID {
+		t.Error("expected the span to continue the trace of traceparent")
+	}
+	if span.Name() != "GET /users/{id}" {
+		t.Errorf("expected the span to be named after the route, got %q", span.Name())
+	}
+	if route := spanAttribute(span, "http.route").AsString(); route != "/users/{id}" {
+		t.Errorf("expected http.route, got %q", route)
+	}
+	if status := spanAttribute(span, "http.response.status_code").AsInt64(); status != http.StatusNotFound {
+		t.Errorf("expected the status code, got %d", status)
+	}
+	if span.Status().Code == codes.Error {
+		t.Error("expected client errors not to mark the span as failed")
+	}
+
+	if requestID != traceID || w.Header().Get("X-Request-ID") != traceID {
+		t.Errorf("expected the trace ID as request ID, got %q", requestID)
+	}
+	if spanAttribute(span, "request.id").AsString() != traceID {
+		t.Error("expected the request ID on the span")
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if failed := recorder.Ended()[1]; failed.Status().Code != codes.Error {
+		t.Error("expected server errors to mark the span as failed")
+	}
+}
+
+func TestRequestID_KeepsClientIDWhenTraced(t *testing.T) {
+	useTraceRecorder(t)
+
+	handler := middleware.Tracing()(middleware.RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "client-id")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Header().Get("X-Request-ID") != "client-id" {
+		t.Errorf("expected the client request ID to be kept, got %q", w.Header().Get("X-Request-ID"))
+	}
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 9457 problem details
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. Type is "about:blank",
+// so Title is the HTTP status text; Code carries the fault code clients
+// can branch on.
+type Problem struct {
+	Type     string         `json:"type" example:"about:blank"`
+	Title    string         `json:"title" example:"Bad Request"`
+	Status   int            `json:"status" example:"400"`
+	Detail   string         `json:"detail,omitempty" example:"validation failed"`
+	Instance string         `json:"instance,omitempty" example:"4f8a3b9e-0c1d-4d2e-9f3a-5b6c7d8e9f0a"`
+	Code     string         `json:"code,omitempty" example:"invalid_input"`
+	Errors   []ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField describes why one request field was rejected
+type ProblemField struct {
+	Field   string `json:"field" example:"email"`
+	Rule    string `json:"rule" example:"required"`
+	Param   string `json:"param,omitempty" example:"8"`
+	Message string `json:"message" example:"field 'email' failed validation 'required'"`
+}
+
+// NewProblem builds the problem details for err. Details of a fault error
+// with a "field" context, as returned by the validator and the password
+// policy, become Errors.
+func NewProblem(r *http.Request, err error) Problem {
+	response := localizeResponse(r, err)
+
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(response.StatusCode),
+		Status:   response.StatusCode,
+		Detail:   response.Message,
+		Instance: GetRequestID(r.Context()),
+		Code:     response.Code,
+	}
+	if problem.Instance == "" {
+		problem.Instance = r.Header.Get("X-Request-ID")
+	}
+
+	for _, detail := range response.Details {
+		field, ok := detail.Context["field"].(string)
+		if !ok {
+			continue
+		}
+		problem.Errors = append(problem.Errors, ProblemField{
+			Field:   field,
+			Rule:    contextString(detail.Context["rule"]),
+			Param:   contextString(detail.Context["param"]),
+			Message: detail.Message,
+		})
+	}
+
+	return problem
+}
+
+func contextString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// WriteProblem writes problem as application/problem+json
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// acceptsProblem reports whether the client asked for problem details.
+// Clients accepting only application/json or anything keep the legacy
+// error body, so existing clients are unaffected.
+func acceptsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil || mediaType != ProblemContentType {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/i18n"
+)
+
+func TestError_ProblemDetails(t *testing.T) {
+	err := fault.New("validation failed",
+		fault.WithCode(fault.Invalid),
+		fault.WithDetails(
+			fault.New("field 'email' failed validation 'required'",
+				fault.WithContext("field", "email"),
+				fault.WithContext("rule", "required"),
+				fault.WithContext("param", ""),
+			),
+			fault.New("password must have at least 12 characters",
+				fault.WithContext("field", "password"),
+				fault.WithContext("rule", "min_length"),
+				fault.WithContext("param", 12),
+			),
+			fault.New("not about a field"),
+		),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+	r.Header.Set("Accept", "application/json, application/problem+json")
+	r = r.WithContext(SetRequestID(r.Context(), "req-123"))
+
+	Error(w, r, err)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != ProblemContentType {
+		t.Errorf("expected Content-Type %s, got %s", ProblemContentType, contentType)
+	}
+
+	var problem Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+
+	if problem.Type != "about:blank" || problem.Title != "Bad Request" || problem.Status != http.StatusBadRequest {
+		t.Errorf("unexpected problem: %+v", problem)
+	}
+	if problem.Detail != "validation failed" || problem.Instance != "req-123" || problem.Code != "invalid_input" {
+		t.Errorf("unexpected problem: %+v", problem)
+	}
+
+	expected := []ProblemField{
+		{Field: "email", Rule: "required", Message: "field 'email' failed validation 'required'"},
+		{Field: "password", Rule: "min_length", Param: "12", Message: "password must have at least 12 characters"},
+	}
+	if len(problem.Errors) != len(expected) {
+		t.Fatalf("expected %d field errors, got %+v", len(expected), problem.Errors)
+	}
+	for i, field := range expected {
+		if problem.Errors[i] != field {
+			t.Errorf("expected %+v, got %+v", field, problem.Errors[i])
+		}
+	}
+}
+
+func TestError_LegacyFormat(t *testing.T) {
+	for _, accept := range []string{"", "application/json", "*/*", "application/problem+json;q=0"} {
+		t.Run(accept, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if accept != "" {
+				r.Header.Set("Accept", accept)
+			}
+
+			Error(w, r, fault.New("test error", fault.WithCode(fault.Invalid)))
+
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+				t.Errorf("expected the legacy format, got Content-Type %s", contentType)
+			}
+		})
+	}
+}
+
+func TestError_Localized(t *testing.T) {
+	errNotFound := errors.New("user not found")
+	bundle, err := i18n.NewBundle(i18n.LocaleEN)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	bundle.AddErrors(i18n.ErrorCatalog{errNotFound: {i18n.LocalePtBR: "usuário não encontrado"}})
+	if err := bundle.AddCatalog(i18n.Catalog{i18n.LocalePtBR: {"password.min_length": "a senha deve ter pelo menos {0} caracteres"}}); err != nil {
+		t.Fatalf("failed to add catalog: %v", err)
+	}
+
+	request := func(accept string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		return r.WithContext(i18n.WithLocalizer(r.Context(), bundle.Localizer(i18n.LocalePtBR)))
+	}
+	policyErr := fault.Wrap(errNotFound, "user not found",
+		fault.WithCode(fault.NotFound),
+		fault.WithDetails(fault.New("password must have at least 12 characters",
+			fault.WithContext("field", "password"),
+			fault.WithContext("rule", "min_length"),
+			fault.WithContext("param", 12),
+		)),
+	)
+
+	t.Run("translates the message", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Error(w, request(""), fault.Wrap(errNotFound, "user not found", fault.WithCode(fault.NotFound)))
+
+		if language := w.Header().Get("Content-Language"); language != i18n.LocalePtBR {
+			t.Errorf("expected Content-Language pt-BR, got %q", language)
+		}
+
+		var response fault.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Message != "usuário não encontrado" {
+			t.Errorf("expected the translated message, got %q", response.Message)
+		}
+	})
+
+	t.Run("joins the translated details in the legacy format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Error(w, request(""), policyErr)
+
+		var response fault.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Message != "a senha deve ter pelo menos 12 caracteres" {
+			t.Errorf("expected the translated detail as message, got %q", response.Message)
+		}
+		if len(response.Details) != 0 {
+			t.Errorf("expected no details in the legacy format, got %+v", response.Details)
+		}
+	})
+
+	t.Run("translates the details of problems", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Error(w, request(ProblemContentType), policyErr)
+
+		var problem Problem
+		if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+			t.Fatalf("failed to decode problem: %v", err)
+		}
+		if problem.Detail != "usuário não encontrado" {
+			t.Errorf("expected the translated message, got %q", problem.Detail)
+		}
+		if len(problem.Errors) != 1 || problem.Errors[0].Message != "a senha deve ter pelo menos 12 caracteres" {
+			t.Errorf("expected the translated detail, got %+v", problem.Errors)
+		}
+	})
+}
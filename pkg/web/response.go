@@ -3,10 +3,17 @@ package web
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/marcelofabianov/fault"
+
+	"github.com/marcelofabianov/course/pkg/i18n"
 )
 
+// CodeUnavailable is the code of errors answered with 503 Service
+// Unavailable, a status no fault code maps to
+const CodeUnavailable fault.Code = "service_unavailable"
+
 type ErrorResponse struct {
 	Code       string `json:"code" example:"VALIDATION_ERROR"`
 	Message    string `json:"message" example:"Invalid request parameters"`
@@ -21,9 +28,66 @@ func Success(w http.ResponseWriter, r *http.Request, status int, data any) {
 	writeJSON(w, status, data)
 }
 
+// Error writes err as problem details when the client accepts
+// application/problem+json, and in the legacy format otherwise. Messages
+// are translated to the locale of the request.
 func Error(w http.ResponseWriter, r *http.Request, err error) {
+	if localizer := i18n.FromContext(r.Context()); localizer != nil {
+		w.Header().Set("Content-Language", localizer.Locale())
+	}
+
+	if acceptsProblem(r) {
+		WriteProblem(w, NewProblem(r, err))
+		return
+	}
+
+	response := localizeResponse(r, err)
+	writeJSON(w, response.StatusCode, legacyResponse(response))
+}
+
+// legacyResponse drops the details of response, which only problem details
+// carry. The message of an error with field details joins their messages,
+// as validation errors always did in this format.
+func legacyResponse(response fault.ErrorResponse) fault.ErrorResponse {
+	messages := make([]string, 0, len(response.Details))
+	for _, detail := range response.Details {
+		if _, ok := detail.Context["field"].(string); ok {
+			messages = append(messages, detail.Message)
+		}
+	}
+	if len(messages) > 0 {
+		response.Message = strings.Join(messages, "; ")
+	}
+
+	response.Details = nil
+	return response
+}
+
+// localizeResponse translates the message of err and the messages of its
+// field details, which are keyed by "<field>.<rule>". Errors with
+// CodeUnavailable get status 503.
+func localizeResponse(r *http.Request, err error) fault.ErrorResponse {
 	response := fault.ToResponse(err)
-	writeJSON(w, response.StatusCode, response)
+	if response.Code == string(CodeUnavailable) {
+		response.StatusCode = http.StatusServiceUnavailable
+	}
+
+	localizer := i18n.FromContext(r.Context())
+	if localizer == nil {
+		return response
+	}
+
+	response.Message = localizer.Error(err, response.Code, response.Message)
+	for i, detail := range response.Details {
+		field, ok := detail.Context["field"].(string)
+		if !ok {
+			continue
+		}
+		key := field + "." + contextString(detail.Context["rule"])
+		response.Details[i].Message = localizer.Message(key, detail.Message, contextString(detail.Context["param"]))
+	}
+
+	return response
 }
 
 func Created(w http.ResponseWriter, r *http.Request, data any) {
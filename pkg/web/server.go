@@ -64,6 +64,26 @@ func NewServer(cfg *config.Config, log *logger.Logger, router http.Handler) *Ser
 	return server
 }
 
+// NewAdminServer creates the internal listener on cfg.Admin. It serves
+// plain HTTP without a write timeout, so CPU profiles and execution traces
+// longer than the API write timeout complete.
+func NewAdminServer(cfg *config.Config, log *logger.Logger, router http.Handler) *Server {
+	addr := fmt.Sprintf("%s:%d", cfg.Admin.Host, cfg.Admin.Port)
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:        addr,
+			Handler:     router,
+			ReadTimeout: cfg.HTTP.ReadTimeout,
+			IdleTimeout: cfg.HTTP.IdleTimeout,
+		},
+		logger:    log,
+		router:    router,
+		addr:      addr,
+		tlsConfig: &config.TLSConfig{},
+	}
+}
+
 func (s *Server) Start() error {
 	if s.tlsConfig.Enabled {
 		s.logger.Info("starting HTTPS server with TLS 1.2/1.3",